@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/newsapi"
+)
+
+// runBackfill implements the "backfill" subcommand: it downloads the query described by
+// the NEWS_* environment variables over a date range, one window at a time
+func runBackfill(ctx context.Context, cfg *config.Config, apiKey string, args []string) error {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fromFlag := fs.String("from", "", "start of the range (RFC 3339 or YYYY-MM-DD), required")
+	toFlag := fs.String("to", "", "end of the range (RFC 3339 or YYYY-MM-DD), defaults to now")
+	window := fs.Duration("window", 24*time.Hour, "initial window size")
+	minWindow := fs.Duration("min-window", time.Hour, "smallest window a busy range is split into")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *fromFlag == "" {
+		return fmt.Errorf("-from is required")
+	}
+
+	from, err := parseTime(*fromFlag)
+	if err != nil {
+		return fmt.Errorf("invalid -from value '%s': %w", *fromFlag, err)
+	}
+
+	to := time.Now()
+	if *toFlag != "" {
+		if to, err = parseTime(*toFlag); err != nil {
+			return fmt.Errorf("invalid -to value '%s': %w", *toFlag, err)
+		}
+	}
+
+	req, err := buildDownloadRequest(cfg, apiKey, newsapi.EndpointEverything)
+	if err != nil {
+		return err
+	}
+
+	downloader, err := newsapi.NewNewsDownloaderWithDefaults(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create news downloader: %w", err)
+	}
+	defer func() {
+		if closeErr := downloader.Close(); closeErr != nil {
+			log.Printf("Error closing downloader: %v", closeErr)
+		}
+	}()
+
+	log.Printf("--- Starting Backfill ---")
+	log.Printf("Query: '%s', Range: %s to %s, Window: %v", req.Query, from.Format(time.RFC3339), to.Format(time.RFC3339), *window)
+
+	backfiller := newsapi.NewBackfiller(downloader, newsapi.BackfillOptions{Window: *window, MinWindow: *minWindow})
+	backfill, err := backfiller.Run(ctx, req, from, to)
+	if backfill != nil {
+		fmt.Printf("\n=== Backfill Windows ===\n")
+		for _, w := range backfill.Windows {
+			status := "ok"
+			if w.Err != nil {
+				status = w.Err.Error()
+			}
+			fmt.Printf("  %s .. %s  results=%d  %s\n",
+				w.From.Format("2006-01-02 15:04"), w.To.Format("2006-01-02 15:04"), w.TotalResults, status)
+		}
+		displayResults(backfill.Aggregate)
+	}
+
+	return err
+}
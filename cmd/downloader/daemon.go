@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/jobs"
+	"go-news-agg/internal/newsapi"
+)
+
+// runDaemon implements the "daemon" subcommand: it keeps running the jobs of a spec
+// file on their schedules until the process is interrupted
+func runDaemon(ctx context.Context, cfg *config.Config, apiKey string, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	specPath := fs.String("spec", os.Getenv("NEWS_JOB_SPEC"), "path to the job spec file (JSON or YAML)")
+	defaultSchedule := fs.String("schedule", os.Getenv("NEWS_SCHEDULE"), "cron expression or interval for jobs without their own")
+	runNow := fs.Bool("run-now", false, "run every job once at startup instead of waiting for its first slot")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *specPath == "" {
+		return fmt.Errorf("-spec or NEWS_JOB_SPEC is required")
+	}
+
+	spec, err := jobs.LoadSpec(*specPath)
+	if err != nil {
+		return err
+	}
+
+	options := jobs.SchedulerOptions{
+		RunOnStart: *runNow,
+		OnComplete: func(result jobs.JobResult, err error) {
+			for _, jobErr := range result.Errors {
+				log.Printf("Job '%s' error: %v", result.Name, jobErr)
+			}
+		},
+	}
+	if *defaultSchedule != "" {
+		// Accept a plain interval such as "30m" as well as a cron expression
+		schedule, intervalErr := jobs.ParseInterval(*defaultSchedule)
+		if intervalErr != nil {
+			if schedule, err = jobs.ParseSchedule(*defaultSchedule); err != nil {
+				return err
+			}
+		}
+		options.DefaultSchedule = schedule
+	}
+
+	downloader, err := newsapi.NewNewsDownloaderWithDefaults(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create news downloader: %w", err)
+	}
+	defer func() {
+		if closeErr := downloader.Close(); closeErr != nil {
+			log.Printf("Error closing downloader: %v", closeErr)
+		}
+	}()
+
+	log.Printf("--- Starting Downloader Daemon ---")
+	log.Printf("Spec: '%s', Jobs: %d", *specPath, len(spec.Jobs))
+
+	scheduler := jobs.NewScheduler(jobs.NewRunner(downloader, apiKey, cfg.MaxPageSize), options)
+	if err := scheduler.Run(ctx, spec); err != nil {
+		return err
+	}
+
+	log.Printf("--- Downloader Daemon Stopped ---")
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/jobs"
+	"go-news-agg/internal/newsapi"
+)
+
+// runJobs implements the "jobs" subcommand: it runs every job of a JSON or YAML spec
+// file through one shared downloader
+func runJobs(ctx context.Context, cfg *config.Config, apiKey string, args []string) error {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	specPath := fs.String("spec", os.Getenv("NEWS_JOB_SPEC"), "path to the job spec file (JSON or YAML)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *specPath == "" {
+		return fmt.Errorf("-spec or NEWS_JOB_SPEC is required")
+	}
+
+	spec, err := jobs.LoadSpec(*specPath)
+	if err != nil {
+		return err
+	}
+
+	downloader, err := newsapi.NewNewsDownloaderWithDefaults(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create news downloader: %w", err)
+	}
+	defer func() {
+		if closeErr := downloader.Close(); closeErr != nil {
+			log.Printf("Error closing downloader: %v", closeErr)
+		}
+	}()
+
+	log.Printf("--- Starting Job Run ---")
+	log.Printf("Spec: '%s', Jobs: %d", *specPath, len(spec.Jobs))
+
+	run, err := jobs.NewRunner(downloader, apiKey, cfg.MaxPageSize).Run(ctx, spec)
+	if run != nil {
+		displayJobResults(run)
+		displayResults(run.Aggregate)
+	}
+	if err != nil {
+		return err
+	}
+
+	if failed := run.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d jobs had failed requests", failed, len(run.Jobs))
+	}
+	return nil
+}
+
+func displayJobResults(run *jobs.RunResult) {
+	fmt.Printf("\n=== Job Summary ===\n")
+	for _, job := range run.Jobs {
+		status := "ok"
+		if len(job.Errors) > 0 {
+			status = fmt.Sprintf("%d failed", len(job.Errors))
+		}
+		fmt.Printf("  %s: requests=%d articles=%d pages=%d files=%d duplicates=%d duration=%v  %s\n",
+			job.Name, job.Requests, job.Result.TotalArticles, job.Result.PagesDownloaded,
+			len(job.Result.FilePaths), job.Result.DuplicatesSkipped, job.Result.Duration.Round(time.Second), status)
+		for _, err := range job.Errors {
+			fmt.Printf("    - %v\n", err)
+		}
+	}
+}
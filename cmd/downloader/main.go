@@ -6,10 +6,13 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"go-news-agg/internal/config"
+	"go-news-agg/internal/feeds"
 	"go-news-agg/internal/newsapi"
 )
 
@@ -33,34 +36,88 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Get API key
-	apiKey := os.Getenv("NEWSAPI_KEY")
-	if apiKey == "" {
+	// Get API keys; feed ingestion does not need one
+	providerName := getEnvWithDefault("NEWS_PROVIDER", newsapi.NewsAPIProviderName)
+	apiKeys, err := cfg.APIKeyList(os.Getenv("NEWSAPI_KEY"))
+	if err != nil {
+		log.Fatalf("Failed to load API keys: %v", err)
+	}
+	if len(apiKeys) == 0 && providerName == newsapi.NewsAPIProviderName {
 		log.Fatalf("Error: NEWSAPI_KEY environment variable not set. Please set your NewsAPI key.")
 	}
 
-	// Setup download parameters
-	query := os.Getenv("NEWS_QUERY")
-	country := getEnvWithDefault("NEWS_COUNTRY", "us")
-	
-	// Calculate "yesterday" for the news search
-	yesterday := time.Now().AddDate(0, 0, -1)
-	yesterdayStartOfDay := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, yesterday.Location())
+	// Requests are made with the first key; clients rotate through the rest
+	apiKey := ""
+	if len(apiKeys) > 0 {
+		apiKey = apiKeys[0]
+	}
+	cfg.APIKeys = apiKeys
 
-	// Create download request
-	req := newsapi.NewDownloadRequest(apiKey, country)
-	req.Query = query
-	req.From = yesterdayStartOfDay
-	req.PageSize = cfg.MaxPageSize
+	// Keep the keys out of every log line from here on
+	for _, key := range apiKeys {
+		newsapi.RegisterSecret(key)
+	}
+	log.SetOutput(newsapi.NewRedactingWriter(os.Stderr))
+	if len(apiKeys) > 1 {
+		log.Printf("Rotating through %d NewsAPI keys", len(apiKeys))
+	}
+
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "sources":
+			if err := runSources(ctx, cfg, apiKey, os.Args[2:]); err != nil {
+				log.Fatalf("Failed to list sources: %v", err)
+			}
+			return
+		case "backfill":
+			if err := runBackfill(ctx, cfg, apiKey, os.Args[2:]); err != nil {
+				log.Fatalf("Backfill failed: %v", err)
+			}
+			return
+		case "jobs":
+			if err := runJobs(ctx, cfg, apiKey, os.Args[2:]); err != nil {
+				log.Fatalf("Job run failed: %v", err)
+			}
+			return
+		case "daemon":
+			if err := runDaemon(ctx, cfg, apiKey, os.Args[2:]); err != nil {
+				log.Fatalf("Daemon failed: %v", err)
+			}
+			return
+		}
+	}
+
+	// Setup download parameters
+	var req *newsapi.DownloadRequest
+	if providerName == feeds.ProviderName {
+		req, err = buildFeedRequest(cfg)
+	} else {
+		req, err = buildDownloadRequest(cfg, apiKey, newsapi.EndpointTopHeadlines)
+	}
+	if err != nil {
+		log.Fatalf("Invalid download parameters: %v", err)
+	}
 
 	log.Printf("--- Starting News Download ---")
-	log.Printf("Query: '%s', Country: '%s', From: '%s'", 
-		req.Query, req.Country, req.From.Format("2006-01-02"))
+	fromDescription := "last run's watermark"
+	if !req.From.IsZero() {
+		fromDescription = req.From.Format(time.RFC3339)
+	}
+	if providerName == feeds.ProviderName {
+		log.Printf("Feeds: %d, From: %s", len(req.Sources), fromDescription)
+	} else {
+		log.Printf("Endpoint: '%s', Query: '%s', Country: '%s', From: %s", 
+			req.Endpoint, req.Query, req.Country, fromDescription)
+	}
 	log.Printf("Output Directory: '%s'", cfg.OutputDir)
+	if cfg.StorageURL != "" {
+		log.Printf("Storage: '%s'", cfg.StorageURL)
+	}
 	log.Printf("Kafka Broker: '%s', Topic: '%s'", cfg.KafkaBroker, cfg.KafkaTopic)
 
 	// Create news downloader
-	downloader, err := newsapi.NewNewsDownloaderWithDefaults(cfg)
+	downloader, err := newDownloader(cfg, providerName)
 	if err != nil {
 		log.Fatalf("Failed to create news downloader: %v", err)
 	}
@@ -74,7 +131,13 @@ func main() {
 	downloadCtx, downloadCancel := context.WithTimeout(ctx, 30*time.Minute)
 	defer downloadCancel()
 
-	result, err := downloader.DownloadAllNewsToFile(downloadCtx, req)
+	var result *newsapi.DownloadResult
+	if resume, _ := strconv.ParseBool(os.Getenv("NEWS_RESUME")); resume {
+		log.Printf("Resuming from checkpoint if one exists")
+		result, err = downloader.ResumeDownload(downloadCtx, req)
+	} else {
+		result, err = downloader.DownloadAllNewsToFile(downloadCtx, req)
+	}
 	if err != nil {
 		log.Fatalf("Failed to download news: %v", err)
 	}
@@ -93,6 +156,80 @@ func main() {
 	fmt.Println("Pipeline finished successfully!")
 }
 
+// buildDownloadRequest creates the download request described by the NEWS_* environment variables
+func buildDownloadRequest(cfg *config.Config, apiKey string, defaultEndpoint newsapi.Endpoint) (*newsapi.DownloadRequest, error) {
+	endpoint := newsapi.Endpoint(getEnvWithDefault("NEWS_ENDPOINT", string(defaultEndpoint)))
+	sources := splitList(os.Getenv("NEWS_SOURCES"))
+
+	// Country only applies to top-headlines and cannot be combined with sources
+	country := ""
+	if endpoint == newsapi.EndpointTopHeadlines && len(sources) == 0 {
+		country = getEnvWithDefault("NEWS_COUNTRY", "us")
+	}
+
+	// An explicit start overrides the query's watermark from previous runs
+	var from time.Time
+	if val := os.Getenv("NEWS_FROM"); val != "" {
+		parsed, err := parseTime(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NEWS_FROM value '%s': %w", val, err)
+		}
+		from = parsed
+	}
+
+	req := newsapi.NewDownloadRequest(apiKey, country)
+	req.Endpoint = endpoint
+	req.Query = os.Getenv("NEWS_QUERY")
+	req.Category = os.Getenv("NEWS_CATEGORY")
+	req.Sources = sources
+	req.Domains = splitList(os.Getenv("NEWS_DOMAINS"))
+	req.Language = os.Getenv("NEWS_LANGUAGE")
+	req.From = from
+	req.PageSize = cfg.MaxPageSize
+
+	return req, nil
+}
+
+// buildFeedRequest creates a request for the RSS/Atom feeds listed in NEWS_FEEDS
+func buildFeedRequest(cfg *config.Config) (*newsapi.DownloadRequest, error) {
+	feedURLs := splitList(os.Getenv("NEWS_FEEDS"))
+	if len(feedURLs) == 0 {
+		return nil, fmt.Errorf("NEWS_FEEDS must list at least one feed URL")
+	}
+
+	var from time.Time
+	if val := os.Getenv("NEWS_FROM"); val != "" {
+		parsed, err := parseTime(val)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NEWS_FROM value '%s': %w", val, err)
+		}
+		from = parsed
+	}
+
+	req := newsapi.NewDownloadRequest("", "")
+	req.Sources = feedURLs
+	req.From = from
+	req.PageSize = cfg.MaxPageSize
+
+	return req, nil
+}
+
+// newDownloader creates a downloader for the named provider
+func newDownloader(cfg *config.Config, providerName string) (*newsapi.NewsDownloader, error) {
+	switch providerName {
+	case newsapi.NewsAPIProviderName:
+		return newsapi.NewNewsDownloaderWithDefaults(cfg)
+	case feeds.ProviderName:
+		provider, err := feeds.NewProvider(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create feed provider: %w", err)
+		}
+		return newsapi.NewNewsDownloaderWithProvider(cfg, provider)
+	default:
+		return nil, fmt.Errorf("unknown NEWS_PROVIDER '%s'", providerName)
+	}
+}
+
 func loadConfiguration() (*config.Config, error) {
 	configPath := os.Getenv("CONFIG_PATH")
 	if configPath != "" {
@@ -121,6 +258,15 @@ func displayResults(result *newsapi.DownloadResult) {
 	fmt.Printf("Total Articles Found: %d\n", result.TotalArticles)
 	fmt.Printf("Pages Downloaded: %d\n", result.PagesDownloaded)
 	fmt.Printf("Files Created: %d\n", len(result.FilePaths))
+	fmt.Printf("Fetch Attempts: %d\n", result.TotalAttempts)
+	fmt.Printf("Duplicates Skipped: %d\n", result.DuplicatesSkipped)
+	fmt.Printf("Window Start: %s\n", result.From.Format(time.RFC3339))
+	if !result.MaxPublishedAt.IsZero() {
+		fmt.Printf("Newest Article: %s\n", result.MaxPublishedAt.Format(time.RFC3339))
+	}
+	if result.ResumedFromPage > 0 {
+		fmt.Printf("Resumed From Page: %d\n", result.ResumedFromPage)
+	}
 	fmt.Printf("Start Time: %s\n", result.StartTime.Format("2006-01-02 15:04:05"))
 	fmt.Printf("End Time: %s\n", result.EndTime.Format("2006-01-02 15:04:05"))
 	fmt.Printf("Duration: %v\n", result.Duration.Round(time.Second))
@@ -132,6 +278,17 @@ func displayResults(result *newsapi.DownloadResult) {
 		}
 	}
 
+	if len(result.KeyUsage) > 0 {
+		fmt.Printf("\nAPI Key Usage:\n")
+		for _, usage := range result.KeyUsage {
+			fmt.Printf("  %s: %d requests, %d rate limited", usage.Key, usage.Requests, usage.RateLimited)
+			if !usage.ExhaustedUntil.IsZero() {
+				fmt.Printf(", exhausted until %s", usage.ExhaustedUntil.Format(time.RFC3339))
+			}
+			fmt.Println()
+		}
+	}
+
 	if len(result.Errors) > 0 {
 		fmt.Printf("\nErrors encountered: %d\n", len(result.Errors))
 	}
@@ -142,4 +299,23 @@ func getEnvWithDefault(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
+}
+
+// splitList splits a comma-separated environment value into its non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseTime parses a timestamp given either as RFC 3339 or as a plain date
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
 }
\ No newline at end of file
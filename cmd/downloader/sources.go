@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/newsapi"
+)
+
+// runSources implements the "sources" subcommand: it lists the publishers known to
+// NewsAPI, serving them from the on-disk cache under OutputDir when it is fresh enough
+func runSources(ctx context.Context, cfg *config.Config, apiKey string, args []string) error {
+	fs := flag.NewFlagSet("sources", flag.ContinueOnError)
+	format := fs.String("format", "table", "output format: table or json")
+	category := fs.String("category", "", "only list sources in this category")
+	language := fs.String("language", "", "only list sources in this language")
+	country := fs.String("country", "", "only list sources from this country")
+	maxAge := fs.Duration("max-age", 24*time.Hour, "maximum age of the cached listing before it is refetched")
+	refresh := fs.Bool("refresh", false, "ignore the cache and always fetch from the API")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *format != "table" && *format != "json" {
+		return fmt.Errorf("unsupported format '%s', expected table or json", *format)
+	}
+
+	req := &newsapi.SourcesRequest{
+		APIKey:   apiKey,
+		Category: *category,
+		Language: *language,
+		Country:  *country,
+	}
+
+	cachePath := newsapi.SourcesCachePath(cfg.OutputDir, req)
+
+	var entry *newsapi.SourcesCacheEntry
+	if !*refresh {
+		cached, err := newsapi.LoadSourcesCache(cachePath, *maxAge)
+		if err != nil {
+			log.Printf("Ignoring unreadable sources cache: %v", err)
+		}
+		entry = cached
+	}
+
+	if entry != nil {
+		log.Printf("Using cached sources from %s (fetched %s)", cachePath, entry.FetchedAt.Format(time.RFC3339))
+	} else {
+		client := newsapi.NewNewsAPIClient(cfg)
+		sources, _, err := client.FetchSources(ctx, req)
+		if err != nil {
+			return fmt.Errorf("failed to fetch sources: %w", err)
+		}
+
+		entry = &newsapi.SourcesCacheEntry{
+			FetchedAt: time.Now(),
+			Category:  req.Category,
+			Language:  req.Language,
+			Country:   req.Country,
+			Sources:   sources,
+		}
+
+		if err := newsapi.SaveSourcesCache(cachePath, entry); err != nil {
+			log.Printf("Failed to cache sources: %v", err)
+		} else {
+			log.Printf("Cached %d sources to %s", len(sources), cachePath)
+		}
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entry.Sources)
+	}
+
+	return printSourcesTable(entry.Sources)
+}
+
+// printSourcesTable writes sources to stdout as an aligned table
+func printSourcesTable(sources []newsapi.SourceInfo) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCATEGORY\tLANGUAGE\tCOUNTRY\tURL")
+	for _, s := range sources {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.ID, s.Name, s.Category, s.Language, s.Country, s.URL)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("\n%d sources\n", len(sources))
+	return nil
+}
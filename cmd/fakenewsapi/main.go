@@ -0,0 +1,57 @@
+// Command fakenewsapi serves a fake NewsAPI on a local port, so the downloader
+// can be run end to end without network access by pointing NEWS_BASE_URL at it.
+package main
+
+import (
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"go-news-agg/internal/fakenewsapi"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8081", "address to listen on")
+	seed := flag.Int64("seed", 1, "seed for the generated corpus")
+	articles := flag.Int("articles", fakenewsapi.DefaultCorpusSize, "number of articles in the corpus")
+	keys := flag.String("keys", "", "comma-separated API keys to accept; any key is accepted when empty")
+	exhaustedKeys := flag.String("exhausted-keys", "", "comma-separated API keys to answer with apiKeyExhausted")
+	quota := flag.Int("quota", 0, "requests each key may make per window before 429s; 0 means unlimited")
+	window := flag.Duration("window", fakenewsapi.DefaultWindow, "period over which the quota is counted")
+	maxResults := flag.Int("max-results", fakenewsapi.DefaultMaxResults, "how deep pagination may reach; negative means unlimited")
+	flag.Parse()
+
+	server := fakenewsapi.NewServer(fakenewsapi.Options{
+		Seed:              *seed,
+		CorpusSize:        *articles,
+		APIKeys:           splitList(*keys),
+		ExhaustedKeys:     splitList(*exhaustedKeys),
+		RequestsPerWindow: *quota,
+		Window:            *window,
+		MaxResults:        *maxResults,
+	})
+
+	listener, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", *addr, err)
+	}
+
+	log.Printf("Fake NewsAPI serving %d articles from %d sources", len(server.Corpus().Articles), len(server.Corpus().Sources))
+	log.Printf("Set NEWS_BASE_URL=http://%s/v2 to point the downloader at it", listener.Addr())
+	if err := http.Serve(listener, server); err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
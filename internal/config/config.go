@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+
+	"go-news-agg/pkg/utils"
 )
 
 // Config holds all the application's configuration parameters
@@ -18,6 +22,64 @@ type Config struct {
 	TimeoutSeconds               int    `json:"timeout_seconds"`
 	MaxRetries                   int    `json:"max_retries"`
 	OutputDir                    string `json:"output_dir"`
+	DedupEnabled                 bool   `json:"dedup_enabled"`
+	DedupRetentionDays           int    `json:"dedup_retention_days"`
+	MaxPageableResults           int    `json:"max_pageable_results"`
+
+	// RequestsPerSecond and RequestsPerDay budget requests per API key before
+	// NewsAPI has to reject them. The free tier allows 100 requests a day; zero
+	// disables either limit.
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	RequestsPerDay    int     `json:"requests_per_day"`
+
+	// Concurrency is how many pages of a query are fetched at once after the
+	// first; 0 or 1 fetches them one after another
+	Concurrency int `json:"concurrency"`
+
+	// HTTPCacheDir turns on an on-disk cache of API responses for development;
+	// entries older than HTTPCacheTTLSeconds are refetched, 0 keeps them forever
+	HTTPCacheDir        string `json:"http_cache_dir,omitempty"`
+	HTTPCacheTTLSeconds int    `json:"http_cache_ttl_seconds"`
+
+	// MaxResponseBytes caps the size of an API response body; larger responses
+	// are rejected rather than read into memory. 0 disables the check.
+	MaxResponseBytes int `json:"max_response_bytes"`
+
+	// OutputFormat is the file format pages are saved in: json (the API
+	// response as is), ndjson, csv or parquet (one row per article)
+	OutputFormat string `json:"output_format"`
+
+	// OutputCompression compresses saved pages as they are written: none, gzip
+	// or zstd. The codec's extension is appended to the file name, e.g. .json.gz
+	OutputCompression string `json:"output_compression"`
+
+	// OutputPathTemplate lays out saved pages, e.g.
+	// {provider}/{endpoint}/{country|query-slug}/{yyyy}/{mm}/{dd}/{run_id}_p{page}.{ext};
+	// see utils.PathTemplate. Empty means YYYY/MM/<run ID>_<country>_page<N>.<ext>;
+	// utils.LegacyPathTemplate keeps the older <timestamp>_<country> file names.
+	// OutputPathHive names directory placeholders Hive-style, e.g. year=2024.
+	OutputPathTemplate string `json:"output_path_template,omitempty"`
+	OutputPathHive     bool   `json:"output_path_hive"`
+
+	// StorageURL is where pages are saved: empty for OutputDir, file:///path for
+	// another directory or s3://bucket/prefix for an S3-compatible bucket. State
+	// such as checkpoints and the dedup store always stays under OutputDir.
+	StorageURL string `json:"storage_url,omitempty"`
+
+	// S3Endpoint, S3Region and S3PathStyle locate the service behind an s3://
+	// StorageURL; the endpoint defaults to AWS. Credentials fall back to the
+	// standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+	S3Endpoint        string `json:"s3_endpoint,omitempty"`
+	S3Region          string `json:"s3_region"`
+	S3PathStyle       bool   `json:"s3_path_style"`
+	S3AccessKeyID     string `json:"s3_access_key_id,omitempty"`
+	S3SecretAccessKey string `json:"s3_secret_access_key,omitempty"`
+	S3SessionToken    string `json:"s3_session_token,omitempty"`
+
+	// APIKeys and APIKeysFile list extra NewsAPI keys to rotate through when one
+	// runs out of quota. The file holds one key per line; '#' starts a comment.
+	APIKeys     []string `json:"api_keys,omitempty"`
+	APIKeysFile string   `json:"api_keys_file,omitempty"`
 }
 
 // DefaultConfig returns a configuration with sensible defaults
@@ -31,6 +93,16 @@ func DefaultConfig() *Config {
 		TimeoutSeconds:               30,
 		MaxRetries:                   3,
 		OutputDir:                    "/tmp/news_downloads",
+		DedupEnabled:                 true,
+		DedupRetentionDays:           30,
+		MaxPageableResults:           100,
+		RequestsPerSecond:            2,
+		Concurrency:                  4,
+		HTTPCacheTTLSeconds:          3600,
+		MaxResponseBytes:             10 << 20,
+		OutputFormat:                 "json",
+		OutputCompression:            "none",
+		S3Region:                     "us-east-1",
 	}
 }
 
@@ -111,6 +183,118 @@ func LoadConfigFromEnv() *Config {
 		cfg.OutputDir = val
 	}
 
+	if val := os.Getenv("NEWS_DEDUP_ENABLED"); val != "" {
+		if parsed, err := strconv.ParseBool(val); err == nil {
+			cfg.DedupEnabled = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_DEDUP_RETENTION_DAYS"); val != "" {
+		if parsed, err := parseIntFromEnv(val); err == nil && parsed >= 0 {
+			cfg.DedupRetentionDays = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_MAX_PAGEABLE_RESULTS"); val != "" {
+		if parsed, err := parseIntFromEnv(val); err == nil && parsed >= 0 {
+			cfg.MaxPageableResults = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_REQUESTS_PER_SECOND"); val != "" {
+		if parsed, err := strconv.ParseFloat(val, 64); err == nil && parsed >= 0 {
+			cfg.RequestsPerSecond = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_REQUESTS_PER_DAY"); val != "" {
+		if parsed, err := parseIntFromEnv(val); err == nil && parsed >= 0 {
+			cfg.RequestsPerDay = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_CONCURRENCY"); val != "" {
+		if parsed, err := parseIntFromEnv(val); err == nil && parsed >= 0 {
+			cfg.Concurrency = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_HTTP_CACHE_DIR"); val != "" {
+		cfg.HTTPCacheDir = val
+	}
+
+	if val := os.Getenv("NEWS_HTTP_CACHE_TTL"); val != "" {
+		if parsed, err := parseIntFromEnv(val); err == nil && parsed >= 0 {
+			cfg.HTTPCacheTTLSeconds = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_MAX_RESPONSE_BYTES"); val != "" {
+		if parsed, err := parseIntFromEnv(val); err == nil && parsed >= 0 {
+			cfg.MaxResponseBytes = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_OUTPUT_FORMAT"); val != "" {
+		cfg.OutputFormat = strings.ToLower(val)
+	}
+
+	if val := os.Getenv("NEWS_OUTPUT_COMPRESSION"); val != "" {
+		cfg.OutputCompression = strings.ToLower(val)
+	}
+
+	if val := os.Getenv("NEWS_OUTPUT_PATH_TEMPLATE"); val != "" {
+		cfg.OutputPathTemplate = val
+	}
+
+	if val := os.Getenv("NEWS_OUTPUT_PATH_HIVE"); val != "" {
+		if parsed, err := strconv.ParseBool(val); err == nil {
+			cfg.OutputPathHive = parsed
+		}
+	}
+
+	if val := os.Getenv("NEWS_STORAGE_URL"); val != "" {
+		cfg.StorageURL = val
+	}
+
+	if val := os.Getenv("NEWS_S3_ENDPOINT"); val != "" {
+		cfg.S3Endpoint = val
+	}
+
+	if val := os.Getenv("NEWS_S3_REGION"); val != "" {
+		cfg.S3Region = val
+	}
+
+	if val := os.Getenv("NEWS_S3_PATH_STYLE"); val != "" {
+		if parsed, err := strconv.ParseBool(val); err == nil {
+			cfg.S3PathStyle = parsed
+		}
+	}
+
+	if val := os.Getenv("AWS_ACCESS_KEY_ID"); val != "" {
+		cfg.S3AccessKeyID = val
+	}
+
+	if val := os.Getenv("AWS_SECRET_ACCESS_KEY"); val != "" {
+		cfg.S3SecretAccessKey = val
+	}
+
+	if val := os.Getenv("AWS_SESSION_TOKEN"); val != "" {
+		cfg.S3SessionToken = val
+	}
+
+	if val := os.Getenv("NEWSAPI_KEYS"); val != "" {
+		for _, key := range strings.Split(val, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				cfg.APIKeys = append(cfg.APIKeys, key)
+			}
+		}
+	}
+
+	if val := os.Getenv("NEWSAPI_KEYS_FILE"); val != "" {
+		cfg.APIKeysFile = val
+	}
+
 	return cfg
 }
 
@@ -148,9 +332,107 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("output_dir cannot be empty")
 	}
 
+	if c.DedupRetentionDays < 0 {
+		return fmt.Errorf("dedup_retention_days cannot be negative, got %d", c.DedupRetentionDays)
+	}
+
+	if c.MaxPageableResults < 0 {
+		return fmt.Errorf("max_pageable_results cannot be negative, got %d", c.MaxPageableResults)
+	}
+
+	if c.RequestsPerSecond < 0 {
+		return fmt.Errorf("requests_per_second cannot be negative, got %g", c.RequestsPerSecond)
+	}
+
+	if c.RequestsPerDay < 0 {
+		return fmt.Errorf("requests_per_day cannot be negative, got %d", c.RequestsPerDay)
+	}
+
+	if c.Concurrency < 0 {
+		return fmt.Errorf("concurrency cannot be negative, got %d", c.Concurrency)
+	}
+
+	if c.HTTPCacheTTLSeconds < 0 {
+		return fmt.Errorf("http_cache_ttl_seconds cannot be negative, got %d", c.HTTPCacheTTLSeconds)
+	}
+
+	if c.MaxResponseBytes < 0 {
+		return fmt.Errorf("max_response_bytes cannot be negative, got %d", c.MaxResponseBytes)
+	}
+
+	switch c.OutputFormat {
+	case "", "json", "ndjson", "csv", "parquet":
+	default:
+		return fmt.Errorf("output_format must be one of json, ndjson, csv or parquet, got '%s'", c.OutputFormat)
+	}
+
+	switch c.OutputCompression {
+	case "", "none", "gzip", "zstd":
+	default:
+		return fmt.Errorf("output_compression must be one of none, gzip or zstd, got '%s'", c.OutputCompression)
+	}
+
+	if _, err := utils.ParsePathTemplate(c.OutputPathTemplate, c.OutputPathHive); err != nil {
+		return fmt.Errorf("output_path_template is invalid: %w", err)
+	}
+
+	if c.StorageURL != "" {
+		location, err := url.Parse(c.StorageURL)
+		if err != nil {
+			return fmt.Errorf("storage_url is invalid: %w", err)
+		}
+		switch {
+		case location.Scheme == "file" && location.Path != "":
+		case location.Scheme == "s3" && location.Host != "":
+			if c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+				return fmt.Errorf("s3_access_key_id and s3_secret_access_key are required for an s3:// storage_url")
+			}
+		default:
+			return fmt.Errorf("storage_url must be file:///path or s3://bucket[/prefix], got '%s'", c.StorageURL)
+		}
+	}
+
+	for i, key := range c.APIKeys {
+		if strings.TrimSpace(key) == "" {
+			return fmt.Errorf("api_keys entry %d cannot be empty", i+1)
+		}
+	}
+
 	return nil
 }
 
+// APIKeyList returns every configured NewsAPI key: primary first, then APIKeys,
+// then the keys in APIKeysFile. Blank entries and duplicates are dropped.
+func (c *Config) APIKeyList(primary string) ([]string, error) {
+	candidates := append([]string{primary}, c.APIKeys...)
+
+	if c.APIKeysFile != "" {
+		data, err := ioutil.ReadFile(c.APIKeysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API keys file '%s': %w", c.APIKeysFile, err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if i := strings.Index(line, "#"); i >= 0 {
+				line = line[:i]
+			}
+			candidates = append(candidates, line)
+		}
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range candidates {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
 // SaveConfig saves the configuration to a JSON file
 func (c *Config) SaveConfig(filePath string) error {
 	if err := c.Validate(); err != nil {
@@ -48,6 +48,50 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default OutputDir, got '%s'", cfg.OutputDir)
 	}
 
+	if !cfg.DedupEnabled {
+		t.Error("Expected DedupEnabled to default to true")
+	}
+
+	if cfg.DedupRetentionDays != 30 {
+		t.Errorf("Expected DedupRetentionDays 30, got %d", cfg.DedupRetentionDays)
+	}
+
+	if cfg.MaxPageableResults != 100 {
+		t.Errorf("Expected MaxPageableResults 100, got %d", cfg.MaxPageableResults)
+	}
+
+	if cfg.HTTPCacheDir != "" || cfg.HTTPCacheTTLSeconds != 3600 {
+		t.Errorf("Expected HTTP cache off with a 3600s TTL, got '%s' and %d", cfg.HTTPCacheDir, cfg.HTTPCacheTTLSeconds)
+	}
+
+	if cfg.Concurrency != 4 {
+		t.Errorf("Expected Concurrency 4, got %d", cfg.Concurrency)
+	}
+
+	if cfg.MaxResponseBytes != 10<<20 {
+		t.Errorf("Expected MaxResponseBytes %d, got %d", 10<<20, cfg.MaxResponseBytes)
+	}
+
+	if cfg.OutputFormat != "json" {
+		t.Errorf("Expected OutputFormat 'json', got '%s'", cfg.OutputFormat)
+	}
+
+	if cfg.OutputCompression != "none" {
+		t.Errorf("Expected OutputCompression 'none', got '%s'", cfg.OutputCompression)
+	}
+
+	if cfg.OutputPathTemplate != "" || cfg.OutputPathHive {
+		t.Errorf("Expected the default path layout, got '%s' (hive %t)", cfg.OutputPathTemplate, cfg.OutputPathHive)
+	}
+
+	if cfg.StorageURL != "" || cfg.S3Region != "us-east-1" {
+		t.Errorf("Expected local storage and region us-east-1, got '%s' and '%s'", cfg.StorageURL, cfg.S3Region)
+	}
+
+	if cfg.RequestsPerSecond != 2 || cfg.RequestsPerDay != 0 {
+		t.Errorf("Expected 2 requests per second and no daily budget, got %g and %d", cfg.RequestsPerSecond, cfg.RequestsPerDay)
+	}
+
 	// Validate that default config passes validation
 	if err := cfg.Validate(); err != nil {
 		t.Errorf("Default config should be valid, got error: %v", err)
@@ -230,6 +274,28 @@ func TestLoadConfigFromEnv(t *testing.T) {
 		"NEWS_TIMEOUT",
 		"NEWS_MAX_RETRIES",
 		"NEWS_OUTPUT_DIR",
+		"NEWS_DEDUP_ENABLED",
+		"NEWS_DEDUP_RETENTION_DAYS",
+		"NEWS_MAX_PAGEABLE_RESULTS",
+		"NEWSAPI_KEYS",
+		"NEWSAPI_KEYS_FILE",
+		"NEWS_REQUESTS_PER_SECOND",
+		"NEWS_REQUESTS_PER_DAY",
+		"NEWS_CONCURRENCY",
+		"NEWS_HTTP_CACHE_DIR",
+		"NEWS_HTTP_CACHE_TTL",
+		"NEWS_MAX_RESPONSE_BYTES",
+		"NEWS_OUTPUT_FORMAT",
+		"NEWS_OUTPUT_COMPRESSION",
+		"NEWS_OUTPUT_PATH_TEMPLATE",
+		"NEWS_OUTPUT_PATH_HIVE",
+		"NEWS_STORAGE_URL",
+		"NEWS_S3_ENDPOINT",
+		"NEWS_S3_REGION",
+		"NEWS_S3_PATH_STYLE",
+		"AWS_ACCESS_KEY_ID",
+		"AWS_SECRET_ACCESS_KEY",
+		"AWS_SESSION_TOKEN",
 	}
 
 	for _, envVar := range envVars {
@@ -265,14 +331,36 @@ func TestLoadConfigFromEnv(t *testing.T) {
 		{
 			name: "valid environment variables",
 			envVars: map[string]string{
-				"NEWS_MAX_PAGE_SIZE":    "50",
-				"NEWS_BASE_URL":         "https://custom.newsapi.org",
-				"NEWS_RATE_LIMIT_DELAY": "120",
-				"KAFKA_BROKER":          "custom-broker:9092",
-				"KAFKA_TOPIC":           "custom_topic",
-				"NEWS_TIMEOUT":          "60",
-				"NEWS_MAX_RETRIES":      "5",
-				"NEWS_OUTPUT_DIR":       "/custom/output",
+				"NEWS_MAX_PAGE_SIZE":        "50",
+				"NEWS_BASE_URL":             "https://custom.newsapi.org",
+				"NEWS_RATE_LIMIT_DELAY":     "120",
+				"KAFKA_BROKER":              "custom-broker:9092",
+				"KAFKA_TOPIC":               "custom_topic",
+				"NEWS_TIMEOUT":              "60",
+				"NEWS_MAX_RETRIES":          "5",
+				"NEWS_OUTPUT_DIR":           "/custom/output",
+				"NEWS_DEDUP_ENABLED":        "false",
+				"NEWS_DEDUP_RETENTION_DAYS": "7",
+				"NEWS_MAX_PAGEABLE_RESULTS": "1000",
+				"NEWSAPI_KEYS":              "key-one, key-two,,",
+				"NEWSAPI_KEYS_FILE":         "/etc/news/keys.txt",
+				"NEWS_REQUESTS_PER_SECOND":  "0.5",
+				"NEWS_REQUESTS_PER_DAY":     "100",
+				"NEWS_CONCURRENCY":          "8",
+				"NEWS_HTTP_CACHE_DIR":       "/tmp/news_cache",
+				"NEWS_HTTP_CACHE_TTL":       "0",
+				"NEWS_MAX_RESPONSE_BYTES":   "1048576",
+				"NEWS_OUTPUT_FORMAT":        "Parquet",
+				"NEWS_OUTPUT_COMPRESSION":   "ZSTD",
+				"NEWS_OUTPUT_PATH_TEMPLATE": "{provider}/{yyyy}/{run_id}_p{page}.{ext}",
+				"NEWS_OUTPUT_PATH_HIVE":     "true",
+				"NEWS_STORAGE_URL":          "s3://news-pages/raw",
+				"NEWS_S3_ENDPOINT":          "http://localhost:9000",
+				"NEWS_S3_REGION":            "eu-west-1",
+				"NEWS_S3_PATH_STYLE":        "true",
+				"AWS_ACCESS_KEY_ID":         "minio",
+				"AWS_SECRET_ACCESS_KEY":     "minio-secret",
+				"AWS_SESSION_TOKEN":         "session",
 			},
 			expected: map[string]interface{}{
 				"MaxPageSize":                  50,
@@ -283,6 +371,28 @@ func TestLoadConfigFromEnv(t *testing.T) {
 				"TimeoutSeconds":               60,
 				"MaxRetries":                   5,
 				"OutputDir":                    "/custom/output",
+				"DedupEnabled":                 false,
+				"DedupRetentionDays":           7,
+				"MaxPageableResults":           1000,
+				"APIKeys":                      "key-one,key-two",
+				"APIKeysFile":                  "/etc/news/keys.txt",
+				"RequestsPerSecond":            0.5,
+				"RequestsPerDay":               100,
+				"Concurrency":                  8,
+				"HTTPCacheDir":                 "/tmp/news_cache",
+				"HTTPCacheTTLSeconds":          0,
+				"MaxResponseBytes":             1048576,
+				"OutputFormat":                 "parquet",
+				"OutputCompression":            "zstd",
+				"OutputPathTemplate":           "{provider}/{yyyy}/{run_id}_p{page}.{ext}",
+				"OutputPathHive":               true,
+				"StorageURL":                   "s3://news-pages/raw",
+				"S3Endpoint":                   "http://localhost:9000",
+				"S3Region":                     "eu-west-1",
+				"S3PathStyle":                  true,
+				"S3AccessKeyID":                "minio",
+				"S3SecretAccessKey":            "minio-secret",
+				"S3SessionToken":               "session",
 			},
 		},
 		{
@@ -353,6 +463,50 @@ func TestLoadConfigFromEnv(t *testing.T) {
 					actualValue = cfg.MaxRetries
 				case "OutputDir":
 					actualValue = cfg.OutputDir
+				case "DedupEnabled":
+					actualValue = cfg.DedupEnabled
+				case "DedupRetentionDays":
+					actualValue = cfg.DedupRetentionDays
+				case "MaxPageableResults":
+					actualValue = cfg.MaxPageableResults
+				case "APIKeys":
+					actualValue = strings.Join(cfg.APIKeys, ",")
+				case "APIKeysFile":
+					actualValue = cfg.APIKeysFile
+				case "RequestsPerSecond":
+					actualValue = cfg.RequestsPerSecond
+				case "RequestsPerDay":
+					actualValue = cfg.RequestsPerDay
+				case "Concurrency":
+					actualValue = cfg.Concurrency
+				case "HTTPCacheDir":
+					actualValue = cfg.HTTPCacheDir
+				case "HTTPCacheTTLSeconds":
+					actualValue = cfg.HTTPCacheTTLSeconds
+				case "MaxResponseBytes":
+					actualValue = cfg.MaxResponseBytes
+				case "OutputFormat":
+					actualValue = cfg.OutputFormat
+				case "OutputCompression":
+					actualValue = cfg.OutputCompression
+				case "OutputPathTemplate":
+					actualValue = cfg.OutputPathTemplate
+				case "OutputPathHive":
+					actualValue = cfg.OutputPathHive
+				case "StorageURL":
+					actualValue = cfg.StorageURL
+				case "S3Endpoint":
+					actualValue = cfg.S3Endpoint
+				case "S3Region":
+					actualValue = cfg.S3Region
+				case "S3PathStyle":
+					actualValue = cfg.S3PathStyle
+				case "S3AccessKeyID":
+					actualValue = cfg.S3AccessKeyID
+				case "S3SecretAccessKey":
+					actualValue = cfg.S3SecretAccessKey
+				case "S3SessionToken":
+					actualValue = cfg.S3SessionToken
 				default:
 					t.Errorf("Unknown field: %s", field)
 					continue
@@ -513,6 +667,215 @@ func TestConfigValidate(t *testing.T) {
 			wantErr: true,
 			errMsg:  "output_dir cannot be empty",
 		},
+		{
+			name: "negative dedup retention",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				DedupRetentionDays:           -1,
+			},
+			wantErr: true,
+			errMsg:  "dedup_retention_days cannot be negative",
+		},
+		{
+			name: "negative max pageable results",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				MaxPageableResults:           -1,
+			},
+			wantErr: true,
+			errMsg:  "max_pageable_results cannot be negative",
+		},
+		{
+			name: "empty api key entry",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				APIKeys:                      []string{"key-one", " "},
+			},
+			wantErr: true,
+			errMsg:  "api_keys entry 2 cannot be empty",
+		},
+		{
+			name: "negative daily request budget",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				RequestsPerDay:               -1,
+			},
+			wantErr: true,
+			errMsg:  "requests_per_day cannot be negative",
+		},
+		{
+			name: "negative concurrency",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				Concurrency:                  -1,
+			},
+			wantErr: true,
+			errMsg:  "concurrency cannot be negative",
+		},
+		{
+			name: "negative http cache ttl",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				HTTPCacheTTLSeconds:          -1,
+			},
+			wantErr: true,
+			errMsg:  "http_cache_ttl_seconds cannot be negative",
+		},
+		{
+			name: "negative max response bytes",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				MaxResponseBytes:             -1,
+			},
+			wantErr: true,
+			errMsg:  "max_response_bytes cannot be negative",
+		},
+		{
+			name: "unknown output format",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				OutputFormat:                 "xml",
+			},
+			wantErr: true,
+			errMsg:  "output_format must be one of",
+		},
+		{
+			name: "unknown output compression",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				OutputCompression:            "bzip2",
+			},
+			wantErr: true,
+			errMsg:  "output_compression must be one of",
+		},
+		{
+			name: "path template without page",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				OutputPathTemplate:           "{yyyy}/{country}.{ext}",
+			},
+			wantErr: true,
+			errMsg:  "output_path_template is invalid",
+		},
+		{
+			name: "unsupported storage URL",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				StorageURL:                   "ftp://files.example.com/news",
+			},
+			wantErr: true,
+			errMsg:  "storage_url must be",
+		},
+		{
+			name: "s3 storage without credentials",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				StorageURL:                   "s3://news-pages",
+			},
+			wantErr: true,
+			errMsg:  "s3_access_key_id and s3_secret_access_key are required",
+		},
+		{
+			name: "s3 storage with credentials",
+			config: &Config{
+				MaxPageSize:                  20,
+				BaseURL:                      "https://newsapi.org",
+				DefaultRateLimitDelaySeconds: 60,
+				KafkaBroker:                  "localhost:9092",
+				KafkaTopic:                   "news",
+				TimeoutSeconds:               30,
+				MaxRetries:                   3,
+				OutputDir:                    "/tmp",
+				StorageURL:                   "s3://news-pages/raw",
+				S3AccessKeyID:                "minio",
+				S3SecretAccessKey:            "minio-secret",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -537,6 +900,38 @@ func TestConfigValidate(t *testing.T) {
 	}
 }
 
+func TestAPIKeyList(t *testing.T) {
+	keysFile := filepath.Join(t.TempDir(), "keys.txt")
+	content := "# rotation pool\nkey-three\n\nkey-two  # duplicate of config\nkey-four\n"
+	if err := ioutil.WriteFile(keysFile, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write keys file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.APIKeys = []string{"key-two", "key-one"}
+	cfg.APIKeysFile = keysFile
+
+	keys, err := cfg.APIKeyList("key-one")
+	if err != nil {
+		t.Fatalf("APIKeyList() unexpected error: %v", err)
+	}
+
+	expected := "key-one,key-two,key-three,key-four"
+	if got := strings.Join(keys, ","); got != expected {
+		t.Errorf("Expected keys %s, got %s", expected, got)
+	}
+
+	keys, err = DefaultConfig().APIKeyList("")
+	if err != nil || len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v, %v", keys, err)
+	}
+
+	cfg.APIKeysFile = filepath.Join(t.TempDir(), "missing.txt")
+	if _, err := cfg.APIKeyList("key-one"); err == nil {
+		t.Error("APIKeyList() expected error for missing keys file")
+	}
+}
+
 func TestSaveConfig(t *testing.T) {
 	cfg := DefaultConfig()
 	cfg.MaxPageSize = 75
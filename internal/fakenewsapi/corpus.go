@@ -0,0 +1,133 @@
+package fakenewsapi
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"go-news-agg/internal/newsapi"
+)
+
+// Corpus is the fixed set of sources and articles a Server answers from.
+// Articles are ordered newest first.
+type Corpus struct {
+	Sources  []newsapi.SourceInfo
+	Articles []newsapi.Article
+}
+
+// corpusSources are the publishers every generated corpus is drawn from
+var corpusSources = []newsapi.SourceInfo{
+	{ID: "abc-news", Name: "ABC News", URL: "https://abcnews.go.com", Category: "general", Language: "en", Country: "us"},
+	{ID: "ars-technica", Name: "Ars Technica", URL: "https://arstechnica.com", Category: "technology", Language: "en", Country: "us"},
+	{ID: "bbc-news", Name: "BBC News", URL: "https://www.bbc.co.uk/news", Category: "general", Language: "en", Country: "gb"},
+	{ID: "bloomberg", Name: "Bloomberg", URL: "https://www.bloomberg.com", Category: "business", Language: "en", Country: "us"},
+	{ID: "der-spiegel", Name: "Der Spiegel", URL: "https://www.spiegel.de", Category: "general", Language: "de", Country: "de"},
+	{ID: "entertainment-weekly", Name: "Entertainment Weekly", URL: "https://www.ew.com", Category: "entertainment", Language: "en", Country: "us"},
+	{ID: "espn", Name: "ESPN", URL: "https://www.espn.com", Category: "sports", Language: "en", Country: "us"},
+	{ID: "le-monde", Name: "Le Monde", URL: "https://www.lemonde.fr", Category: "general", Language: "fr", Country: "fr"},
+	{ID: "medical-news-today", Name: "Medical News Today", URL: "https://www.medicalnewstoday.com", Category: "health", Language: "en", Country: "us"},
+	{ID: "new-scientist", Name: "New Scientist", URL: "https://www.newscientist.com", Category: "science", Language: "en", Country: "gb"},
+	{ID: "reuters", Name: "Reuters", URL: "https://www.reuters.com", Category: "general", Language: "en", Country: "us"},
+	{ID: "techcrunch", Name: "TechCrunch", URL: "https://techcrunch.com", Category: "technology", Language: "en", Country: "us"},
+}
+
+var (
+	corpusTopics = []string{
+		"artificial intelligence", "interest rates", "climate policy", "the election", "electric vehicles",
+		"the transfer window", "vaccine trials", "the Mars mission", "chip exports", "streaming prices",
+		"housing costs", "cybersecurity", "renewable energy", "the World Cup", "quantum computing",
+	}
+	corpusHeadlines = []string{
+		"What we know about %s so far",
+		"Experts weigh in on %s",
+		"New report raises questions over %s",
+		"Markets react to latest news on %s",
+		"Five things to watch in %s this week",
+		"Officials announce changes to %s",
+		"Analysis: why %s matters now",
+		"Live updates: %s",
+	}
+	corpusAuthors = []string{
+		"Alex Morgan", "Sam Patel", "Jordan Lee", "Casey Nguyen", "Riley Schmidt",
+		"Taylor Brooks", "Jamie Dubois", "Morgan Fischer", "",
+	}
+)
+
+// GenerateCorpus builds a corpus of size articles spread over the week before now.
+// The same seed always yields the same articles, relative to now.
+func GenerateCorpus(seed int64, size int, now time.Time) *Corpus {
+	rng := rand.New(rand.NewSource(seed))
+	now = now.UTC().Truncate(time.Second)
+
+	corpus := &Corpus{
+		Sources:  append([]newsapi.SourceInfo(nil), corpusSources...),
+		Articles: make([]newsapi.Article, 0, size),
+	}
+	for i := range corpus.Sources {
+		corpus.Sources[i].Description = fmt.Sprintf("The latest %s news from %s.", corpus.Sources[i].Category, corpus.Sources[i].Name)
+	}
+
+	for i := 0; i < size; i++ {
+		source := corpus.Sources[rng.Intn(len(corpus.Sources))]
+		topic := corpusTopics[rng.Intn(len(corpusTopics))]
+		title := fmt.Sprintf(corpusHeadlines[rng.Intn(len(corpusHeadlines))], topic)
+		title = strings.ToUpper(title[:1]) + title[1:]
+		publishedAt := now.Add(-time.Duration(rng.Int63n(int64(7 * 24 * time.Hour)))).Truncate(time.Second)
+		articleURL := fmt.Sprintf("%s/%s/%s-%d", source.URL, publishedAt.Format("2006/01/02"), slugify(title), i)
+
+		corpus.Articles = append(corpus.Articles, newsapi.Article{
+			Source:      newsapi.Source{ID: source.ID, Name: source.Name},
+			Author:      corpusAuthors[rng.Intn(len(corpusAuthors))],
+			Title:       title,
+			Description: fmt.Sprintf("%s reports on the latest developments in %s.", source.Name, topic),
+			URL:         articleURL,
+			URLToImage:  articleURL + ".jpg",
+			PublishedAt: publishedAt,
+			Content:     fmt.Sprintf("The story of %s continued to unfold on %s… [+%d chars]", topic, publishedAt.Format("January 2"), 1000+rng.Intn(4000)),
+		})
+	}
+
+	sort.SliceStable(corpus.Articles, func(i, j int) bool {
+		return corpus.Articles[i].PublishedAt.After(corpus.Articles[j].PublishedAt)
+	})
+
+	return corpus
+}
+
+// source returns the corpus source with id
+func (c *Corpus) source(id string) (newsapi.SourceInfo, bool) {
+	for _, source := range c.Sources {
+		if source.ID == id {
+			return source, true
+		}
+	}
+	return newsapi.SourceInfo{}, false
+}
+
+// slugify turns a headline into the lower-case, dash-separated form used in URLs
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// articleDomain returns the host of an article URL without a leading "www."
+func articleDomain(articleURL string) string {
+	parsed, err := url.Parse(articleURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+}
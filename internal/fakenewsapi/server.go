@@ -0,0 +1,548 @@
+// Package fakenewsapi is an in-process stand-in for newsapi.org. It serves
+// top-headlines, everything and sources from a seeded corpus with NewsAPI's
+// pagination, rate limit headers and error responses, so the downloader can be
+// exercised end to end without network access or API quota.
+package fakenewsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-news-agg/internal/newsapi"
+)
+
+const (
+	// DefaultCorpusSize is the number of articles generated when Options.CorpusSize is zero
+	DefaultCorpusSize = 200
+	// DefaultMaxResults is how deep pagination may reach, as on NewsAPI's developer plan
+	DefaultMaxResults = 100
+	// DefaultWindow is the period over which Options.RequestsPerWindow is counted
+	DefaultWindow = 24 * time.Hour
+
+	// maxPageSize is the largest pageSize NewsAPI accepts
+	maxPageSize = 100
+)
+
+// Options configures a Server
+type Options struct {
+	// Corpus is served as is; when nil one is generated from Seed and CorpusSize
+	Corpus     *Corpus
+	Seed       int64
+	CorpusSize int
+
+	// APIKeys are the keys the server accepts; when empty any key is accepted
+	APIKeys []string
+	// ExhaustedKeys are accepted but always answered with apiKeyExhausted
+	ExhaustedKeys []string
+
+	// RequestsPerWindow is each key's quota, answered with 429 rateLimited once
+	// spent; zero means unlimited and no rate limit headers are sent
+	RequestsPerWindow int
+	// Window is the quota period, DefaultWindow when zero
+	Window time.Duration
+
+	// MaxResults is how deep pagination may reach before maximumResultsReached,
+	// DefaultMaxResults when zero; a negative value removes the limit
+	MaxResults int
+
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+}
+
+// apiError is an error response in NewsAPI's format
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+// errorResponse is the body of an error response
+type errorResponse struct {
+	Status  string `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// articlesResponse is the body of a top-headlines or everything response
+type articlesResponse struct {
+	Status       string            `json:"status"`
+	TotalResults int               `json:"totalResults"`
+	Articles     []newsapi.Article `json:"articles"`
+}
+
+// sourcesResponse is the body of a sources response
+type sourcesResponse struct {
+	Status  string               `json:"status"`
+	Sources []newsapi.SourceInfo `json:"sources"`
+}
+
+// Server is a fake NewsAPI. It is an http.Handler serving the /v2 routes and is
+// safe for concurrent use.
+type Server struct {
+	corpus            *Corpus
+	keys              map[string]bool
+	exhausted         map[string]bool
+	requestsPerWindow int
+	window            time.Duration
+	maxResults        int
+	now               func() time.Time
+
+	mutex       sync.Mutex
+	windowStart time.Time
+	used        map[string]int
+	requests    int
+}
+
+// NewServer creates a Server from options
+func NewServer(options Options) *Server {
+	now := options.Now
+	if now == nil {
+		now = time.Now
+	}
+
+	corpus := options.Corpus
+	if corpus == nil {
+		size := options.CorpusSize
+		if size <= 0 {
+			size = DefaultCorpusSize
+		}
+		corpus = GenerateCorpus(options.Seed, size, now())
+	}
+
+	window := options.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+
+	maxResults := options.MaxResults
+	if maxResults == 0 {
+		maxResults = DefaultMaxResults
+	}
+
+	s := &Server{
+		corpus:            corpus,
+		keys:              make(map[string]bool),
+		exhausted:         make(map[string]bool),
+		requestsPerWindow: options.RequestsPerWindow,
+		window:            window,
+		maxResults:        maxResults,
+		now:               now,
+		windowStart:       now(),
+		used:              make(map[string]int),
+	}
+	for _, key := range options.APIKeys {
+		s.keys[key] = true
+	}
+	for _, key := range options.ExhaustedKeys {
+		s.exhausted[key] = true
+	}
+
+	return s
+}
+
+// Corpus returns the articles and sources the server answers from
+func (s *Server) Corpus() *Corpus {
+	return s.corpus
+}
+
+// Requests returns how many requests the server has received
+func (s *Server) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	s.requests++
+	s.mutex.Unlock()
+
+	if r.Method != http.MethodGet {
+		writeError(w, &apiError{http.StatusMethodNotAllowed, "methodNotAllowed", fmt.Sprintf("The %s method is not supported.", r.Method)})
+		return
+	}
+
+	var handle func(url.Values) (interface{}, *apiError)
+	switch strings.TrimSuffix(r.URL.Path, "/") {
+	case "/v2/top-headlines":
+		handle = s.topHeadlines
+	case "/v2/everything":
+		handle = s.everything
+	case "/v2/top-headlines/sources", "/v2/sources":
+		handle = s.sources
+	default:
+		writeError(w, &apiError{http.StatusNotFound, "routeNotFound", fmt.Sprintf("There is no endpoint at %s.", r.URL.Path)})
+		return
+	}
+
+	key := requestKey(r)
+	if apiErr := s.authorize(key); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	if apiErr := s.consume(w.Header(), key); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	body, apiErr := handle(r.URL.Query())
+	if apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	json.NewEncoder(w).Encode(body)
+}
+
+// authorize checks the request's API key
+func (s *Server) authorize(key string) *apiError {
+	if key == "" {
+		return &apiError{http.StatusUnauthorized, "apiKeyMissing", "Your API key is missing. Append this to the URL with the apiKey param, or use the x-api-key HTTP header."}
+	}
+	if s.exhausted[key] {
+		return &apiError{http.StatusTooManyRequests, newsapi.CodeAPIKeyExhausted, "Your API key has no more requests available."}
+	}
+	if len(s.keys) > 0 && !s.keys[key] {
+		return &apiError{http.StatusUnauthorized, "apiKeyInvalid", "Your API key is invalid or incorrect. Check your key, or go to https://newsapi.org to create a free API key."}
+	}
+	return nil
+}
+
+// consume counts a request against key's quota and sets the rate limit headers
+func (s *Server) consume(header http.Header, key string) *apiError {
+	if s.requestsPerWindow <= 0 {
+		return nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	if elapsed := now.Sub(s.windowStart); elapsed >= s.window {
+		s.windowStart = s.windowStart.Add(elapsed / s.window * s.window)
+		s.used = make(map[string]int)
+	}
+	reset := s.windowStart.Add(s.window)
+
+	header.Set("X-RateLimit-Limit", strconv.Itoa(s.requestsPerWindow))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+	if s.used[key] >= s.requestsPerWindow {
+		header.Set("X-RateLimit-Remaining", "0")
+		header.Set("Retry-After", strconv.Itoa(int(reset.Sub(now).Seconds()+0.5)))
+		return &apiError{http.StatusTooManyRequests, newsapi.CodeRateLimited, fmt.Sprintf(
+			"You have made too many requests recently. Accounts are limited to %d requests over a %s period. Please upgrade to a paid plan if you need more requests.",
+			s.requestsPerWindow, s.window)}
+	}
+
+	s.used[key]++
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(s.requestsPerWindow-s.used[key]))
+	return nil
+}
+
+// topHeadlines answers /v2/top-headlines
+func (s *Server) topHeadlines(params url.Values) (interface{}, *apiError) {
+	sources := splitList(params.Get("sources"))
+	country := params.Get("country")
+	category := params.Get("category")
+	query := params.Get("q")
+
+	if len(sources) > 0 && (country != "" || category != "") {
+		return nil, &apiError{http.StatusBadRequest, "parametersIncompatible", "You cannot mix the sources parameter with the country or category parameters."}
+	}
+	if len(sources) == 0 && country == "" && category == "" && query == "" {
+		return nil, &apiError{http.StatusBadRequest, "parametersMissing", "Required parameters are missing. Please set any of the following parameters and try again: sources, q, country, category."}
+	}
+
+	page, pageSize, apiErr := s.pagination(params, 20)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	wanted := stringSet(sources)
+	var matches []newsapi.Article
+	for _, article := range s.corpus.Articles {
+		source, _ := s.corpus.source(article.Source.ID)
+		if len(wanted) > 0 && !wanted[source.ID] {
+			continue
+		}
+		if (country != "" && source.Country != country) || (category != "" && source.Category != category) {
+			continue
+		}
+		if !matchesQuery(article, query, nil) {
+			continue
+		}
+		matches = append(matches, article)
+	}
+
+	return paginate(matches, page, pageSize), nil
+}
+
+// everything answers /v2/everything. Results are always ordered newest first,
+// whatever sortBy asks for.
+func (s *Server) everything(params url.Values) (interface{}, *apiError) {
+	query := params.Get("q")
+	sources := splitList(params.Get("sources"))
+	domains := splitList(params.Get("domains"))
+	excludeDomains := splitList(params.Get("excludeDomains"))
+	searchIn := splitList(params.Get("searchIn"))
+	language := params.Get("language")
+
+	if query == "" && len(sources) == 0 && len(domains) == 0 {
+		return nil, &apiError{http.StatusBadRequest, "parametersMissing", "Required parameters are missing, the scope of your search is too broad. Please set any of the following required parameters and try again: q, sources, domains."}
+	}
+
+	for _, field := range searchIn {
+		if field != "title" && field != "description" && field != "content" {
+			return nil, &apiError{http.StatusBadRequest, "parameterInvalid", fmt.Sprintf("The searchIn parameter contains an invalid value '%s'. Possible options: title, description, content.", field)}
+		}
+	}
+
+	switch sortBy := params.Get("sortBy"); sortBy {
+	case "", "relevancy", "popularity", "publishedAt":
+	default:
+		return nil, &apiError{http.StatusBadRequest, "parameterInvalid", fmt.Sprintf("The sortBy parameter has an invalid value '%s'. Possible options: relevancy, popularity, publishedAt.", sortBy)}
+	}
+
+	from, apiErr := parseTime(params, "from")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	to, apiErr := parseTime(params, "to")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	page, pageSize, apiErr := s.pagination(params, maxPageSize)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	wanted := stringSet(sources)
+	var matches []newsapi.Article
+	for _, article := range s.corpus.Articles {
+		source, _ := s.corpus.source(article.Source.ID)
+		if len(wanted) > 0 && !wanted[source.ID] {
+			continue
+		}
+		if language != "" && source.Language != language {
+			continue
+		}
+		domain := articleDomain(article.URL)
+		if len(domains) > 0 && !matchesDomain(domain, domains) {
+			continue
+		}
+		if matchesDomain(domain, excludeDomains) {
+			continue
+		}
+		if (!from.IsZero() && article.PublishedAt.Before(from)) || (!to.IsZero() && article.PublishedAt.After(to)) {
+			continue
+		}
+		if !matchesQuery(article, query, searchIn) {
+			continue
+		}
+		matches = append(matches, article)
+	}
+
+	return paginate(matches, page, pageSize), nil
+}
+
+// sources answers /v2/top-headlines/sources
+func (s *Server) sources(params url.Values) (interface{}, *apiError) {
+	category := params.Get("category")
+	language := params.Get("language")
+	country := params.Get("country")
+
+	resp := &sourcesResponse{Status: "ok", Sources: []newsapi.SourceInfo{}}
+	for _, source := range s.corpus.Sources {
+		if (category != "" && source.Category != category) ||
+			(language != "" && source.Language != language) ||
+			(country != "" && source.Country != country) {
+			continue
+		}
+		resp.Sources = append(resp.Sources, source)
+	}
+
+	sort.Slice(resp.Sources, func(i, j int) bool {
+		return resp.Sources[i].ID < resp.Sources[j].ID
+	})
+	return resp, nil
+}
+
+// pagination reads page and pageSize, rejecting pages beyond the server's result limit
+func (s *Server) pagination(params url.Values, defaultPageSize int) (page, pageSize int, apiErr *apiError) {
+	page, pageSize = 1, defaultPageSize
+
+	if value := params.Get("pageSize"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPageSize {
+			return 0, 0, &apiError{http.StatusBadRequest, "parameterInvalid", fmt.Sprintf("The pageSize parameter must be a number between 1 and %d.", maxPageSize)}
+		}
+		pageSize = parsed
+	}
+
+	if value := params.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, &apiError{http.StatusBadRequest, "parameterInvalid", "The page parameter must be a number greater than 0."}
+		}
+		page = parsed
+	}
+
+	if s.maxResults > 0 && page*pageSize > s.maxResults {
+		return 0, 0, &apiError{http.StatusUpgradeRequired, "maximumResultsReached", fmt.Sprintf(
+			"You have requested too many results. Accounts are limited to a max of %d results. You are trying to request results %d to %d. Please upgrade to a paid plan if you need more results.",
+			s.maxResults, (page-1)*pageSize, page*pageSize)}
+	}
+
+	return page, pageSize, nil
+}
+
+// paginate returns one page of matches, with totalResults counting all of them
+func paginate(matches []newsapi.Article, page, pageSize int) *articlesResponse {
+	start := (page - 1) * pageSize
+	if start > len(matches) {
+		start = len(matches)
+	}
+	end := start + pageSize
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	return &articlesResponse{
+		Status:       "ok",
+		TotalResults: len(matches),
+		Articles:     append([]newsapi.Article{}, matches[start:end]...),
+	}
+}
+
+// matchesQuery reports whether every term of query appears in the searched fields
+// of article. Terms are matched case-insensitively; a term prefixed with "-" must
+// not appear, and surrounding quotes and a "+" prefix are ignored.
+func matchesQuery(article newsapi.Article, query string, searchIn []string) bool {
+	if query == "" {
+		return true
+	}
+
+	fields := map[string]string{
+		"title":       article.Title,
+		"description": article.Description,
+		"content":     article.Content,
+	}
+	if len(searchIn) == 0 {
+		searchIn = []string{"title", "description", "content"}
+	}
+
+	var text strings.Builder
+	for _, field := range searchIn {
+		text.WriteString(strings.ToLower(fields[field]))
+		text.WriteByte('\n')
+	}
+	haystack := text.String()
+
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		exclude := strings.HasPrefix(term, "-")
+		term = strings.Trim(strings.TrimLeft(term, "+-"), `"`)
+		if term == "" || term == "and" {
+			continue
+		}
+		if strings.Contains(haystack, term) == exclude {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesDomain reports whether domain is one of domains or a subdomain of one
+func matchesDomain(domain string, domains []string) bool {
+	for _, candidate := range domains {
+		candidate = strings.TrimPrefix(strings.ToLower(candidate), "www.")
+		if domain == candidate || strings.HasSuffix(domain, "."+candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime reads an ISO 8601 date or date-time parameter
+func parseTime(params url.Values, name string) (time.Time, *apiError) {
+	value := params.Get(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, &apiError{http.StatusBadRequest, "parameterInvalid", fmt.Sprintf("The %s parameter is in an invalid format. Please use an ISO 8601 date or date-time, e.g. 2024-01-15 or 2024-01-15T09:30:00.", name)}
+}
+
+// requestKey returns the API key from the X-Api-Key header, the apiKey query
+// parameter or the Authorization header, in that order
+func requestKey(r *http.Request) string {
+	if key := r.Header.Get(newsapi.APIKeyHeader); key != "" {
+		return key
+	}
+	if key := r.URL.Query().Get("apiKey"); key != "" {
+		return key
+	}
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+}
+
+// writeError sends apiErr in NewsAPI's error format
+func writeError(w http.ResponseWriter, apiErr *apiError) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(apiErr.status)
+	json.NewEncoder(w).Encode(&errorResponse{Status: "error", Code: apiErr.code, Message: apiErr.message})
+}
+
+// splitList splits a comma-separated parameter, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// stringSet returns items as a set
+func stringSet(items []string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// TestServer is a Server listening on a local port, for tests
+type TestServer struct {
+	*httptest.Server
+	API *Server
+}
+
+// NewTestServer starts a Server created from options on a local port. Close it
+// when done.
+func NewTestServer(options Options) *TestServer {
+	api := NewServer(options)
+	return &TestServer{Server: httptest.NewServer(api), API: api}
+}
+
+// BaseURL returns the value NEWS_BASE_URL takes to point the downloader at the server
+func (t *TestServer) BaseURL() string {
+	return t.URL + "/v2"
+}
@@ -0,0 +1,266 @@
+package fakenewsapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/newsapi"
+)
+
+// countingPublisher records published messages in memory
+type countingPublisher struct {
+	mutex    sync.Mutex
+	messages []string
+}
+
+func (p *countingPublisher) Publish(broker, topic, message string) error {
+	return p.PublishWithContext(context.Background(), broker, topic, message)
+}
+
+func (p *countingPublisher) PublishWithContext(ctx context.Context, broker, topic, message string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func (p *countingPublisher) Close() error {
+	return nil
+}
+
+// get requests path with params from server, decoding the body into out
+func get(t *testing.T, server *TestServer, key, path string, params url.Values, out interface{}) *http.Response {
+	t.Helper()
+
+	req, _ := http.NewRequest(http.MethodGet, server.BaseURL()+path+"?"+params.Encode(), nil)
+	if key != "" {
+		req.Header.Set(newsapi.APIKeyHeader, key)
+	}
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("Failed to decode %s response: %v", path, err)
+		}
+	}
+	return resp
+}
+
+func TestGenerateCorpus_IsSeeded(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	first := GenerateCorpus(7, 50, now)
+	second := GenerateCorpus(7, 50, now)
+	other := GenerateCorpus(8, 50, now)
+
+	if len(first.Articles) != 50 {
+		t.Fatalf("Expected 50 articles, got %d", len(first.Articles))
+	}
+	for i := range first.Articles {
+		if first.Articles[i] != second.Articles[i] {
+			t.Fatalf("Article %d differs between runs with the same seed", i)
+		}
+		if i > 0 && first.Articles[i].PublishedAt.After(first.Articles[i-1].PublishedAt) {
+			t.Errorf("Articles are not ordered newest first at %d", i)
+		}
+		if first.Articles[i].PublishedAt.After(now) || first.Articles[i].PublishedAt.Before(now.Add(-7*24*time.Hour)) {
+			t.Errorf("Article %d published outside the last week: %v", i, first.Articles[i].PublishedAt)
+		}
+	}
+	if first.Articles[0] == other.Articles[0] {
+		t.Error("Expected a different seed to give a different corpus")
+	}
+}
+
+func TestServer_PaginatesTopHeadlines(t *testing.T) {
+	server := NewTestServer(Options{Seed: 1, CorpusSize: 300})
+	defer server.Close()
+
+	expected := 0
+	for _, article := range server.API.Corpus().Articles {
+		if source, _ := server.API.Corpus().source(article.Source.ID); source.Country == "us" {
+			expected++
+		}
+	}
+
+	seen := make(map[string]bool)
+	var previous time.Time
+	for page := 1; page <= 5; page++ {
+		var resp articlesResponse
+		get(t, server, "any-key", "/top-headlines", url.Values{"country": {"us"}, "pageSize": {"20"}, "page": {strconv.Itoa(page)}}, &resp)
+
+		if resp.Status != "ok" || resp.TotalResults != expected || len(resp.Articles) != 20 {
+			t.Fatalf("Page %d: unexpected response: status %s, %d of %d results", page, resp.Status, len(resp.Articles), resp.TotalResults)
+		}
+		for _, article := range resp.Articles {
+			if seen[article.URL] {
+				t.Errorf("Article %s served twice", article.URL)
+			}
+			if !previous.IsZero() && article.PublishedAt.After(previous) {
+				t.Errorf("Articles are not ordered newest first across pages")
+			}
+			seen[article.URL] = true
+			previous = article.PublishedAt
+		}
+	}
+
+	var tooDeep errorResponse
+	resp := get(t, server, "any-key", "/top-headlines", url.Values{"country": {"us"}, "pageSize": {"20"}, "page": {"6"}}, &tooDeep)
+	if resp.StatusCode != http.StatusUpgradeRequired || tooDeep.Code != "maximumResultsReached" {
+		t.Errorf("Expected 426 maximumResultsReached beyond 100 results, got %d %s", resp.StatusCode, tooDeep.Code)
+	}
+}
+
+func TestServer_ErrorResponses(t *testing.T) {
+	server := NewTestServer(Options{APIKeys: []string{"good-key"}})
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		key    string
+		path   string
+		params url.Values
+		status int
+		code   string
+	}{
+		{"missing key", "", "/top-headlines", url.Values{"country": {"us"}}, http.StatusUnauthorized, "apiKeyMissing"},
+		{"invalid key", "bad-key", "/top-headlines", url.Values{"country": {"us"}}, http.StatusUnauthorized, "apiKeyInvalid"},
+		{"no scope", "good-key", "/top-headlines", url.Values{}, http.StatusBadRequest, "parametersMissing"},
+		{"sources with country", "good-key", "/top-headlines", url.Values{"sources": {"bbc-news"}, "country": {"us"}}, http.StatusBadRequest, "parametersIncompatible"},
+		{"page size too large", "good-key", "/top-headlines", url.Values{"country": {"us"}, "pageSize": {"500"}}, http.StatusBadRequest, "parameterInvalid"},
+		{"everything without scope", "good-key", "/everything", url.Values{"language": {"en"}}, http.StatusBadRequest, "parametersMissing"},
+		{"bad from date", "good-key", "/everything", url.Values{"q": {"energy"}, "from": {"last tuesday"}}, http.StatusBadRequest, "parameterInvalid"},
+		{"bad sortBy", "good-key", "/everything", url.Values{"q": {"energy"}, "sortBy": {"random"}}, http.StatusBadRequest, "parameterInvalid"},
+		{"unknown route", "good-key", "/archive", url.Values{}, http.StatusNotFound, "routeNotFound"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body errorResponse
+			resp := get(t, server, tt.key, tt.path, tt.params, &body)
+			if resp.StatusCode != tt.status || body.Status != "error" || body.Code != tt.code {
+				t.Errorf("Expected %d %s, got %d %s %s", tt.status, tt.code, resp.StatusCode, body.Status, body.Code)
+			}
+		})
+	}
+
+	// The key is also accepted as a query parameter
+	var ok articlesResponse
+	if resp := get(t, server, "", "/top-headlines", url.Values{"country": {"us"}, "apiKey": {"good-key"}}, &ok); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the apiKey parameter to be accepted, got %d", resp.StatusCode)
+	}
+}
+
+func TestServer_RateLimitsPerKey(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	server := NewTestServer(Options{RequestsPerWindow: 2, Window: time.Hour, Now: func() time.Time { return now }})
+	defer server.Close()
+
+	params := url.Values{"country": {"us"}}
+	for _, remaining := range []string{"1", "0"} {
+		resp := get(t, server, "key-a", "/top-headlines", params, nil)
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("X-RateLimit-Remaining") != remaining {
+			t.Errorf("Expected 200 with %s remaining, got %d with %s", remaining, resp.StatusCode, resp.Header.Get("X-RateLimit-Remaining"))
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "2" || resp.Header.Get("X-RateLimit-Reset") != strconv.Itoa(int(now.Add(time.Hour).Unix())) {
+			t.Errorf("Unexpected rate limit headers: %v", resp.Header)
+		}
+	}
+
+	var limited errorResponse
+	resp := get(t, server, "key-a", "/top-headlines", params, &limited)
+	if resp.StatusCode != http.StatusTooManyRequests || limited.Code != newsapi.CodeRateLimited || resp.Header.Get("Retry-After") != "3600" {
+		t.Errorf("Expected 429 rateLimited with Retry-After, got %d %s %q", resp.StatusCode, limited.Code, resp.Header.Get("Retry-After"))
+	}
+
+	// Each key has its own quota, and quotas refill with the next window
+	if resp := get(t, server, "key-b", "/top-headlines", params, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected another key to be unaffected, got %d", resp.StatusCode)
+	}
+	now = now.Add(90 * time.Minute)
+	if resp := get(t, server, "key-a", "/top-headlines", params, nil); resp.StatusCode != http.StatusOK || resp.Header.Get("X-RateLimit-Remaining") != "1" {
+		t.Errorf("Expected the quota to refill, got %d with %s remaining", resp.StatusCode, resp.Header.Get("X-RateLimit-Remaining"))
+	}
+}
+
+func TestServer_EverythingAndSources(t *testing.T) {
+	server := NewTestServer(Options{Seed: 3, CorpusSize: 400})
+	defer server.Close()
+
+	var resp articlesResponse
+	get(t, server, "any-key", "/everything", url.Values{"q": {"energy -live"}, "domains": {"bloomberg.com,techcrunch.com"}, "searchIn": {"title"}}, &resp)
+	if resp.TotalResults == 0 {
+		t.Fatal("Expected some articles to match")
+	}
+	for _, article := range resp.Articles {
+		domain := articleDomain(article.URL)
+		if domain != "bloomberg.com" && domain != "techcrunch.com" {
+			t.Errorf("Article from unexpected domain %s", domain)
+		}
+		if !matchesQuery(article, "energy -live", []string{"title"}) {
+			t.Errorf("Article does not match the query: %s", article.Title)
+		}
+	}
+
+	var sources sourcesResponse
+	get(t, server, "any-key", "/top-headlines/sources", url.Values{"category": {"technology"}}, &sources)
+	if len(sources.Sources) != 2 || sources.Sources[0].ID != "ars-technica" || sources.Sources[1].ID != "techcrunch" {
+		t.Errorf("Unexpected technology sources: %+v", sources.Sources)
+	}
+}
+
+func TestServer_DrivesDownloaderEndToEnd(t *testing.T) {
+	server := NewTestServer(Options{
+		Seed:          5,
+		CorpusSize:    300,
+		APIKeys:       []string{"spent-key", "fresh-key"},
+		ExhaustedKeys: []string{"spent-key"},
+	})
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.BaseURL = server.BaseURL()
+	cfg.OutputDir = t.TempDir()
+	cfg.RequestsPerSecond = 0
+	cfg.APIKeys = []string{"spent-key", "fresh-key"}
+
+	publisher := &countingPublisher{}
+	downloader := newsapi.NewNewsDownloader(newsapi.NewNewsAPIClient(cfg), publisher, cfg)
+
+	req := newsapi.NewDownloadRequest("spent-key", "us")
+	req.From = time.Now().Add(-8 * 24 * time.Hour)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Download against the fake server failed: %v", err)
+	}
+
+	// The developer plan's 100 result limit cuts the download at 5 pages of 20
+	if result.PagesDownloaded != 5 || result.TotalArticles <= 100 || !result.Truncated {
+		t.Errorf("Expected more than 100 results cut to 5 pages, got %d in %d (truncated %v)", result.TotalArticles, result.PagesDownloaded, result.Truncated)
+	}
+	if len(publisher.messages) != 5 {
+		t.Errorf("Expected 5 published files, got %d", len(publisher.messages))
+	}
+
+	newest := server.API.Corpus().Articles[0].PublishedAt
+	if !result.MaxPublishedAt.Equal(newest) {
+		t.Errorf("Expected newest article at %v, got %v", newest, result.MaxPublishedAt)
+	}
+
+	// The exhausted key was rotated away from after its first request
+	usage := result.KeyUsage
+	if len(usage) != 2 || usage[0].Requests != 1 || usage[1].Requests != 5 {
+		t.Errorf("Unexpected key usage: %+v", usage)
+	}
+}
@@ -0,0 +1,302 @@
+// Package fakes3 is an in-process stand-in for an S3-compatible service such as
+// MinIO. It keeps objects in memory and serves path-style PutObject, GetObject,
+// HeadObject, DeleteObject and ListObjectsV2 requests for any bucket, so S3
+// storage can be exercised without network access or credentials.
+package fakes3
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMaxKeys is the largest page of a listing, as on S3
+const DefaultMaxKeys = 1000
+
+// Options configures a Server
+type Options struct {
+	// AccessKeyID is the only access key the server accepts; when empty any
+	// signed request is accepted
+	AccessKeyID string
+
+	// MaxKeys caps the objects returned per listing page, DefaultMaxKeys when zero
+	MaxKeys int
+
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+}
+
+// object is a stored object
+type object struct {
+	data    []byte
+	modTime time.Time
+}
+
+// Server is an http.Handler answering like an S3-compatible service. Requests
+// must carry a Signature Version 4 Authorization header for the configured
+// access key, and uploads must match their X-Amz-Content-Sha256. Signatures
+// themselves are not recomputed.
+type Server struct {
+	options Options
+
+	mutex    sync.Mutex
+	objects  map[string]object
+	requests int
+}
+
+// NewServer returns an empty server
+func NewServer(options Options) *Server {
+	if options.MaxKeys <= 0 {
+		options.MaxKeys = DefaultMaxKeys
+	}
+	if options.Now == nil {
+		options.Now = time.Now
+	}
+
+	return &Server{
+		options: options,
+		objects: make(map[string]object),
+	}
+}
+
+// Object returns the content stored under key in bucket
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	obj, ok := s.objects[bucket+"/"+key]
+	return obj.data, ok
+}
+
+// Keys returns the keys stored in bucket, sorted
+func (s *Server) Keys(bucket string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.keys(bucket, "")
+}
+
+// Requests returns the number of requests served
+func (s *Server) Requests() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests
+}
+
+// s3Error is an error response in S3's format
+type s3Error struct {
+	XMLName xml.Name `xml:"Error"`
+	status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+	Key     string `xml:"Key,omitempty"`
+}
+
+// listBucketResult is the body of a ListObjectsV2 response
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string         `xml:"Name"`
+	Prefix                string         `xml:"Prefix"`
+	KeyCount              int            `xml:"KeyCount"`
+	MaxKeys               int            `xml:"MaxKeys"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	Contents              []listedObject `xml:"Contents"`
+	ContinuationToken     string         `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string         `xml:"NextContinuationToken,omitempty"`
+}
+
+// listedObject is an object in a listing
+type listedObject struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int    `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests++
+
+	if apiErr := s.authorize(r); apiErr != nil {
+		writeError(w, apiErr)
+		return
+	}
+
+	bucket, key := splitPath(r.URL.Path)
+	if bucket == "" {
+		writeError(w, &s3Error{status: http.StatusBadRequest, Code: "InvalidBucketName", Message: "The specified bucket is not valid."})
+		return
+	}
+
+	switch {
+	case key == "" && r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		s.list(w, r, bucket)
+	case key == "":
+		writeError(w, &s3Error{status: http.StatusNotImplemented, Code: "NotImplemented", Message: "Only ListObjectsV2 is supported on buckets."})
+	case r.Method == http.MethodPut:
+		s.put(w, r, bucket, key)
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		s.get(w, r, bucket, key)
+	case r.Method == http.MethodDelete:
+		delete(s.objects, bucket+"/"+key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, &s3Error{status: http.StatusMethodNotAllowed, Code: "MethodNotAllowed", Message: "The specified method is not allowed against this resource."})
+	}
+}
+
+// authorize checks that the request is signed for the configured access key
+func (s *Server) authorize(r *http.Request) *s3Error {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=") || r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		return &s3Error{status: http.StatusForbidden, Code: "AccessDenied", Message: "Access Denied."}
+	}
+
+	credential := strings.TrimPrefix(auth, "AWS4-HMAC-SHA256 Credential=")
+	accessKeyID := credential
+	if i := strings.Index(credential, "/"); i >= 0 {
+		accessKeyID = credential[:i]
+	}
+	if s.options.AccessKeyID != "" && accessKeyID != s.options.AccessKeyID {
+		return &s3Error{status: http.StatusForbidden, Code: "InvalidAccessKeyId", Message: "The Access Key Id you provided does not exist in our records."}
+	}
+	return nil
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, &s3Error{status: http.StatusBadRequest, Code: "IncompleteBody", Message: err.Error(), Key: key})
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	if declared := r.Header.Get("X-Amz-Content-Sha256"); declared != "UNSIGNED-PAYLOAD" && declared != hex.EncodeToString(sum[:]) {
+		writeError(w, &s3Error{status: http.StatusBadRequest, Code: "XAmzContentSHA256Mismatch", Message: "The provided 'x-amz-content-sha256' header does not match what was computed.", Key: key})
+		return
+	}
+
+	s.objects[bucket+"/"+key] = object{data: data, modTime: s.options.Now().UTC()}
+	w.Header().Set("ETag", etag(data))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) get(w http.ResponseWriter, r *http.Request, bucket, key string) {
+	obj, ok := s.objects[bucket+"/"+key]
+	if !ok {
+		writeError(w, &s3Error{status: http.StatusNotFound, Code: "NoSuchKey", Message: "The specified key does not exist.", Key: key})
+		return
+	}
+
+	w.Header().Set("Content-Length", strconv.Itoa(len(obj.data)))
+	w.Header().Set("Last-Modified", obj.modTime.Format(http.TimeFormat))
+	w.Header().Set("ETag", etag(obj.data))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		w.Write(obj.data)
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, bucket string) {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	keys := s.keys(bucket, prefix)
+
+	// Continuation tokens are the last key of the previous page, encoded
+	start := 0
+	token := query.Get("continuation-token")
+	if token != "" {
+		after, err := base64.URLEncoding.DecodeString(token)
+		if err != nil {
+			writeError(w, &s3Error{status: http.StatusBadRequest, Code: "InvalidArgument", Message: "The continuation token provided is incorrect."})
+			return
+		}
+		start = sort.SearchStrings(keys, string(after)+"\x00")
+	}
+
+	result := listBucketResult{Name: bucket, Prefix: prefix, MaxKeys: s.options.MaxKeys, ContinuationToken: token}
+	end := start + s.options.MaxKeys
+	if end < len(keys) {
+		result.IsTruncated = true
+		result.NextContinuationToken = base64.URLEncoding.EncodeToString([]byte(keys[end-1]))
+	} else {
+		end = len(keys)
+	}
+
+	for _, key := range keys[start:end] {
+		obj := s.objects[bucket+"/"+key]
+		result.Contents = append(result.Contents, listedObject{
+			Key:          key,
+			LastModified: obj.modTime.Format("2006-01-02T15:04:05.000Z"),
+			ETag:         etag(obj.data),
+			Size:         len(obj.data),
+			StorageClass: "STANDARD",
+		})
+	}
+	result.KeyCount = len(result.Contents)
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(result)
+}
+
+// keys returns the sorted keys in bucket starting with prefix; the caller holds the mutex
+func (s *Server) keys(bucket, prefix string) []string {
+	keys := make([]string, 0)
+	for name := range s.objects {
+		if key := strings.TrimPrefix(name, bucket+"/"); key != name && strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// splitPath splits a path-style request path into bucket and key
+func splitPath(path string) (bucket, key string) {
+	path = strings.TrimPrefix(path, "/")
+	if i := strings.Index(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return path, ""
+}
+
+// etag returns the quoted MD5 of data, as S3 reports for single-part uploads
+func etag(data []byte) string {
+	sum := md5.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`
+}
+
+func writeError(w http.ResponseWriter, apiErr *s3Error) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(apiErr.status)
+	fmt.Fprint(w, xml.Header)
+	xml.NewEncoder(w).Encode(apiErr)
+}
+
+// TestServer is a Server listening on a local port
+type TestServer struct {
+	*httptest.Server
+	API *Server
+}
+
+// NewTestServer starts a Server on a local port; call Close when done
+func NewTestServer(options Options) *TestServer {
+	api := NewServer(options)
+	return &TestServer{Server: httptest.NewServer(api), API: api}
+}
@@ -0,0 +1,48 @@
+package fakes3
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestServerRejectsUnsignedRequests(t *testing.T) {
+	server := NewTestServer(Options{AccessKeyID: "minio"})
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/news-pages/page1.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(body), "<Code>AccessDenied</Code>") {
+		t.Errorf("Expected AccessDenied, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestServerRejectsMismatchedPayloads(t *testing.T) {
+	server := NewTestServer(Options{})
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, server.URL+"/news-pages/page1.json", strings.NewReader("{}"))
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=any/20240115/us-east-1/s3/aws4_request, SignedHeaders=host, Signature=00")
+	req.Header.Set("X-Amz-Date", "20240115T000000Z")
+	req.Header.Set("X-Amz-Content-Sha256", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "XAmzContentSHA256Mismatch") {
+		t.Errorf("Expected XAmzContentSHA256Mismatch, got %d: %s", resp.StatusCode, body)
+	}
+	if keys := server.API.Keys("news-pages"); len(keys) != 0 {
+		t.Errorf("Expected nothing stored, got %v", keys)
+	}
+}
@@ -0,0 +1,364 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-news-agg/internal/newsapi"
+)
+
+// nsAtom is the Atom 1.0 XML namespace
+const nsAtom = "http://www.w3.org/2005/Atom"
+
+// rssDocument is an RSS 2.0 document
+type rssDocument struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title          string           `xml:"title"`
+	Links          []string         `xml:"link"`
+	Description    string           `xml:"description"`
+	Encoded        string           `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate        string           `xml:"pubDate"`
+	Date           string           `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author         string           `xml:"author"`
+	Creator        string           `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	GUID           string           `xml:"guid"`
+	Enclosures     []rssEnclosure   `xml:"enclosure"`
+	MediaContent   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroup     *mediaGroup      `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type rssEnclosure struct {
+	URL  string `xml:"url,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// atomFeed is an Atom 1.0 document
+type atomFeed struct {
+	Title   string      `xml:"http://www.w3.org/2005/Atom title"`
+	Entries []atomEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type atomEntry struct {
+	ID             string           `xml:"http://www.w3.org/2005/Atom id"`
+	Title          string           `xml:"http://www.w3.org/2005/Atom title"`
+	Links          []atomLink       `xml:"http://www.w3.org/2005/Atom link"`
+	Summary        string           `xml:"http://www.w3.org/2005/Atom summary"`
+	Content        string           `xml:"http://www.w3.org/2005/Atom content"`
+	Published      string           `xml:"http://www.w3.org/2005/Atom published"`
+	Updated        string           `xml:"http://www.w3.org/2005/Atom updated"`
+	Authors        []atomPerson     `xml:"http://www.w3.org/2005/Atom author"`
+	Creator        string           `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	MediaContent   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+	MediaGroup     *mediaGroup      `xml:"http://search.yahoo.com/mrss/ group"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"http://www.w3.org/2005/Atom name"`
+}
+
+// mediaContent is a Media RSS media:content element
+type mediaContent struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Medium string `xml:"medium,attr"`
+}
+
+type mediaThumbnail struct {
+	URL string `xml:"url,attr"`
+}
+
+type mediaGroup struct {
+	Content   []mediaContent   `xml:"http://search.yahoo.com/mrss/ content"`
+	Thumbnail []mediaThumbnail `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// Feed is a parsed RSS or Atom feed
+type Feed struct {
+	Title    string
+	Articles []newsapi.Article
+}
+
+// Parse reads an RSS 2.0 or Atom 1.0 document. feedURL names the feed in the
+// articles' source when the document has no title, and resolves relative links.
+func Parse(r io.Reader, feedURL string) (*Feed, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(feedURL)
+
+	var feed *Feed
+	switch {
+	case root.Local == "rss":
+		var doc rssDocument
+		if err := newDecoder(data).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		feed = parseRSS(&doc, base)
+	case root.Local == "feed" && root.Space == nsAtom:
+		var doc atomFeed
+		if err := newDecoder(data).Decode(&doc); err != nil {
+			return nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+		}
+		feed = parseAtom(&doc, base)
+	default:
+		return nil, fmt.Errorf("unsupported feed format: root element <%s>", root.Local)
+	}
+
+	if feed.Title == "" && base != nil {
+		feed.Title = base.Host
+	}
+	for i := range feed.Articles {
+		feed.Articles[i].Source = newsapi.Source{Name: feed.Title}
+	}
+
+	return feed, nil
+}
+
+func parseRSS(doc *rssDocument, base *url.URL) *Feed {
+	feed := &Feed{Title: cleanText(doc.Channel.Title)}
+
+	for _, item := range doc.Channel.Items {
+		link := firstNonEmpty(item.Links...)
+		if link == "" && looksLikeURL(item.GUID) {
+			link = item.GUID
+		}
+
+		content := item.Encoded
+		if content == "" {
+			content = item.Description
+		}
+
+		feed.Articles = append(feed.Articles, newsapi.Article{
+			Author:      cleanText(firstNonEmpty(item.Creator, item.Author)),
+			Title:       cleanText(item.Title),
+			Description: cleanText(item.Description),
+			URL:         resolveURL(base, link),
+			URLToImage:  resolveURL(base, imageURL(item.MediaContent, item.MediaThumbnail, item.MediaGroup, item.Enclosures)),
+			PublishedAt: parseDate(firstNonEmpty(item.PubDate, item.Date)),
+			Content:     cleanText(content),
+		})
+	}
+
+	return feed
+}
+
+func parseAtom(doc *atomFeed, base *url.URL) *Feed {
+	feed := &Feed{Title: cleanText(doc.Title)}
+
+	for _, entry := range doc.Entries {
+		var authors []string
+		for _, author := range entry.Authors {
+			if name := strings.TrimSpace(author.Name); name != "" {
+				authors = append(authors, name)
+			}
+		}
+		author := strings.Join(authors, ", ")
+		if author == "" {
+			author = entry.Creator
+		}
+
+		var enclosures []rssEnclosure
+		for _, link := range entry.Links {
+			if link.Rel == "enclosure" {
+				enclosures = append(enclosures, rssEnclosure{URL: link.Href, Type: link.Type})
+			}
+		}
+
+		content := entry.Content
+		if content == "" {
+			content = entry.Summary
+		}
+
+		feed.Articles = append(feed.Articles, newsapi.Article{
+			Author:      cleanText(author),
+			Title:       cleanText(entry.Title),
+			Description: cleanText(entry.Summary),
+			URL:         resolveURL(base, atomAlternate(entry.Links, entry.ID)),
+			URLToImage:  resolveURL(base, imageURL(entry.MediaContent, entry.MediaThumbnail, entry.MediaGroup, enclosures)),
+			PublishedAt: parseDate(firstNonEmpty(entry.Published, entry.Updated)),
+			Content:     cleanText(content),
+		})
+	}
+
+	return feed
+}
+
+// atomAlternate returns the entry's alternate link, which is the default relation
+func atomAlternate(links []atomLink, id string) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if looksLikeURL(id) {
+		return id
+	}
+	return ""
+}
+
+// imageURL picks the article image from Media RSS elements, falling back to an image enclosure
+func imageURL(contents []mediaContent, thumbnails []mediaThumbnail, group *mediaGroup, enclosures []rssEnclosure) string {
+	if group != nil {
+		contents = append(contents, group.Content...)
+		thumbnails = append(thumbnails, group.Thumbnail...)
+	}
+
+	for _, content := range contents {
+		if content.URL != "" && (content.Medium == "image" || strings.HasPrefix(content.Type, "image/") ||
+			(content.Medium == "" && content.Type == "")) {
+			return content.URL
+		}
+	}
+	for _, thumbnail := range thumbnails {
+		if thumbnail.URL != "" {
+			return thumbnail.URL
+		}
+	}
+	for _, enclosure := range enclosures {
+		if enclosure.URL != "" && strings.HasPrefix(enclosure.Type, "image/") {
+			return enclosure.URL
+		}
+	}
+	return ""
+}
+
+// dateLayouts are the timestamp formats seen in the wild, RFC 822 variants first
+var dateLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"02 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses a feed timestamp, returning the zero time if no known layout matches
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	tagPattern        = regexp.MustCompile(`<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// cleanText strips markup and entities from feed text and collapses whitespace
+func cleanText(value string) string {
+	value = tagPattern.ReplaceAllString(value, " ")
+	value = html.UnescapeString(value)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(value, " "))
+}
+
+func resolveURL(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || base == nil {
+		return ref
+	}
+	parsed, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+func looksLikeURL(value string) bool {
+	return strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// rootElement returns the name of the document's first element
+func rootElement(data []byte) (xml.Name, error) {
+	decoder := newDecoder(data)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("failed to parse feed: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+func newDecoder(data []byte) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charsetReader
+	return decoder
+}
+
+// charsetReader converts the single-byte encodings older feeds still use to UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	case "iso-8859-1", "latin1", "latin-1", "windows-1252", "cp1252":
+		data, err := ioutil.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed charset '%s'", charset)
+	}
+}
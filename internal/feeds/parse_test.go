@@ -0,0 +1,221 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"
+	xmlns:dc="http://purl.org/dc/elements/1.1/"
+	xmlns:content="http://purl.org/rss/1.0/modules/content/"
+	xmlns:media="http://search.yahoo.com/mrss/"
+	xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+	<title>Example News</title>
+	<link>https://news.example.com/</link>
+	<atom:link href="https://news.example.com/feed.xml" rel="self" type="application/rss+xml"/>
+	<item>
+		<title>Markets &amp; Rates</title>
+		<link>https://news.example.com/markets</link>
+		<description><![CDATA[<p>Rates <b>rose</b> again.</p>]]></description>
+		<content:encoded><![CDATA[<p>Full story about rates.</p>]]></content:encoded>
+		<pubDate>Fri, 27 Oct 2023 10:00:00 +0000</pubDate>
+		<dc:creator>Jane Doe</dc:creator>
+		<media:content url="https://img.example.com/markets.jpg" medium="image"/>
+	</item>
+	<item>
+		<title>Relative link</title>
+		<link>/local/story</link>
+		<description>Plain text</description>
+		<pubDate>Fri, 27 Oct 2023 11:30:00 GMT</pubDate>
+		<author>desk@example.com (News Desk)</author>
+		<enclosure url="https://img.example.com/local.png" type="image/png" length="100"/>
+	</item>
+	<item>
+		<title>Guid only</title>
+		<guid isPermaLink="true">https://news.example.com/guid-only</guid>
+		<media:group>
+			<media:thumbnail url="https://img.example.com/thumb.jpg"/>
+		</media:group>
+	</item>
+</channel>
+</rss>`
+
+const sampleAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:media="http://search.yahoo.com/mrss/">
+	<title type="text">Atom Example</title>
+	<entry>
+		<id>tag:example.com,2023:1</id>
+		<title>First entry</title>
+		<link rel="alternate" type="text/html" href="https://atom.example.com/first"/>
+		<link rel="enclosure" type="image/jpeg" href="https://atom.example.com/first.jpg"/>
+		<summary>Short summary</summary>
+		<content type="html">&lt;p&gt;Long content&lt;/p&gt;</content>
+		<published>2023-10-27T09:15:00Z</published>
+		<updated>2023-10-27T12:00:00Z</updated>
+		<author><name>Alice</name></author>
+		<author><name>Bob</name></author>
+	</entry>
+	<entry>
+		<id>https://atom.example.com/second</id>
+		<title>Second entry</title>
+		<updated>2023-10-27T08:00:00+02:00</updated>
+		<media:content url="https://atom.example.com/second.jpg" type="image/jpeg"/>
+	</entry>
+</feed>`
+
+func TestParse_RSS(t *testing.T) {
+	feed, err := Parse(strings.NewReader(sampleRSS), "https://news.example.com/feed.xml")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	if feed.Title != "Example News" {
+		t.Errorf("Expected title 'Example News', got '%s'", feed.Title)
+	}
+	if len(feed.Articles) != 3 {
+		t.Fatalf("Expected 3 articles, got %d", len(feed.Articles))
+	}
+
+	first := feed.Articles[0]
+	if first.Title != "Markets & Rates" {
+		t.Errorf("Expected unescaped title, got '%s'", first.Title)
+	}
+	if first.Description != "Rates rose again." {
+		t.Errorf("Expected markup stripped from description, got '%s'", first.Description)
+	}
+	if first.Content != "Full story about rates." {
+		t.Errorf("Expected content:encoded as content, got '%s'", first.Content)
+	}
+	if first.Author != "Jane Doe" {
+		t.Errorf("Expected dc:creator as author, got '%s'", first.Author)
+	}
+	if first.URLToImage != "https://img.example.com/markets.jpg" {
+		t.Errorf("Expected media:content image, got '%s'", first.URLToImage)
+	}
+	if !first.PublishedAt.Equal(time.Date(2023, 10, 27, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected published time: %v", first.PublishedAt)
+	}
+	if first.Source.Name != "Example News" {
+		t.Errorf("Expected source 'Example News', got '%+v'", first.Source)
+	}
+
+	second := feed.Articles[1]
+	if second.URL != "https://news.example.com/local/story" {
+		t.Errorf("Expected resolved relative link, got '%s'", second.URL)
+	}
+	if second.URLToImage != "https://img.example.com/local.png" {
+		t.Errorf("Expected image enclosure, got '%s'", second.URLToImage)
+	}
+	if second.Content != "Plain text" {
+		t.Errorf("Expected description as content fallback, got '%s'", second.Content)
+	}
+	if !second.PublishedAt.Equal(time.Date(2023, 10, 27, 11, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected published time: %v", second.PublishedAt)
+	}
+
+	third := feed.Articles[2]
+	if third.URL != "https://news.example.com/guid-only" {
+		t.Errorf("Expected permalink guid as URL, got '%s'", third.URL)
+	}
+	if third.URLToImage != "https://img.example.com/thumb.jpg" {
+		t.Errorf("Expected grouped thumbnail, got '%s'", third.URLToImage)
+	}
+	if !third.PublishedAt.IsZero() {
+		t.Errorf("Expected zero time for undated item, got %v", third.PublishedAt)
+	}
+}
+
+func TestParse_Atom(t *testing.T) {
+	feed, err := Parse(strings.NewReader(sampleAtom), "https://atom.example.com/feed")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	if feed.Title != "Atom Example" || len(feed.Articles) != 2 {
+		t.Fatalf("Unexpected feed: %+v", feed)
+	}
+
+	first := feed.Articles[0]
+	if first.URL != "https://atom.example.com/first" {
+		t.Errorf("Expected alternate link, got '%s'", first.URL)
+	}
+	if first.Author != "Alice, Bob" {
+		t.Errorf("Expected both authors, got '%s'", first.Author)
+	}
+	if first.Description != "Short summary" || first.Content != "Long content" {
+		t.Errorf("Unexpected text: description='%s' content='%s'", first.Description, first.Content)
+	}
+	if first.URLToImage != "https://atom.example.com/first.jpg" {
+		t.Errorf("Expected image enclosure link, got '%s'", first.URLToImage)
+	}
+	if !first.PublishedAt.Equal(time.Date(2023, 10, 27, 9, 15, 0, 0, time.UTC)) {
+		t.Errorf("Expected published over updated, got %v", first.PublishedAt)
+	}
+
+	second := feed.Articles[1]
+	if second.URL != "https://atom.example.com/second" {
+		t.Errorf("Expected id as URL fallback, got '%s'", second.URL)
+	}
+	if !second.PublishedAt.Equal(time.Date(2023, 10, 27, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected updated converted to UTC, got %v", second.PublishedAt)
+	}
+	if second.URLToImage != "https://atom.example.com/second.jpg" {
+		t.Errorf("Expected media:content image, got '%s'", second.URLToImage)
+	}
+}
+
+func TestParse_Latin1(t *testing.T) {
+	doc := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Caf\xe9</title>" +
+		"<item><title>Cr\xe8me</title><link>https://example.com/a</link></item></channel></rss>"
+
+	feed, err := Parse(strings.NewReader(doc), "https://example.com/feed")
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	if feed.Title != "Café" || feed.Articles[0].Title != "Crème" {
+		t.Errorf("Expected Latin-1 decoded text, got '%s' / '%s'", feed.Title, feed.Articles[0].Title)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not xml", "{\"status\": \"ok\"}"},
+		{"unknown root", "<html><body>hi</body></html>"},
+		{"atom without namespace", "<feed><entry><title>x</title></entry></feed>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(tt.doc), "https://example.com/feed"); err == nil {
+				t.Error("Parse() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2023, 10, 27, 10, 0, 0, 0, time.UTC)
+
+	for _, value := range []string{
+		"Fri, 27 Oct 2023 10:00:00 +0000",
+		"Fri, 27 Oct 2023 10:00:00 GMT",
+		"Fri, 27 Oct 2023 12:00:00 +0200",
+		"27 Oct 2023 10:00:00 +0000",
+		"2023-10-27T10:00:00Z",
+		"2023-10-27T12:00:00+02:00",
+	} {
+		if got := parseDate(value); !got.Equal(want) {
+			t.Errorf("parseDate(%q) = %v, want %v", value, got, want)
+		}
+	}
+
+	if got := parseDate("yesterday"); !got.IsZero() {
+		t.Errorf("Expected zero time for unparseable date, got %v", got)
+	}
+}
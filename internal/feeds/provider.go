@@ -0,0 +1,343 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/newsapi"
+)
+
+const (
+	// ProviderName is the name of the feed provider
+	ProviderName = "feeds"
+
+	// defaultRetryAfter is how long to back off from a feed that answers 429
+	// without a Retry-After header
+	defaultRetryAfter = 60 * time.Second
+
+	userAgent = "go-news-agg feed reader"
+)
+
+// HTTPDoer sends HTTP requests; *http.Client implements it. Unlike the NewsAPI
+// client's HTTPClient it takes a full request, since conditional GETs need headers.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Provider ingests RSS 2.0 and Atom feeds. The feed URLs are taken from the
+// request's Sources. The first page of a download fetches every feed, and the
+// merged articles, newest first, are then paged through at the request's page size.
+// Validators are only persisted once the downloader reports the download completed
+// through FinishDownload, so a feed whose articles were never saved is fetched in
+// full after a restart rather than answering 304.
+type Provider struct {
+	httpClient HTTPDoer
+	statePath  string
+	maxBytes   int64
+
+	mutex sync.Mutex
+	// validators are the latest validators of each feed, sent with the next request
+	validators map[string]Validators
+	// saved are the validators as last persisted to statePath
+	saved map[string]Validators
+	// feedArticles holds each feed's articles from its last full response, served
+	// again when the feed answers 304 Not Modified within the same process
+	feedArticles map[string][]newsapi.Article
+	// downloads holds each download in progress, by fingerprint
+	downloads map[string]*download
+}
+
+// download is the state of one download in progress
+type download struct {
+	// articles are the merged articles of every feed
+	articles []newsapi.Article
+	// validators are those of the feeds fetched for the download, persisted once it completes
+	validators map[string]Validators
+}
+
+// NewProvider creates a feed provider that keeps its validators under cfg.OutputDir
+func NewProvider(cfg *config.Config) (*Provider, error) {
+	httpClient := &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second}
+	provider, err := NewProviderWithHTTPClient(httpClient, StatePath(cfg.OutputDir))
+	if err != nil {
+		return nil, err
+	}
+	provider.SetMaxResponseBytes(int64(cfg.MaxResponseBytes))
+	return provider, nil
+}
+
+// NewProviderWithHTTPClient creates a feed provider with a custom HTTP client.
+// An empty statePath keeps validators in memory only.
+func NewProviderWithHTTPClient(httpClient HTTPDoer, statePath string) (*Provider, error) {
+	saved := make(map[string]Validators)
+	if statePath != "" {
+		loaded, err := LoadState(statePath)
+		if err != nil {
+			return nil, err
+		}
+		saved = loaded
+	}
+
+	validators := make(map[string]Validators, len(saved))
+	for feedURL, feedValidators := range saved {
+		validators[feedURL] = feedValidators
+	}
+
+	return &Provider{
+		httpClient:   httpClient,
+		statePath:    statePath,
+		validators:   validators,
+		saved:        saved,
+		feedArticles: make(map[string][]newsapi.Article),
+		downloads:    make(map[string]*download),
+	}, nil
+}
+
+// SetMaxResponseBytes caps the size of a feed body, like Config.MaxResponseBytes
+// caps API responses; zero or less disables the cap
+func (p *Provider) SetMaxResponseBytes(limit int64) {
+	p.maxBytes = limit
+}
+
+// Name implements newsapi.Provider.
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// ValidateRequest implements newsapi.Provider.
+func (p *Provider) ValidateRequest(req *newsapi.DownloadRequest) error {
+	if len(req.Sources) == 0 {
+		return &newsapi.ValidationError{Field: "sources", Message: "at least one feed URL is required"}
+	}
+
+	for _, feedURL := range req.Sources {
+		parsed, err := url.Parse(feedURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return &newsapi.ValidationError{Field: "sources", Message: fmt.Sprintf("'%s' is not an http(s) feed URL", feedURL)}
+		}
+	}
+
+	if req.PageSize <= 0 || req.PageSize > 100 {
+		return &newsapi.ValidationError{Field: "page_size", Message: "must be between 1 and 100"}
+	}
+
+	if req.StartPage <= 0 {
+		return &newsapi.ValidationError{Field: "start_page", Message: "must be greater than 0"}
+	}
+
+	if !req.From.IsZero() && !req.To.IsZero() && req.From.After(req.To) {
+		return &newsapi.ValidationError{Field: "from/to", Message: "from date must be before to date"}
+	}
+
+	return nil
+}
+
+// FetchPage implements newsapi.Provider. Articles published before req.From or
+// after req.To are left out; articles without a date are always kept.
+func (p *Provider) FetchPage(ctx context.Context, req *newsapi.DownloadRequest, page int) (*newsapi.Page, error) {
+	fingerprint := req.Fingerprint()
+
+	p.mutex.Lock()
+	current, ok := p.downloads[fingerprint]
+	p.mutex.Unlock()
+
+	if page == req.StartPage || !ok {
+		fetched, err := p.fetchAll(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		current = fetched
+
+		p.mutex.Lock()
+		p.downloads[fingerprint] = current
+		p.mutex.Unlock()
+	}
+	articles := current.articles
+
+	start := (page - 1) * req.PageSize
+	end := start + req.PageSize
+	if start > len(articles) {
+		start = len(articles)
+	}
+	if end > len(articles) {
+		end = len(articles)
+	}
+
+	return &newsapi.Page{
+		Articles:     articles[start:end],
+		TotalResults: len(articles),
+	}, nil
+}
+
+// FinishDownload implements newsapi.DownloadFinisher. It releases the articles of
+// the download and, when it completed, persists the validators of its feeds.
+func (p *Provider) FinishDownload(req *newsapi.DownloadRequest, completed bool) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	fingerprint := req.Fingerprint()
+	finished, ok := p.downloads[fingerprint]
+	delete(p.downloads, fingerprint)
+	if !ok || !completed {
+		return nil
+	}
+
+	for feedURL, feedValidators := range finished.validators {
+		p.saved[feedURL] = feedValidators
+	}
+	if p.statePath == "" {
+		return nil
+	}
+	return SaveState(p.statePath, p.saved)
+}
+
+// fetchAll fetches every feed of req and merges their articles, newest first.
+// A feed that fails is logged and skipped, unless every feed fails.
+func (p *Provider) fetchAll(ctx context.Context, req *newsapi.DownloadRequest) (*download, error) {
+	var (
+		articles []newsapi.Article
+		firstErr error
+		failed   int
+	)
+	fetched := &download{validators: make(map[string]Validators)}
+
+	for _, feedURL := range req.Sources {
+		feedArticles, err := p.fetchFeed(ctx, feedURL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			log.Printf("Failed to fetch feed %s: %v", feedURL, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+			continue
+		}
+
+		p.mutex.Lock()
+		if feedValidators, ok := p.validators[feedURL]; ok {
+			fetched.validators[feedURL] = feedValidators
+		}
+		p.mutex.Unlock()
+
+		for _, article := range feedArticles {
+			if inWindow(article.PublishedAt, req.From, req.To) {
+				articles = append(articles, article)
+			}
+		}
+	}
+
+	if failed == len(req.Sources) {
+		return nil, firstErr
+	}
+
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].PublishedAt.After(articles[j].PublishedAt)
+	})
+
+	fetched.articles = articles
+	return fetched, nil
+}
+
+// fetchFeed downloads and parses one feed with a conditional GET
+func (p *Provider) fetchFeed(ctx context.Context, feedURL string) ([]newsapi.Article, error) {
+	httpReq, err := http.NewRequest(http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("User-Agent", userAgent)
+	httpReq.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8")
+
+	p.mutex.Lock()
+	validators, known := p.validators[feedURL]
+	p.mutex.Unlock()
+
+	if known {
+		if validators.ETag != "" {
+			httpReq.Header.Set("If-None-Match", validators.ETag)
+		}
+		if validators.LastModified != "" {
+			httpReq.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+		log.Printf("Feed %s not modified", feedURL)
+		return p.feedArticles[feedURL], nil
+
+	case resp.StatusCode == http.StatusTooManyRequests:
+		retryAfter := defaultRetryAfter
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return nil, &newsapi.RateLimitError{
+			RetryAfter: retryAfter,
+			ResetTime:  time.Now().Add(retryAfter),
+			Message:    fmt.Sprintf("feed %s is rate limited, retry after %v", feedURL, retryAfter),
+		}
+
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return nil, &newsapi.NewsAPIError{
+			StatusCode: resp.StatusCode,
+			Code:       "feedUnavailable",
+			Message:    http.StatusText(resp.StatusCode),
+			URL:        feedURL,
+		}
+	}
+
+	// Refuse a body that announces it is over the limit before reading any of it
+	if p.maxBytes > 0 && resp.ContentLength > p.maxBytes {
+		return nil, &newsapi.ResponseTooLargeError{Limit: p.maxBytes, URL: feedURL}
+	}
+
+	feed, err := Parse(newsapi.LimitResponseBody(resp.Body, feedURL, p.maxBytes), feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.feedArticles[feedURL] = feed.Articles
+	p.validators[feedURL] = Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		UpdatedAt:    time.Now(),
+	}
+
+	return feed.Articles, nil
+}
+
+// inWindow reports whether publishedAt falls within [from, to]; unset bounds and
+// undated articles always match
+func inWindow(publishedAt, from, to time.Time) bool {
+	if publishedAt.IsZero() {
+		return true
+	}
+	if !from.IsZero() && publishedAt.Before(from) {
+		return false
+	}
+	if !to.IsZero() && publishedAt.After(to) {
+		return false
+	}
+	return true
+}
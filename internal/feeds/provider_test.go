@@ -0,0 +1,333 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/newsapi"
+)
+
+// Compile-time check that the feed provider plugs into the downloader
+var _ newsapi.Provider = (*Provider)(nil)
+
+// feedServer serves sampleRSS and sampleAtom with ETag and Last-Modified validators
+type feedServer struct {
+	mutex       sync.Mutex
+	requests    int
+	conditional int
+	status      int
+}
+
+func (s *feedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.requests++
+
+	if s.status != 0 {
+		w.WriteHeader(s.status)
+		return
+	}
+
+	etag := `"` + strings.TrimPrefix(r.URL.Path, "/") + `-v1"`
+	if r.Header.Get("If-None-Match") == etag {
+		s.conditional++
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", "Fri, 27 Oct 2023 12:00:00 GMT")
+	switch r.URL.Path {
+	case "/rss":
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(sampleRSS))
+	case "/atom":
+		w.Header().Set("Content-Type", "application/atom+xml")
+		w.Write([]byte(sampleAtom))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newFeedRequest(feedURLs ...string) *newsapi.DownloadRequest {
+	req := newsapi.NewDownloadRequest("", "")
+	req.Sources = feedURLs
+	req.PageSize = 2
+	return req
+}
+
+func TestProvider_FetchPage(t *testing.T) {
+	handler := &feedServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	provider, err := NewProviderWithHTTPClient(server.Client(), "")
+	if err != nil {
+		t.Fatalf("NewProviderWithHTTPClient() unexpected error: %v", err)
+	}
+
+	req := newFeedRequest(server.URL+"/rss", server.URL+"/atom")
+	if err := provider.ValidateRequest(req); err != nil {
+		t.Fatalf("ValidateRequest() unexpected error: %v", err)
+	}
+
+	first, err := provider.FetchPage(context.Background(), req, 1)
+	if err != nil {
+		t.Fatalf("FetchPage() unexpected error: %v", err)
+	}
+	if first.TotalResults != 5 || len(first.Articles) != 2 {
+		t.Fatalf("Expected 5 results with 2 on page 1, got %d with %d", first.TotalResults, len(first.Articles))
+	}
+
+	// Articles from both feeds are merged newest first
+	if first.Articles[0].Title != "Relative link" || first.Articles[1].Title != "Markets & Rates" {
+		t.Errorf("Unexpected order: '%s', '%s'", first.Articles[0].Title, first.Articles[1].Title)
+	}
+
+	// Later pages are served from the first page's fetch
+	third, err := provider.FetchPage(context.Background(), req, 3)
+	if err != nil {
+		t.Fatalf("FetchPage() unexpected error: %v", err)
+	}
+	if len(third.Articles) != 1 || handler.requests != 2 {
+		t.Errorf("Expected 1 article on page 3 and no new requests, got %d articles and %d requests", len(third.Articles), handler.requests)
+	}
+}
+
+func TestProvider_ConditionalGet(t *testing.T) {
+	handler := &feedServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	statePath := StatePath(t.TempDir())
+	provider, err := NewProviderWithHTTPClient(server.Client(), statePath)
+	if err != nil {
+		t.Fatalf("NewProviderWithHTTPClient() unexpected error: %v", err)
+	}
+
+	req := newFeedRequest(server.URL + "/rss")
+	if _, err := provider.FetchPage(context.Background(), req, 1); err != nil {
+		t.Fatalf("FetchPage() unexpected error: %v", err)
+	}
+
+	// Within the process, an unchanged feed still yields its articles
+	page, err := provider.FetchPage(context.Background(), req, 1)
+	if err != nil {
+		t.Fatalf("FetchPage() unexpected error: %v", err)
+	}
+	if handler.conditional != 1 || page.TotalResults != 3 {
+		t.Errorf("Expected a 304 served from memory, got %d conditional hits and %d results", handler.conditional, page.TotalResults)
+	}
+
+	// Validators are only persisted once the download's pages were saved
+	if err := provider.FinishDownload(req, false); err != nil {
+		t.Fatalf("FinishDownload() unexpected error: %v", err)
+	}
+	if state, err := LoadState(statePath); err != nil || len(state) != 0 {
+		t.Fatalf("Expected no persisted validators after a failed download, got %+v, %v", state, err)
+	}
+	if len(provider.downloads) != 0 {
+		t.Errorf("Expected the finished download to be released, got %d held", len(provider.downloads))
+	}
+
+	if _, err := provider.FetchPage(context.Background(), req, 1); err != nil {
+		t.Fatalf("FetchPage() unexpected error: %v", err)
+	}
+	if err := provider.FinishDownload(req, true); err != nil {
+		t.Fatalf("FinishDownload() unexpected error: %v", err)
+	}
+
+	// A new process reuses the persisted validators, and an unchanged feed has nothing new
+	state, err := LoadState(statePath)
+	if err != nil || state[server.URL+"/rss"].ETag != `"rss-v1"` {
+		t.Fatalf("Expected persisted ETag, got %+v, %v", state, err)
+	}
+
+	restarted, err := NewProviderWithHTTPClient(server.Client(), statePath)
+	if err != nil {
+		t.Fatalf("NewProviderWithHTTPClient() unexpected error: %v", err)
+	}
+	page, err = restarted.FetchPage(context.Background(), req, 1)
+	if err != nil {
+		t.Fatalf("FetchPage() unexpected error: %v", err)
+	}
+	if handler.conditional != 3 || page.TotalResults != 0 {
+		t.Errorf("Expected an empty 304 after restart, got %d conditional hits and %d results", handler.conditional, page.TotalResults)
+	}
+}
+
+func TestProvider_Errors(t *testing.T) {
+	handler := &feedServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	provider, _ := NewProviderWithHTTPClient(server.Client(), "")
+
+	// One missing feed does not fail the others
+	page, err := provider.FetchPage(context.Background(), newFeedRequest(server.URL+"/rss", server.URL+"/missing"), 1)
+	if err != nil || page.TotalResults != 3 {
+		t.Errorf("Expected the working feed's 3 results, got %+v, %v", page, err)
+	}
+
+	// When every feed fails the error is returned as-is
+	_, err = provider.FetchPage(context.Background(), newFeedRequest(server.URL+"/missing"), 1)
+	if apiErr, ok := err.(*newsapi.NewsAPIError); !ok || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a 404 NewsAPIError, got %v", err)
+	}
+
+	handler.status = http.StatusTooManyRequests
+	_, err = provider.FetchPage(context.Background(), newFeedRequest(server.URL+"/rss"), 1)
+	if _, ok := err.(*newsapi.RateLimitError); !ok {
+		t.Errorf("Expected a RateLimitError, got %v", err)
+	}
+
+	handler.status = http.StatusBadGateway
+	_, err = provider.FetchPage(context.Background(), newFeedRequest(server.URL+"/rss"), 1)
+	if !newsapi.IsRetryable(err) {
+		t.Errorf("Expected a retryable error for 502, got %v", err)
+	}
+}
+
+func TestProvider_ValidateRequest(t *testing.T) {
+	provider, _ := NewProviderWithHTTPClient(http.DefaultClient, "")
+
+	tests := []struct {
+		name    string
+		req     *newsapi.DownloadRequest
+		wantErr bool
+	}{
+		{"valid", newFeedRequest("https://example.com/feed"), false},
+		{"no feeds", newFeedRequest(), true},
+		{"not a URL", newFeedRequest("example.com/feed"), true},
+		{"ftp", newFeedRequest("ftp://example.com/feed"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := provider.ValidateRequest(tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// recordingPublisher implements kafka_producer.KafkaPublisher in memory
+type recordingPublisher struct {
+	messages []string
+}
+
+func (p *recordingPublisher) Publish(broker, topic, message string) error {
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+func (p *recordingPublisher) PublishWithContext(ctx context.Context, broker, topic, message string) error {
+	return p.Publish(broker, topic, message)
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+func TestProvider_ThroughDownloader(t *testing.T) {
+	server := httptest.NewServer(&feedServer{})
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+
+	provider, err := NewProviderWithHTTPClient(server.Client(), StatePath(cfg.OutputDir))
+	if err != nil {
+		t.Fatalf("NewProviderWithHTTPClient() unexpected error: %v", err)
+	}
+	publisher := &recordingPublisher{}
+	downloader := newsapi.NewNewsDownloader(provider, publisher, cfg)
+
+	req := newFeedRequest(server.URL+"/rss", server.URL+"/atom")
+	req.From = time.Date(2023, 10, 27, 9, 0, 0, 0, time.UTC)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("DownloadAllNewsToFile() unexpected error: %v", err)
+	}
+
+	// The Atom entry from 06:00 falls before From; the undated item is kept
+	if result.TotalArticles != 4 || len(result.FilePaths) != 2 || len(publisher.messages) != 2 {
+		t.Errorf("Expected 4 articles in 2 published files, got %d in %d files and %d messages",
+			result.TotalArticles, len(result.FilePaths), len(publisher.messages))
+	}
+
+	// The completed download persisted its validators and released its articles
+	if state, err := LoadState(StatePath(cfg.OutputDir)); err != nil || len(state) != 2 {
+		t.Errorf("Expected validators for both feeds, got %+v, %v", state, err)
+	}
+	if len(provider.downloads) != 0 {
+		t.Errorf("Expected no downloads held after the run, got %d", len(provider.downloads))
+	}
+}
+
+func TestProvider_CapsFeedBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing first sends the body chunked, without a Content-Length
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(sampleRSS))
+	}))
+	defer server.Close()
+
+	provider, err := NewProviderWithHTTPClient(server.Client(), "")
+	if err != nil {
+		t.Fatalf("NewProviderWithHTTPClient() unexpected error: %v", err)
+	}
+	provider.SetMaxResponseBytes(int64(len(sampleRSS) / 2))
+
+	for _, path := range []string{"/sized", "/chunked"} {
+		var tooLarge *newsapi.ResponseTooLargeError
+		if _, err := provider.fetchFeed(context.Background(), server.URL+path); !errors.As(err, &tooLarge) {
+			t.Errorf("%s: expected a ResponseTooLargeError, got %v", path, err)
+		}
+	}
+}
+
+func TestProvider_PathsHaveNoEndpoint(t *testing.T) {
+	server := httptest.NewServer(&feedServer{})
+	defer server.Close()
+
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.OutputPathTemplate = "{provider}/{endpoint}/{run_id}_p{page}.{ext}"
+
+	provider, err := NewProviderWithHTTPClient(server.Client(), "")
+	if err != nil {
+		t.Fatalf("NewProviderWithHTTPClient() unexpected error: %v", err)
+	}
+	downloader := newsapi.NewNewsDownloader(provider, &recordingPublisher{}, cfg)
+
+	req := newFeedRequest(server.URL + "/rss")
+	req.From = time.Date(2023, 10, 27, 9, 0, 0, 0, time.UTC)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("DownloadAllNewsToFile() unexpected error: %v", err)
+	}
+	if len(result.FilePaths) == 0 {
+		t.Fatal("Expected saved pages")
+	}
+
+	// Feed requests carry the default top-headlines endpoint, which is not theirs
+	for _, path := range result.FilePaths {
+		if !strings.Contains(filepath.ToSlash(path), "/feeds/all/") {
+			t.Errorf("Expected an empty endpoint in %s", path)
+		}
+	}
+}
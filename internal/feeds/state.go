@@ -0,0 +1,65 @@
+package feeds
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go-news-agg/internal/newsapi"
+)
+
+// Validators are the HTTP cache validators last returned for a feed, sent back as
+// If-None-Match and If-Modified-Since so unchanged feeds are not downloaded again
+type Validators struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// StatePath returns where the feed validators are stored under dir
+func StatePath(dir string) string {
+	return filepath.Join(dir, ".feeds", "validators.json")
+}
+
+// LoadState reads the validators of every feed, keyed by feed URL. A missing file
+// yields an empty state.
+func LoadState(filePath string) (map[string]Validators, error) {
+	state := make(map[string]Validators)
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, &newsapi.FileOperationError{Operation: "read file", FilePath: filePath, Cause: err}
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, &newsapi.FileOperationError{Operation: "unmarshal JSON", FilePath: filePath, Cause: err}
+	}
+	return state, nil
+}
+
+// SaveState writes the feed validators atomically
+func SaveState(filePath string, state map[string]Validators) error {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &newsapi.FileOperationError{Operation: "create directory", FilePath: dir, Cause: err}
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return &newsapi.FileOperationError{Operation: "marshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return &newsapi.FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err}
+	}
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return &newsapi.FileOperationError{Operation: "rename file", FilePath: filePath, Cause: err}
+	}
+	return nil
+}
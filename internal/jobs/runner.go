@@ -0,0 +1,173 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go-news-agg/internal/newsapi"
+)
+
+// Downloader is the part of *newsapi.NewsDownloader the runner needs. Every job
+// goes through the same downloader, so they share one client, rate limiter and
+// Kafka producer.
+type Downloader interface {
+	DownloadAllNewsToFile(ctx context.Context, req *newsapi.DownloadRequest) (*newsapi.DownloadResult, error)
+}
+
+// JobResult summarises one job of a spec
+type JobResult struct {
+	Name     string                  `json:"name"`
+	Requests int                     `json:"requests"`
+	Result   *newsapi.DownloadResult `json:"result"`
+	Errors   []error                 `json:"errors,omitempty"`
+}
+
+// MarshalJSON encodes the job with its errors as redacted messages, since error
+// values have no JSON form of their own
+func (j JobResult) MarshalJSON() ([]byte, error) {
+	type plain JobResult
+	var messages []string
+	for _, err := range j.Errors {
+		messages = append(messages, newsapi.Redact(err.Error()))
+	}
+	return json.Marshal(struct {
+		plain
+		Errors []string `json:"errors,omitempty"`
+	}{
+		plain:  plain(j),
+		Errors: messages,
+	})
+}
+
+// RunResult aggregates every job of a spec
+type RunResult struct {
+	Jobs      []JobResult             `json:"jobs"`
+	Aggregate *newsapi.DownloadResult `json:"aggregate"`
+}
+
+// Failed reports the number of jobs that had at least one failed request
+func (r *RunResult) Failed() int {
+	failed := 0
+	for _, job := range r.Jobs {
+		if len(job.Errors) > 0 {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Runner executes the jobs of a spec one request at a time
+type Runner struct {
+	downloader      Downloader
+	apiKey          string
+	defaultPageSize int
+}
+
+// NewRunner creates a runner. defaultPageSize applies to jobs that set no page size.
+func NewRunner(downloader Downloader, apiKey string, defaultPageSize int) *Runner {
+	return &Runner{
+		downloader:      downloader,
+		apiKey:          apiKey,
+		defaultPageSize: defaultPageSize,
+	}
+}
+
+// Run executes every job in the spec. A failed request is recorded against its job
+// and the run carries on; only cancellation of ctx stops it early.
+func (r *Runner) Run(ctx context.Context, spec *Spec) (*RunResult, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	run := &RunResult{
+		Aggregate: &newsapi.DownloadResult{
+			StartTime: time.Now(),
+			FilePaths: make([]string, 0),
+			Errors:    make([]error, 0),
+		},
+	}
+
+	for _, job := range spec.Jobs {
+		jobResult, err := r.RunJob(ctx, job)
+		run.Jobs = append(run.Jobs, jobResult)
+		run.Aggregate.Merge(jobResult.Result)
+
+		// A request that failed on a page already brought that page's error in with its result
+		for _, jobErr := range jobResult.Errors {
+			if !jobResult.Result.ReportsError(jobErr) {
+				run.Aggregate.Errors = append(run.Aggregate.Errors, jobErr)
+			}
+		}
+
+		if err != nil {
+			return r.finish(run), err
+		}
+	}
+
+	return r.finish(run), nil
+}
+
+// RunJob downloads every request of one job. The returned error is only set when
+// ctx was cancelled or a stop was requested; other failures are kept in the job's result.
+func (r *Runner) RunJob(ctx context.Context, job Job) (JobResult, error) {
+	requests := job.Requests(r.apiKey, r.defaultPageSize)
+	jobResult := JobResult{
+		Name:     job.Name,
+		Requests: len(requests),
+		Result: &newsapi.DownloadResult{
+			StartTime: time.Now(),
+			FilePaths: make([]string, 0),
+			Errors:    make([]error, 0),
+		},
+	}
+
+	log.Printf("Running job '%s' (%d requests)", job.Name, len(requests))
+
+	for _, req := range requests {
+		if ctx.Err() != nil {
+			return r.finishJob(jobResult), ctx.Err()
+		}
+		if newsapi.StopRequested(ctx) {
+			return r.finishJob(jobResult), newsapi.ErrStopped
+		}
+
+		result, err := r.downloader.DownloadAllNewsToFile(ctx, req)
+		if result != nil {
+			jobResult.Result.Merge(result)
+		}
+		if err != nil {
+			err = fmt.Errorf("job '%s' %s: %w", job.Name, describeRequest(req), err)
+			log.Printf("Request failed: %v", err)
+			jobResult.Errors = append(jobResult.Errors, err)
+			if ctx.Err() != nil {
+				return r.finishJob(jobResult), ctx.Err()
+			}
+			if errors.Is(err, newsapi.ErrStopped) {
+				return r.finishJob(jobResult), newsapi.ErrStopped
+			}
+		}
+	}
+
+	return r.finishJob(jobResult), nil
+}
+
+func (r *Runner) finishJob(jobResult JobResult) JobResult {
+	jobResult.Result.EndTime = time.Now()
+	jobResult.Result.Duration = jobResult.Result.EndTime.Sub(jobResult.Result.StartTime)
+	return jobResult
+}
+
+func (r *Runner) finish(run *RunResult) *RunResult {
+	run.Aggregate.EndTime = time.Now()
+	run.Aggregate.Duration = run.Aggregate.EndTime.Sub(run.Aggregate.StartTime)
+	return run
+}
+
+// describeRequest identifies which combination of a job a request came from
+func describeRequest(req *newsapi.DownloadRequest) string {
+	return fmt.Sprintf("[country=%q category=%q query=%q language=%q]", req.Country, req.Category, req.Query, req.Language)
+}
@@ -0,0 +1,209 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/newsapi"
+)
+
+// fakeDownloader records every request and returns one page per request,
+// failing the requests whose query is listed in fail
+type fakeDownloader struct {
+	mutex    sync.Mutex
+	requests []*newsapi.DownloadRequest
+	fail     map[string]bool
+	onCall   func()
+	delay    time.Duration
+
+	// active and maxActive count concurrent calls
+	active    int
+	maxActive int
+}
+
+func (f *fakeDownloader) DownloadAllNewsToFile(ctx context.Context, req *newsapi.DownloadRequest) (*newsapi.DownloadResult, error) {
+	f.mutex.Lock()
+	f.requests = append(f.requests, req)
+	calls := len(f.requests)
+	f.active++
+	if f.active > f.maxActive {
+		f.maxActive = f.active
+	}
+	f.mutex.Unlock()
+
+	defer func() {
+		f.mutex.Lock()
+		f.active--
+		f.mutex.Unlock()
+	}()
+
+	if f.onCall != nil {
+		f.onCall()
+	}
+	time.Sleep(f.delay)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if f.fail[req.Query] {
+		return nil, errors.New("boom")
+	}
+
+	return &newsapi.DownloadResult{
+		TotalArticles:   10,
+		PagesDownloaded: 1,
+		FilePaths:       []string{fmt.Sprintf("%s_%s_%s.json", req.Country, req.Category, req.Query)},
+		TotalAttempts:   1,
+		MaxPublishedAt:  time.Date(2023, 10, 27, 10, calls, 0, 0, time.UTC),
+	}, nil
+}
+
+func TestRunner_Run(t *testing.T) {
+	spec := &Spec{Jobs: []Job{
+		{Name: "headlines", Countries: []string{"us", "gb"}, Categories: []string{"business", "technology"}, PageSize: 50},
+		{Name: "search", Endpoint: newsapi.EndpointEverything, Queries: []string{"golang", "rust"}, Languages: []string{"en"}},
+	}}
+
+	downloader := &fakeDownloader{fail: map[string]bool{"rust": true}}
+	run, err := NewRunner(downloader, "key", 20).Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if len(downloader.requests) != 6 {
+		t.Fatalf("Expected 6 requests, got %d", len(downloader.requests))
+	}
+	if len(run.Jobs) != 2 {
+		t.Fatalf("Expected 2 job results, got %d", len(run.Jobs))
+	}
+
+	headlines := run.Jobs[0]
+	if headlines.Name != "headlines" || headlines.Requests != 4 {
+		t.Errorf("Unexpected headlines result: %+v", headlines)
+	}
+	if headlines.Result.TotalArticles != 40 || len(headlines.Result.FilePaths) != 4 {
+		t.Errorf("Expected 40 articles in 4 files, got %d in %d", headlines.Result.TotalArticles, len(headlines.Result.FilePaths))
+	}
+	if len(headlines.Errors) != 0 {
+		t.Errorf("Expected no headlines errors, got %v", headlines.Errors)
+	}
+
+	search := run.Jobs[1]
+	if search.Requests != 2 || search.Result.TotalArticles != 10 {
+		t.Errorf("Unexpected search result: %+v", search)
+	}
+	if len(search.Errors) != 1 {
+		t.Errorf("Expected 1 search error, got %v", search.Errors)
+	}
+
+	if run.Aggregate.TotalArticles != 50 || len(run.Aggregate.FilePaths) != 5 {
+		t.Errorf("Expected 50 articles in 5 files overall, got %d in %d", run.Aggregate.TotalArticles, len(run.Aggregate.FilePaths))
+	}
+	if len(run.Aggregate.Errors) != 1 {
+		t.Errorf("Expected 1 aggregate error, got %v", run.Aggregate.Errors)
+	}
+	if run.Failed() != 1 {
+		t.Errorf("Expected 1 failed job, got %d", run.Failed())
+	}
+	if run.Aggregate.MaxPublishedAt.Minute() != 5 {
+		t.Errorf("Expected newest article from the last successful request, got %v", run.Aggregate.MaxPublishedAt)
+	}
+}
+
+// pageErrorDownloader fails every request on its second page, returning the
+// partial result along with the error like *newsapi.NewsDownloader does
+type pageErrorDownloader struct{}
+
+func (pageErrorDownloader) DownloadAllNewsToFile(ctx context.Context, req *newsapi.DownloadRequest) (*newsapi.DownloadResult, error) {
+	apiErr := &newsapi.NewsAPIError{StatusCode: 401, Code: "apiKeyExhausted"}
+	return &newsapi.DownloadResult{
+		PagesDownloaded: 1,
+		Errors:          []error{fmt.Errorf("page 2: %w", apiErr)},
+	}, fmt.Errorf("API error on page 2: %w", apiErr)
+}
+
+func TestRunner_RunCountsPageErrorsOnce(t *testing.T) {
+	spec := &Spec{Jobs: []Job{{Name: "headlines", Countries: []string{"us"}}}}
+
+	run, err := NewRunner(pageErrorDownloader{}, "key", 20).Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if run.Failed() != 1 || len(run.Jobs[0].Errors) != 1 {
+		t.Errorf("Expected the request failure to be recorded against its job, got %v", run.Jobs[0].Errors)
+	}
+	if len(run.Aggregate.Errors) != 1 {
+		t.Errorf("Expected 1 aggregate error, got %v", run.Aggregate.Errors)
+	}
+}
+
+func TestJobResult_MarshalsErrorMessages(t *testing.T) {
+	spec := &Spec{Jobs: []Job{{Name: "headlines", Countries: []string{"us"}}}}
+
+	run, err := NewRunner(pageErrorDownloader{}, "key", 20).Run(context.Background(), spec)
+	if err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+
+	var report struct {
+		Jobs []struct {
+			Name   string   `json:"name"`
+			Errors []string `json:"errors"`
+		} `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Expected job errors as strings, got %s: %v", data, err)
+	}
+	if len(report.Jobs) != 1 || report.Jobs[0].Name != "headlines" || len(report.Jobs[0].Errors) != 1 || report.Jobs[0].Errors[0] == "" {
+		t.Errorf("Expected the job's error message in the report, got %s", data)
+	}
+}
+
+func TestRunner_RunCancelled(t *testing.T) {
+	spec := &Spec{Jobs: []Job{
+		{Name: "first", Countries: []string{"us", "gb", "de"}},
+		{Name: "second", Countries: []string{"fr"}},
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	downloader := &fakeDownloader{onCall: cancel}
+	run, err := NewRunner(downloader, "key", 20).Run(ctx, spec)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	if len(downloader.requests) != 1 {
+		t.Errorf("Expected the run to stop after 1 request, got %d", len(downloader.requests))
+	}
+	if run == nil || len(run.Jobs) != 1 {
+		t.Fatalf("Expected a partial result with 1 job, got %+v", run)
+	}
+	if len(run.Jobs[0].Errors) != 1 || len(run.Aggregate.Errors) != 1 {
+		t.Errorf("Expected the interrupted request to be recorded, got %v", run.Aggregate.Errors)
+	}
+}
+
+func TestRunner_RunInvalidSpec(t *testing.T) {
+	_, err := NewRunner(&fakeDownloader{}, "key", 20).Run(context.Background(), &Spec{})
+	if err == nil {
+		t.Error("Run() expected error for empty spec")
+	}
+}
+
+func (f *fakeDownloader) calls() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return len(f.requests)
+}
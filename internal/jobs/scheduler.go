@@ -0,0 +1,203 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"go-news-agg/internal/newsapi"
+)
+
+// Schedule decides when a job runs next
+type Schedule interface {
+	// Next returns the first run time strictly after t
+	Next(t time.Time) time.Time
+}
+
+// intervalSchedule runs a job at a fixed interval after the previous run time
+type intervalSchedule struct {
+	every time.Duration
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.every)
+}
+
+// ParseSchedule parses a standard five-field cron expression or a descriptor such as
+// "@hourly" or "@every 15m"
+func ParseSchedule(expr string) (Schedule, error) {
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule '%s': %w", expr, err)
+	}
+	return schedule, nil
+}
+
+// ParseInterval parses a positive duration such as "15m" into a schedule
+func ParseInterval(value string) (Schedule, error) {
+	every, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interval '%s': %w", value, err)
+	}
+	if every <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got %s", value)
+	}
+	return intervalSchedule{every: every}, nil
+}
+
+// schedule returns the job's own schedule, or nil when it sets none
+func (j *Job) schedule() (Schedule, error) {
+	switch {
+	case j.Schedule != "" && j.Interval != "":
+		return nil, fmt.Errorf("schedule and interval cannot both be set")
+	case j.Schedule != "":
+		return ParseSchedule(j.Schedule)
+	case j.Interval != "":
+		return ParseInterval(j.Interval)
+	default:
+		return nil, nil
+	}
+}
+
+// SchedulerOptions controls a Scheduler
+type SchedulerOptions struct {
+	// DefaultSchedule applies to jobs without a schedule or interval of their own
+	DefaultSchedule Schedule
+	// RunOnStart runs every job once as soon as the scheduler starts
+	RunOnStart bool
+	// OnComplete, if set, is called after every job run
+	OnComplete func(result JobResult, err error)
+}
+
+// Scheduler runs the jobs of a spec repeatedly on their schedules. A job whose
+// previous run is still in progress when it is due again is skipped for that slot,
+// so runs of the same job never overlap.
+type Scheduler struct {
+	runner  *Runner
+	options SchedulerOptions
+
+	mutex   sync.Mutex
+	running map[string]bool
+	wg      sync.WaitGroup
+
+	// now is the clock, replaceable in tests
+	now func() time.Time
+}
+
+// NewScheduler creates a scheduler that runs jobs through runner
+func NewScheduler(runner *Runner, options SchedulerOptions) *Scheduler {
+	return &Scheduler{
+		runner:  runner,
+		options: options,
+		running: make(map[string]bool),
+		now:     time.Now,
+	}
+}
+
+// Run schedules every job of the spec until ctx is done. Cancelling ctx does not
+// interrupt runs in flight: they finish the page they are on and stop at the next
+// page boundary, and Run returns once they have.
+func (s *Scheduler) Run(ctx context.Context, spec *Spec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+
+	schedules := make([]Schedule, len(spec.Jobs))
+	for i, job := range spec.Jobs {
+		schedule, err := job.schedule()
+		if err != nil {
+			return fmt.Errorf("job '%s': %w", job.Name, err)
+		}
+		if schedule == nil {
+			schedule = s.options.DefaultSchedule
+		}
+		if schedule == nil {
+			return fmt.Errorf("job '%s': no schedule or interval set and no default schedule given", job.Name)
+		}
+		schedules[i] = schedule
+	}
+
+	// Runs are detached from ctx so that shutdown stops them between pages rather
+	// than mid-request
+	runCtx := newsapi.WithStop(context.Background(), ctx.Done())
+
+	var loops sync.WaitGroup
+	for i, job := range spec.Jobs {
+		loops.Add(1)
+		go func(job Job, schedule Schedule) {
+			defer loops.Done()
+			s.loop(ctx, runCtx, job, schedule)
+		}(job, schedules[i])
+	}
+
+	<-ctx.Done()
+	log.Printf("Scheduler stopping, waiting for running jobs to finish their current page")
+
+	loops.Wait()
+	s.wg.Wait()
+	return nil
+}
+
+// loop triggers one job each time its schedule comes due, until ctx is done
+func (s *Scheduler) loop(ctx, runCtx context.Context, job Job, schedule Schedule) {
+	if s.options.RunOnStart {
+		s.trigger(runCtx, job)
+	}
+
+	for {
+		next := schedule.Next(s.now())
+		log.Printf("Job '%s' next run at %s", job.Name, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(next.Sub(s.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		// The timer may have fired together with shutdown
+		if ctx.Err() != nil {
+			return
+		}
+		s.trigger(runCtx, job)
+	}
+}
+
+// trigger starts a run of job unless one is already in progress
+func (s *Scheduler) trigger(ctx context.Context, job Job) {
+	s.mutex.Lock()
+	if s.running[job.Name] {
+		s.mutex.Unlock()
+		log.Printf("Job '%s' is still running, skipping this run", job.Name)
+		return
+	}
+	s.running[job.Name] = true
+	s.wg.Add(1)
+	s.mutex.Unlock()
+
+	go func() {
+		defer func() {
+			s.mutex.Lock()
+			s.running[job.Name] = false
+			s.mutex.Unlock()
+			s.wg.Done()
+		}()
+
+		result, err := s.runner.RunJob(ctx, job)
+		if err != nil {
+			log.Printf("Job '%s' stopped: %v", job.Name, err)
+		} else {
+			log.Printf("Job '%s' finished: %d articles, %d files, %d errors in %v", job.Name,
+				result.Result.TotalArticles, len(result.Result.FilePaths), len(result.Errors), result.Result.Duration)
+		}
+
+		if s.options.OnComplete != nil {
+			s.options.OnComplete(result, err)
+		}
+	}()
+}
@@ -0,0 +1,157 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/newsapi"
+)
+
+func TestParseSchedule(t *testing.T) {
+	base := time.Date(2024, 1, 15, 10, 20, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    time.Time
+		wantErr bool
+	}{
+		{"hourly descriptor", "@hourly", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC), false},
+		{"every descriptor", "@every 15m", base.Add(15 * time.Minute), false},
+		{"cron expression", "30 6 * * *", time.Date(2024, 1, 16, 6, 30, 0, 0, time.UTC), false},
+		{"invalid", "not a schedule", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("ParseSchedule() expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSchedule() unexpected error: %v", err)
+			}
+			if got := schedule.Next(base); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJob_ValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		job     Job
+		wantErr string
+	}{
+		{"interval", Job{Name: "a", Countries: []string{"us"}, Interval: "15m"}, ""},
+		{"cron", Job{Name: "a", Countries: []string{"us"}, Schedule: "0 * * * *"}, ""},
+		{"both", Job{Name: "a", Countries: []string{"us"}, Schedule: "@hourly", Interval: "15m"}, "cannot both be set"},
+		{"bad interval", Job{Name: "a", Countries: []string{"us"}, Interval: "soon"}, "invalid interval"},
+		{"negative interval", Job{Name: "a", Countries: []string{"us"}, Interval: "-5m"}, "must be positive"},
+		{"bad cron", Job{Name: "a", Countries: []string{"us"}, Schedule: "61 * * * *"}, "invalid schedule"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.job.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing '%s'", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduler_PreventsOverlappingRuns(t *testing.T) {
+	spec := &Spec{Jobs: []Job{{Name: "us", Countries: []string{"us"}, Interval: "5ms"}}}
+	downloader := &fakeDownloader{delay: 40 * time.Millisecond}
+
+	var mutex sync.Mutex
+	completed := 0
+	scheduler := NewScheduler(NewRunner(downloader, "key", 20), SchedulerOptions{
+		OnComplete: func(result JobResult, err error) {
+			mutex.Lock()
+			completed++
+			mutex.Unlock()
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	if err := scheduler.Run(ctx, spec); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if downloader.maxActive != 1 {
+		t.Errorf("Expected runs of the same job never to overlap, got %d concurrent", downloader.maxActive)
+	}
+	if downloader.calls() < 2 {
+		t.Errorf("Expected the job to run repeatedly, got %d runs", downloader.calls())
+	}
+	// Intervals that came due during a run were skipped rather than queued
+	if downloader.calls() > 5 {
+		t.Errorf("Expected skipped slots while running, got %d runs", downloader.calls())
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if completed != downloader.calls() {
+		t.Errorf("Expected every run to complete before Run returned, got %d of %d", completed, downloader.calls())
+	}
+}
+
+func TestScheduler_ShutdownFinishesRunInFlight(t *testing.T) {
+	spec := &Spec{Jobs: []Job{{Name: "multi", Countries: []string{"us", "gb", "de"}, Interval: "1h"}}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Shut down while the first request is in flight
+	downloader := &fakeDownloader{delay: 20 * time.Millisecond, onCall: cancel}
+
+	var result JobResult
+	var runErr error
+	scheduler := NewScheduler(NewRunner(downloader, "key", 20), SchedulerOptions{
+		RunOnStart: true,
+		OnComplete: func(r JobResult, err error) {
+			result, runErr = r, err
+		},
+	})
+
+	if err := scheduler.Run(ctx, spec); err != nil {
+		t.Fatalf("Run() unexpected error: %v", err)
+	}
+
+	if downloader.calls() != 1 {
+		t.Errorf("Expected the run to stop after the request in flight, got %d requests", downloader.calls())
+	}
+	if result.Result == nil || result.Result.TotalArticles != 10 {
+		t.Errorf("Expected the request in flight to complete, got %+v", result)
+	}
+	if runErr != newsapi.ErrStopped {
+		t.Errorf("Expected ErrStopped, got %v", runErr)
+	}
+}
+
+func TestScheduler_RequiresSchedule(t *testing.T) {
+	spec := &Spec{Jobs: []Job{{Name: "us", Countries: []string{"us"}}}}
+	scheduler := NewScheduler(NewRunner(&fakeDownloader{}, "key", 20), SchedulerOptions{})
+
+	err := scheduler.Run(context.Background(), spec)
+	if err == nil || !strings.Contains(err.Error(), "no schedule") {
+		t.Errorf("Run() error = %v, want missing schedule error", err)
+	}
+}
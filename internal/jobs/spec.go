@@ -0,0 +1,179 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"go-news-agg/internal/newsapi"
+)
+
+// Spec is a collection of named download jobs run together in one process
+type Spec struct {
+	Jobs []Job `json:"jobs" yaml:"jobs"`
+}
+
+// Job describes one named download. Every combination of its list fields becomes
+// a separate request, e.g. two countries and three categories make six requests.
+type Job struct {
+	Name       string           `json:"name" yaml:"name"`
+	Endpoint   newsapi.Endpoint `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	Countries  []string         `json:"countries,omitempty" yaml:"countries,omitempty"`
+	Categories []string         `json:"categories,omitempty" yaml:"categories,omitempty"`
+	Queries    []string         `json:"queries,omitempty" yaml:"queries,omitempty"`
+	Languages  []string         `json:"languages,omitempty" yaml:"languages,omitempty"`
+	Sources    []string         `json:"sources,omitempty" yaml:"sources,omitempty"`
+	Domains    []string         `json:"domains,omitempty" yaml:"domains,omitempty"`
+	SearchIn   []string         `json:"search_in,omitempty" yaml:"search_in,omitempty"`
+	SortBy     string           `json:"sort_by,omitempty" yaml:"sort_by,omitempty"`
+	PageSize   int              `json:"page_size,omitempty" yaml:"page_size,omitempty"`
+
+	// Schedule is a cron expression such as "0 * * * *" or "@hourly", and Interval a
+	// duration such as "15m"; at most one may be set. Both only apply in daemon mode.
+	Schedule string `json:"schedule,omitempty" yaml:"schedule,omitempty"`
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+}
+
+// LoadSpec reads a job spec from a JSON or YAML file, chosen by the file extension
+func LoadSpec(filePath string) (*Spec, error) {
+	if filePath == "" {
+		return nil, fmt.Errorf("job spec path cannot be empty")
+	}
+
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job spec '%s': %w", filePath, err)
+	}
+
+	spec, err := ParseSpec(data, filepath.Ext(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("invalid job spec '%s': %w", filePath, err)
+	}
+
+	return spec, nil
+}
+
+// ParseSpec decodes a job spec. ext selects the format: ".yaml" and ".yml" are
+// parsed as YAML, anything else as JSON.
+func ParseSpec(data []byte, ext string) (*Spec, error) {
+	var spec Spec
+
+	switch strings.ToLower(ext) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal YAML: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(data, &spec); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+	}
+
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// Validate checks that the spec has uniquely named, well-formed jobs
+func (s *Spec) Validate() error {
+	if len(s.Jobs) == 0 {
+		return fmt.Errorf("job spec must define at least one job")
+	}
+
+	seen := make(map[string]bool, len(s.Jobs))
+	for i, job := range s.Jobs {
+		if job.Name == "" {
+			return fmt.Errorf("job %d: name cannot be empty", i+1)
+		}
+		if seen[job.Name] {
+			return fmt.Errorf("job '%s': duplicate name", job.Name)
+		}
+		seen[job.Name] = true
+
+		if err := job.Validate(); err != nil {
+			return fmt.Errorf("job '%s': %w", job.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that every request the job expands to is valid
+func (j *Job) Validate() error {
+	if j.PageSize < 0 || j.PageSize > 100 {
+		return fmt.Errorf("page_size must be between 1 and 100, got %d", j.PageSize)
+	}
+
+	if j.Endpoint != newsapi.EndpointEverything && len(j.Languages) > 0 {
+		return fmt.Errorf("languages only apply to the everything endpoint")
+	}
+
+	if j.Schedule != "" || j.Interval != "" {
+		if _, err := j.schedule(); err != nil {
+			return err
+		}
+	}
+
+	// Expand with a placeholder key, since only the shape of the requests matters here
+	for _, req := range j.Requests("placeholder", 20) {
+		if err := req.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Requests expands the job into one download request per combination of countries,
+// categories, queries and languages. defaultPageSize applies when the job sets none.
+func (j *Job) Requests(apiKey string, defaultPageSize int) []*newsapi.DownloadRequest {
+	endpoint := j.Endpoint
+	if endpoint == "" {
+		endpoint = newsapi.EndpointTopHeadlines
+	}
+
+	pageSize := j.PageSize
+	if pageSize == 0 {
+		pageSize = defaultPageSize
+	}
+
+	var requests []*newsapi.DownloadRequest
+	for _, country := range orBlank(j.Countries) {
+		for _, category := range orBlank(j.Categories) {
+			for _, query := range orBlank(j.Queries) {
+				for _, language := range orBlank(j.Languages) {
+					req := newsapi.NewDownloadRequest(apiKey, country)
+					req.Endpoint = endpoint
+					req.Category = category
+					req.Query = query
+					req.Language = language
+					req.Sources = j.Sources
+					req.Domains = j.Domains
+					req.SearchIn = j.SearchIn
+					req.PageSize = pageSize
+					if j.SortBy != "" {
+						req.SortBy = j.SortBy
+					}
+					requests = append(requests, req)
+				}
+			}
+		}
+	}
+
+	return requests
+}
+
+// orBlank returns values, or a single blank value when values is empty, so that
+// unset list fields do not multiply the number of requests
+func orBlank(values []string) []string {
+	if len(values) == 0 {
+		return []string{""}
+	}
+	return values
+}
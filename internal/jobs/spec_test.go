@@ -0,0 +1,189 @@
+package jobs
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-news-agg/internal/newsapi"
+)
+
+func TestParseSpec_Formats(t *testing.T) {
+	jsonSpec := `{"jobs": [
+		{"name": "headlines", "countries": ["us", "gb"], "categories": ["business", "technology"], "page_size": 50},
+		{"name": "climate", "endpoint": "everything", "queries": ["climate", "energy"], "languages": ["en", "de"]}
+	]}`
+	yamlSpec := `
+jobs:
+  - name: headlines
+    countries: [us, gb]
+    categories: [business, technology]
+    page_size: 50
+  - name: climate
+    endpoint: everything
+    queries: [climate, energy]
+    languages: [en, de]
+`
+
+	tests := []struct {
+		name string
+		data string
+		ext  string
+	}{
+		{"json", jsonSpec, ".json"},
+		{"yaml", yamlSpec, ".yaml"},
+		{"yml", yamlSpec, ".yml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := ParseSpec([]byte(tt.data), tt.ext)
+			if err != nil {
+				t.Fatalf("ParseSpec() unexpected error: %v", err)
+			}
+
+			if len(spec.Jobs) != 2 {
+				t.Fatalf("Expected 2 jobs, got %d", len(spec.Jobs))
+			}
+			if spec.Jobs[0].PageSize != 50 {
+				t.Errorf("Expected page size 50, got %d", spec.Jobs[0].PageSize)
+			}
+			if spec.Jobs[1].Endpoint != newsapi.EndpointEverything {
+				t.Errorf("Expected everything endpoint, got '%s'", spec.Jobs[1].Endpoint)
+			}
+			if len(spec.Jobs[1].Languages) != 2 {
+				t.Errorf("Expected 2 languages, got %v", spec.Jobs[1].Languages)
+			}
+		})
+	}
+}
+
+func TestSpec_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    Spec
+		wantErr string
+	}{
+		{
+			name:    "no jobs",
+			spec:    Spec{},
+			wantErr: "at least one job",
+		},
+		{
+			name:    "missing name",
+			spec:    Spec{Jobs: []Job{{Countries: []string{"us"}}}},
+			wantErr: "name cannot be empty",
+		},
+		{
+			name: "duplicate name",
+			spec: Spec{Jobs: []Job{
+				{Name: "a", Countries: []string{"us"}},
+				{Name: "a", Countries: []string{"gb"}},
+			}},
+			wantErr: "duplicate name",
+		},
+		{
+			name:    "languages on top-headlines",
+			spec:    Spec{Jobs: []Job{{Name: "a", Countries: []string{"us"}, Languages: []string{"en"}}}},
+			wantErr: "languages only apply",
+		},
+		{
+			name:    "page size too large",
+			spec:    Spec{Jobs: []Job{{Name: "a", Countries: []string{"us"}, PageSize: 500}}},
+			wantErr: "page_size",
+		},
+		{
+			name:    "invalid request",
+			spec:    Spec{Jobs: []Job{{Name: "a", Countries: []string{"us"}, Sources: []string{"bbc-news"}}}},
+			wantErr: "job 'a'",
+		},
+		{
+			name: "valid",
+			spec: Spec{Jobs: []Job{
+				{Name: "a", Countries: []string{"us"}},
+				{Name: "b", Endpoint: newsapi.EndpointEverything, Queries: []string{"golang"}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want error containing '%s'", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJob_Requests(t *testing.T) {
+	job := Job{
+		Name:       "headlines",
+		Countries:  []string{"us", "gb"},
+		Categories: []string{"business", "technology", "sports"},
+		PageSize:   50,
+	}
+
+	requests := job.Requests("key", 20)
+	if len(requests) != 6 {
+		t.Fatalf("Expected 6 requests, got %d", len(requests))
+	}
+
+	seen := make(map[string]bool)
+	for _, req := range requests {
+		if req.APIKey != "key" {
+			t.Errorf("Expected API key 'key', got '%s'", req.APIKey)
+		}
+		if req.Endpoint != newsapi.EndpointTopHeadlines {
+			t.Errorf("Expected top-headlines endpoint, got '%s'", req.Endpoint)
+		}
+		if req.PageSize != 50 {
+			t.Errorf("Expected page size 50, got %d", req.PageSize)
+		}
+		seen[req.Country+"/"+req.Category] = true
+	}
+	if len(seen) != 6 {
+		t.Errorf("Expected 6 distinct country/category pairs, got %v", seen)
+	}
+
+	// Unset lists do not multiply requests, and the default page size applies
+	single := Job{Name: "q", Endpoint: newsapi.EndpointEverything, Queries: []string{"golang"}}
+	requests = single.Requests("key", 20)
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d", len(requests))
+	}
+	if requests[0].Query != "golang" || requests[0].PageSize != 20 || requests[0].Country != "" {
+		t.Errorf("Unexpected request: %+v", requests[0])
+	}
+}
+
+func TestLoadSpec(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "jobs.yaml")
+	if err := ioutil.WriteFile(path, []byte("jobs:\n  - name: us\n    countries: [us]\n"), 0644); err != nil {
+		t.Fatalf("Failed to write spec: %v", err)
+	}
+
+	spec, err := LoadSpec(path)
+	if err != nil {
+		t.Fatalf("LoadSpec() unexpected error: %v", err)
+	}
+	if len(spec.Jobs) != 1 || spec.Jobs[0].Name != "us" {
+		t.Errorf("Unexpected spec: %+v", spec)
+	}
+
+	if _, err := LoadSpec(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadSpec() expected error for missing file")
+	}
+	if _, err := LoadSpec(""); err == nil {
+		t.Error("LoadSpec() expected error for empty path")
+	}
+}
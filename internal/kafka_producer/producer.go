@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -16,6 +17,13 @@ type KafkaPublisher interface {
 	Close() error
 }
 
+// HeaderPublisher is a KafkaPublisher that can attach headers to a message, so
+// consumers learn how to read it without parsing the value
+type HeaderPublisher interface {
+	KafkaPublisher
+	PublishWithHeaders(ctx context.Context, broker, topic, message string, headers map[string]string) error
+}
+
 type Producer struct {
 	producer *kafka.Producer
 	mutex    sync.Mutex
@@ -73,6 +81,10 @@ func (p *Producer) Publish(broker, topic, message string) error {
 }
 
 func (p *Producer) PublishWithContext(ctx context.Context, broker, topic, message string) error {
+	return p.PublishWithHeaders(ctx, broker, topic, message, nil)
+}
+
+func (p *Producer) PublishWithHeaders(ctx context.Context, broker, topic, message string, headers map[string]string) error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
 
@@ -89,7 +101,8 @@ func (p *Producer) PublishWithContext(ctx context.Context, broker, topic, messag
 			Topic:     &topic,
 			Partition: kafka.PartitionAny,
 		},
-		Value: []byte(message),
+		Value:   []byte(message),
+		Headers: messageHeaders(headers),
 	}
 
 	deliveryChan := make(chan kafka.Event, 1)
@@ -113,6 +126,26 @@ func (p *Producer) PublishWithContext(ctx context.Context, broker, topic, messag
 	return nil
 }
 
+// messageHeaders converts headers to Kafka headers, sorted by key so that
+// messages are produced identically for the same input
+func messageHeaders(headers map[string]string) []kafka.Header {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]kafka.Header, len(keys))
+	for i, key := range keys {
+		result[i] = kafka.Header{Key: key, Value: []byte(headers[key])}
+	}
+	return result
+}
+
 func (p *Producer) Close() error {
 	p.mutex.Lock()
 	defer p.mutex.Unlock()
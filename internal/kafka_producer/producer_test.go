@@ -399,4 +399,15 @@ func TestMockKafkaPublisher(t *testing.T) {
 	if err != nil {
 		t.Errorf("Mock publish should not return error after reset: %v", err)
 	}
-}
\ No newline at end of file
+}
+
+func TestMessageHeaders(t *testing.T) {
+	if headers := messageHeaders(nil); headers != nil {
+		t.Errorf("Expected no headers, got %v", headers)
+	}
+
+	headers := messageHeaders(map[string]string{"format": "json", "compression": "gzip"})
+	if len(headers) != 2 || headers[0].Key != "compression" || string(headers[0].Value) != "gzip" || headers[1].Key != "format" {
+		t.Errorf("Expected headers sorted by key, got %v", headers)
+	}
+}
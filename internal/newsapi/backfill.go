@@ -0,0 +1,203 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	// defaultBackfillWindow is the initial size of each backfill window
+	defaultBackfillWindow = 24 * time.Hour
+	// defaultBackfillMinWindow is the smallest window a busy range is split into
+	defaultBackfillMinWindow = 1 * time.Hour
+)
+
+// BackfillOptions controls how a backfill splits its date range
+type BackfillOptions struct {
+	// Window is the initial window size; defaults to one day
+	Window time.Duration
+	// MinWindow is the size below which windows are no longer split, even if they
+	// still hold more results than can be paged through; defaults to one hour
+	MinWindow time.Duration
+}
+
+// BackfillWindow reports the outcome of downloading one window of a backfill
+type BackfillWindow struct {
+	From         time.Time       `json:"from"`
+	To           time.Time       `json:"to"`
+	TotalResults int             `json:"total_results"`
+	Result       *DownloadResult `json:"result,omitempty"`
+	Err          error           `json:"error,omitempty"`
+}
+
+// BackfillResult aggregates the windows of a backfill
+type BackfillResult struct {
+	Windows   []BackfillWindow `json:"windows"`
+	Aggregate *DownloadResult  `json:"aggregate"`
+}
+
+// Backfiller downloads a long date range as a series of smaller windows, so that no
+// single query returns more results than NewsAPI allows paging through
+type Backfiller struct {
+	downloader *NewsDownloader
+	options    BackfillOptions
+}
+
+// NewBackfiller creates a backfill driver on top of an existing downloader
+func NewBackfiller(downloader *NewsDownloader, options BackfillOptions) *Backfiller {
+	if options.Window <= 0 {
+		options.Window = defaultBackfillWindow
+	}
+	if options.MinWindow <= 0 {
+		options.MinWindow = defaultBackfillMinWindow
+	}
+	if options.MinWindow > options.Window {
+		options.MinWindow = options.Window
+	}
+
+	return &Backfiller{
+		downloader: downloader,
+		options:    options,
+	}
+}
+
+// Run downloads every article matching req published in [from, to).
+// The first page of each window is fetched first to learn its result count, and
+// windows holding more results than can be paged through are halved until they
+// fit or reach MinWindow. Windows that fit reuse that page rather than fetching it again.
+func (b *Backfiller) Run(ctx context.Context, req *DownloadRequest, from, to time.Time) (*BackfillResult, error) {
+	if req.EffectiveEndpoint() != EndpointEverything {
+		return nil, &ValidationError{Field: "endpoint", Message: "backfill requires the everything endpoint"}
+	}
+
+	if from.IsZero() || to.IsZero() || !from.Before(to) {
+		return nil, &ValidationError{Field: "from/to", Message: "backfill needs a non-empty range with from before to"}
+	}
+
+	// Validate once up front, so an invalid request costs no quota
+	rangeReq := *req
+	rangeReq.From = from
+	rangeReq.To = to
+	if err := b.downloader.provider.ValidateRequest(&rangeReq); err != nil {
+		return nil, fmt.Errorf("invalid download request: %w", err)
+	}
+
+	backfill := &BackfillResult{
+		Aggregate: &DownloadResult{
+			StartTime: time.Now(),
+			From:      from,
+			FilePaths: make([]string, 0),
+			Errors:    make([]error, 0),
+		},
+	}
+
+	// Pending windows, processed in chronological order
+	pending := splitRange(from, to, b.options.Window)
+
+	for len(pending) > 0 {
+		window := pending[0]
+		pending = pending[1:]
+
+		if err := ctx.Err(); err != nil {
+			return b.finish(backfill), fmt.Errorf("backfill cancelled: %w", err)
+		}
+
+		windowReq := *req
+		windowReq.From = window.From
+		windowReq.To = window.To
+		windowReq.StartPage = 1
+
+		// The first page tells how many results the window holds. Its attempts
+		// count here unless the window is downloaded, whose result counts them.
+		first := b.downloader.fetchPage(ctx, &windowReq, 1)
+		if err := first.err; err != nil {
+			backfill.Aggregate.TotalAttempts += first.attempts
+			window.Err = err
+			backfill.Windows = append(backfill.Windows, window)
+			backfill.Aggregate.Errors = append(backfill.Aggregate.Errors, fmt.Errorf("window %s: %w", formatWindow(window), err))
+			if isFatal(err) || errors.Is(err, ErrStopped) {
+				return b.finish(backfill), fmt.Errorf("backfill stopped at window %s: %w", formatWindow(window), err)
+			}
+			continue
+		}
+
+		total := first.resp.TotalResults
+		window.TotalResults = total
+		limit := b.downloader.config.MaxPageableResults
+		if limit > 0 && total > limit && window.To.Sub(window.From) > b.options.MinWindow {
+			mid := window.From.Add(window.To.Sub(window.From) / 2)
+			log.Printf("Window %s has %d results (limit %d), splitting", formatWindow(window), total, limit)
+			backfill.Aggregate.TotalAttempts += first.attempts
+			pending = append([]BackfillWindow{{From: window.From, To: mid}, {From: mid, To: window.To}}, pending...)
+			continue
+		}
+
+		if total == 0 {
+			log.Printf("Window %s has no results", formatWindow(window))
+			backfill.Aggregate.TotalAttempts += first.attempts
+			backfill.Windows = append(backfill.Windows, window)
+			continue
+		}
+
+		log.Printf("Backfilling window %s (%d results)", formatWindow(window), total)
+		result, err := b.downloader.downloadToFile(ctx, &windowReq, &first)
+		window.Result = result
+		window.Err = err
+		backfill.Windows = append(backfill.Windows, window)
+		backfill.Aggregate.Merge(result)
+
+		if err != nil {
+			// The page error that stopped the window was merged with its result already
+			if !result.ReportsError(err) {
+				backfill.Aggregate.Errors = append(backfill.Aggregate.Errors, fmt.Errorf("window %s: %w", formatWindow(window), err))
+			}
+			if ctx.Err() != nil || isFatal(err) || errors.Is(err, ErrStopped) {
+				return b.finish(backfill), fmt.Errorf("backfill stopped at window %s: %w", formatWindow(window), err)
+			}
+		}
+	}
+
+	return b.finish(backfill), nil
+}
+
+// finish stamps the end time of the aggregate result and the provider's key
+// usage, which includes the requests of windows that were split
+func (b *Backfiller) finish(backfill *BackfillResult) *BackfillResult {
+	b.downloader.reportKeyUsage(backfill.Aggregate, nil)
+	backfill.Aggregate.EndTime = time.Now()
+	backfill.Aggregate.Duration = backfill.Aggregate.EndTime.Sub(backfill.Aggregate.StartTime)
+	return backfill
+}
+
+// splitRange cuts [from, to) into consecutive windows of at most size
+func splitRange(from, to time.Time, size time.Duration) []BackfillWindow {
+	var windows []BackfillWindow
+	for start := from; start.Before(to); start = start.Add(size) {
+		end := start.Add(size)
+		if end.After(to) {
+			end = to
+		}
+		windows = append(windows, BackfillWindow{From: start, To: end})
+	}
+	return windows
+}
+
+// isFatal reports whether an error will repeat for every window, such as a bad
+// API key or a spent daily budget
+func isFatal(err error) bool {
+	var budgetErr *BudgetExhaustedError
+	if errors.As(err, &budgetErr) {
+		return true
+	}
+	var apiErr *NewsAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError
+}
+
+// formatWindow renders a window for log and error messages
+func formatWindow(window BackfillWindow) string {
+	return window.From.Format(time.RFC3339) + ".." + window.To.Format(time.RFC3339)
+}
@@ -0,0 +1,238 @@
+package newsapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// corpusHTTPClient answers everything queries from an in-memory corpus,
+// honouring from/to, page and pageSize like NewsAPI does
+type corpusHTTPClient struct {
+	mutex    sync.Mutex
+	articles []Article
+	requests []url.Values
+}
+
+func (c *corpusHTTPClient) Get(rawURL string) (*http.Response, error) {
+	return c.GetWithContext(context.Background(), rawURL)
+}
+
+func (c *corpusHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.GetWithContext(req.Context(), req.URL.String())
+}
+
+func (c *corpusHTTPClient) GetWithContext(ctx context.Context, rawURL string) (*http.Response, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	params := parsed.Query()
+
+	c.mutex.Lock()
+	c.requests = append(c.requests, params)
+	c.mutex.Unlock()
+
+	from, _ := time.Parse(time.RFC3339, params.Get("from"))
+	to, _ := time.Parse(time.RFC3339, params.Get("to"))
+	page, _ := strconv.Atoi(params.Get("page"))
+	pageSize, _ := strconv.Atoi(params.Get("pageSize"))
+
+	var matching []Article
+	for _, article := range c.articles {
+		if !article.PublishedAt.Before(from) && article.PublishedAt.Before(to) {
+			matching = append(matching, article)
+		}
+	}
+
+	start := (page - 1) * pageSize
+	end := start + pageSize
+	if start > len(matching) {
+		start = len(matching)
+	}
+	if end > len(matching) {
+		end = len(matching)
+	}
+
+	body, _ := json.Marshal(NewsAPIResponse{Status: "ok", TotalResults: len(matching), Articles: matching[start:end]})
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(string(body))),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func TestSplitRange(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)
+
+	windows := splitRange(from, to, 24*time.Hour)
+	if len(windows) != 3 {
+		t.Fatalf("Expected 3 windows, got %d", len(windows))
+	}
+
+	if !windows[2].From.Equal(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)) || !windows[2].To.Equal(to) {
+		t.Errorf("Expected last window to be clipped to the range end, got %s", formatWindow(windows[2]))
+	}
+}
+
+func TestBackfiller_SplitsBusyWindows(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	corpus := &corpusHTTPClient{}
+	for i := 0; i < 6; i++ {
+		// Three articles in each half of the first day, none on the second
+		corpus.articles = append(corpus.articles, Article{
+			Title:       fmt.Sprintf("Article %d", i),
+			URL:         fmt.Sprintf("https://example.com/%d", i),
+			PublishedAt: day.Add(time.Duration(1+i*4) * time.Hour),
+		})
+	}
+
+	downloader, publisher := newTestDownloader(t, corpus)
+	downloader.config.MaxPageableResults = 4
+
+	req := &DownloadRequest{
+		APIKey:    "test-key",
+		Endpoint:  EndpointEverything,
+		Query:     "test",
+		PageSize:  2,
+		StartPage: 1,
+	}
+
+	backfiller := NewBackfiller(downloader, BackfillOptions{Window: 24 * time.Hour, MinWindow: time.Hour})
+	backfill, err := backfiller.Run(context.Background(), req, day, day.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// The busy first day is split in two, the empty second day is skipped
+	if len(backfill.Windows) != 3 {
+		t.Fatalf("Expected 3 windows, got %d", len(backfill.Windows))
+	}
+
+	if backfill.Windows[0].To.Sub(backfill.Windows[0].From) != 12*time.Hour {
+		t.Errorf("Expected first window to be narrowed to 12h, got %s", formatWindow(backfill.Windows[0]))
+	}
+
+	if backfill.Windows[2].TotalResults != 0 || backfill.Windows[2].Result != nil {
+		t.Errorf("Expected empty second day not to be downloaded, got %+v", backfill.Windows[2])
+	}
+
+	aggregate := backfill.Aggregate
+	if aggregate.TotalArticles != 6 || aggregate.PagesDownloaded != 4 || aggregate.Truncated {
+		t.Errorf("Unexpected aggregate: articles=%d pages=%d truncated=%v", aggregate.TotalArticles, aggregate.PagesDownloaded, aggregate.Truncated)
+	}
+
+	if len(publisher.messages) != 4 {
+		t.Errorf("Expected 4 published pages, got %d", len(publisher.messages))
+	}
+
+	if !aggregate.MaxPublishedAt.Equal(corpus.articles[5].PublishedAt) {
+		t.Errorf("Expected newest article %v, got %v", corpus.articles[5].PublishedAt, aggregate.MaxPublishedAt)
+	}
+
+	// One probe per window, whose page is reused, plus the second page of each half
+	// day, and every one of them counts, the probe of the split day included
+	if len(corpus.requests) != 6 || aggregate.TotalAttempts != 6 {
+		t.Errorf("Expected 6 requests and attempts, got %d and %d", len(corpus.requests), aggregate.TotalAttempts)
+	}
+	for _, params := range corpus.requests {
+		if params.Get("pageSize") != "2" {
+			t.Errorf("Expected every request to use the real page size, got %s", params.Encode())
+		}
+	}
+}
+
+func TestBackfiller_CountsWindowErrorsOnce(t *testing.T) {
+	page := createMockNewsAPIResponse()
+	page.TotalResults = 4
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, page)},
+		{status: http.StatusUnauthorized, body: `{"status": "error", "code": "apiKeyExhausted", "message": "quota used up"}`},
+	}}
+	downloader, _ := newTestDownloader(t, httpClient)
+
+	req := &DownloadRequest{APIKey: "test-key", Endpoint: EndpointEverything, Query: "test", PageSize: 2, StartPage: 1}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	backfill, err := NewBackfiller(downloader, BackfillOptions{}).Run(context.Background(), req, from, from.Add(24*time.Hour))
+	if err == nil {
+		t.Fatal("Expected the exhausted key to stop the backfill")
+	}
+
+	if httpClient.calls() != 2 {
+		t.Errorf("Expected the probed page to be reused, got %d requests", httpClient.calls())
+	}
+	if len(backfill.Aggregate.Errors) != 1 {
+		t.Errorf("Expected the failure to be counted once, got %v", backfill.Aggregate.Errors)
+	}
+	if backfill.Aggregate.PagesDownloaded != 1 {
+		t.Errorf("Expected the probed page to be saved, got %d pages", backfill.Aggregate.PagesDownloaded)
+	}
+}
+
+func TestBackfiller_RequiresEverythingEndpoint(t *testing.T) {
+	downloader, _ := newTestDownloader(t, NewMockHTTPClient())
+	backfiller := NewBackfiller(downloader, BackfillOptions{})
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, err := backfiller.Run(context.Background(), NewDownloadRequest("test-key", "us"), from, from.Add(24*time.Hour))
+
+	validationErr, ok := err.(*ValidationError)
+	if !ok || validationErr.Field != "endpoint" {
+		t.Errorf("Expected endpoint validation error, got %v", err)
+	}
+}
+
+func TestBackfiller_ValidatesBeforeFetching(t *testing.T) {
+	corpus := &corpusHTTPClient{}
+	downloader, _ := newTestDownloader(t, corpus)
+
+	// An everything request needs a query, sources or domains
+	req := &DownloadRequest{APIKey: "test-key", Endpoint: EndpointEverything, PageSize: 2, StartPage: 1}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := NewBackfiller(downloader, BackfillOptions{Window: time.Hour}).Run(context.Background(), req, from, from.Add(24*time.Hour))
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	if len(corpus.requests) != 0 {
+		t.Errorf("Expected no requests for an invalid backfill, got %d", len(corpus.requests))
+	}
+}
+
+func TestNewsDownloader_TruncatesAtPageableLimit(t *testing.T) {
+	page := createMockNewsAPIResponse()
+	page.TotalResults = 500
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, page)},
+	}}
+
+	downloader, _ := newTestDownloader(t, httpClient)
+	downloader.config.MaxPageableResults = 2
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !result.Truncated || httpClient.calls() != 1 {
+		t.Errorf("Expected a truncated single-page download, got truncated=%v calls=%d", result.Truncated, httpClient.calls())
+	}
+}
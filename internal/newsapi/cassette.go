@@ -0,0 +1,294 @@
+package newsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cassette is a recording of HTTP interactions that can be replayed in tests
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and the response it received
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the redacted form of a recorded request
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// RecordedResponse is the redacted form of a recorded response
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(filePath string) (*Cassette, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, &FileOperationError{Operation: "read file", FilePath: filePath, Cause: err}
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, &FileOperationError{Operation: "unmarshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	return &cassette, nil
+}
+
+// Save writes the cassette to filePath, replacing any previous version atomically
+func (c *Cassette) Save(filePath string) error {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &FileOperationError{Operation: "create directory", FilePath: dir, Cause: err}
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return &FileOperationError{Operation: "marshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return &FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err}
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return &FileOperationError{Operation: "rename file", FilePath: filePath, Cause: err}
+	}
+
+	return nil
+}
+
+// RecordingHTTPClient passes requests through to another HTTPClient and records
+// every exchange into a cassette file. API keys are redacted from the recorded
+// URL, headers and body. The cassette is saved after each interaction.
+type RecordingHTTPClient struct {
+	inner    HTTPClient
+	path     string
+	cassette Cassette
+	mutex    sync.Mutex
+}
+
+// NewRecordingHTTPClient records the traffic of inner into the cassette at path
+func NewRecordingHTTPClient(inner HTTPClient, path string) *RecordingHTTPClient {
+	return &RecordingHTTPClient{inner: inner, path: path}
+}
+
+// Get implements the HTTPClient interface.
+func (r *RecordingHTTPClient) Get(url string) (*http.Response, error) {
+	return r.GetWithContext(context.Background(), url)
+}
+
+// GetWithContext implements the HTTPClient interface.
+func (r *RecordingHTTPClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.Do(req)
+}
+
+// Do sends a prepared request. Transport errors are passed on
+// without being recorded.
+func (r *RecordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	resp, err := doRequest(r.inner, req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			URL:    Redact(req.URL.String()),
+			Header: redactHeader(req.Header),
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     redactHeader(resp.Header),
+			Body:       Redact(string(body)),
+		},
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.Save(r.path); err != nil {
+		return nil, fmt.Errorf("failed to record interaction: %w", err)
+	}
+
+	return resp, nil
+}
+
+// ReplayingHTTPClient answers requests from a cassette without touching the
+// network. A request matches an interaction with the same method, path and query
+// parameters; the scheme and host are ignored so a cassette recorded against
+// NewsAPI can be replayed at any base URL, and parameter order does not matter.
+// Interactions are served in recorded order, and once every match has been
+// served the last one is repeated.
+type ReplayingHTTPClient struct {
+	cassette     *Cassette
+	served       []bool
+	ignoreParams map[string]bool
+	mutex        sync.Mutex
+}
+
+// NewReplayingHTTPClient replays cassette. Query parameters named in
+// ignoreParams, such as "from" and "to" that change from run to run, are left
+// out of matching. Parameters that carry the API key are always ignored.
+func NewReplayingHTTPClient(cassette *Cassette, ignoreParams ...string) *ReplayingHTTPClient {
+	ignore := map[string]bool{"apiKey": true, "api_key": true}
+	for _, param := range ignoreParams {
+		ignore[param] = true
+	}
+
+	return &ReplayingHTTPClient{
+		cassette:     cassette,
+		served:       make([]bool, len(cassette.Interactions)),
+		ignoreParams: ignore,
+	}
+}
+
+// Get implements the HTTPClient interface.
+func (r *ReplayingHTTPClient) Get(url string) (*http.Response, error) {
+	return r.GetWithContext(context.Background(), url)
+}
+
+// GetWithContext implements the HTTPClient interface.
+func (r *ReplayingHTTPClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return r.Do(req)
+}
+
+// Do sends a prepared request. A request that matches no recorded
+// interaction is an error.
+func (r *ReplayingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	last := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.matches(interaction.Request, req) {
+			continue
+		}
+		if !r.served[i] {
+			r.served[i] = true
+			return interaction.Response.response(req), nil
+		}
+		last = i
+	}
+
+	if last >= 0 {
+		return r.cassette.Interactions[last].Response.response(req), nil
+	}
+
+	return nil, fmt.Errorf("no recorded interaction matches %s %s", req.Method, Redact(req.URL.String()))
+}
+
+// Unserved returns how many recorded interactions have not been replayed yet
+func (r *ReplayingHTTPClient) Unserved() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	count := 0
+	for _, served := range r.served {
+		if !served {
+			count++
+		}
+	}
+	return count
+}
+
+// matches reports whether req is the recorded request
+func (r *ReplayingHTTPClient) matches(recorded RecordedRequest, req *http.Request) bool {
+	if !strings.EqualFold(recorded.Method, req.Method) {
+		return false
+	}
+
+	recordedURL, err := url.Parse(recorded.URL)
+	if err != nil || recordedURL.Path != req.URL.Path {
+		return false
+	}
+
+	want := r.significantParams(recordedURL.Query())
+	got := r.significantParams(req.URL.Query())
+	if len(want) != len(got) {
+		return false
+	}
+	for name, value := range want {
+		if got[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// significantParams flattens the query parameters that take part in matching
+func (r *ReplayingHTTPClient) significantParams(params url.Values) map[string]string {
+	significant := make(map[string]string)
+	for name, values := range params {
+		if !r.ignoreParams[name] {
+			significant[name] = strings.Join(values, ",")
+		}
+	}
+	return significant
+}
+
+// response rebuilds the recorded response for req
+func (rr RecordedResponse) response(req *http.Request) *http.Response {
+	header := rr.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(rr.StatusCode),
+		StatusCode:    rr.StatusCode,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(rr.Body)),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
+
+// redactHeader copies header with credentials replaced by RedactedValue
+func redactHeader(header http.Header) http.Header {
+	redacted := make(http.Header, len(header))
+	for name, values := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case http.CanonicalHeaderKey(APIKeyHeader), "Authorization":
+			redacted[name] = []string{RedactedValue}
+		default:
+			redacted[name] = append([]string(nil), values...)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,172 @@
+package newsapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newPagingServer serves total articles from /v2/top-headlines in NewsAPI's format
+func newPagingServer(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(APIKeyHeader) != secretTestKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"status":"error","code":"apiKeyInvalid","message":"bad key"}`)
+			return
+		}
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		pageSize, _ := strconv.Atoi(r.URL.Query().Get("pageSize"))
+		resp := NewsAPIResponse{Status: "ok", TotalResults: total}
+		for i := (page - 1) * pageSize; i < page*pageSize && i < total; i++ {
+			resp.Articles = append(resp.Articles, Article{
+				Title:       fmt.Sprintf("Recorded article %d", i),
+				URL:         fmt.Sprintf("https://example.com/recorded/%d", i),
+				PublishedAt: time.Date(2024, 1, 15, 9, i, 0, 0, time.UTC),
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCassette_RecordAndReplayDownload(t *testing.T) {
+	server := newPagingServer(t, 5)
+	cassettePath := filepath.Join(t.TempDir(), "cassettes", "top_headlines.json")
+
+	req := NewDownloadRequest(secretTestKey, "us")
+	req.PageSize = 2
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	// Record real traffic
+	recorder := NewRecordingHTTPClient(&defaultHTTPClient{client: server.Client()}, cassettePath)
+	recordingDownloader, _ := newTestDownloader(t, recorder)
+	recordingDownloader.provider.(*NewsAPIClient).baseURL = server.URL + "/v2"
+
+	recorded, err := recordingDownloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Recording download failed: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(cassettePath)
+	if err != nil {
+		t.Fatalf("Failed to read cassette: %v", err)
+	}
+	if strings.Contains(string(data), secretTestKey) {
+		t.Errorf("Cassette contains the API key: %s", data)
+	}
+
+	cassette, err := LoadCassette(cassettePath)
+	if err != nil {
+		t.Fatalf("LoadCassette() unexpected error: %v", err)
+	}
+	if len(cassette.Interactions) != 3 {
+		t.Fatalf("Expected 3 recorded interactions, got %d", len(cassette.Interactions))
+	}
+
+	// Replay it against the default NewsAPI base URL, without a server
+	server.Close()
+	replayer := NewReplayingHTTPClient(cassette, "from", "to")
+	replayingDownloader, publisher := newTestDownloader(t, replayer)
+
+	replayed, err := replayingDownloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Replayed download failed: %v", err)
+	}
+
+	if replayed.TotalArticles != recorded.TotalArticles || replayed.PagesDownloaded != 3 || len(publisher.messages) != 3 {
+		t.Errorf("Expected the replay to match the recording, got %d articles in %d pages", replayed.TotalArticles, replayed.PagesDownloaded)
+	}
+	if !replayed.MaxPublishedAt.Equal(recorded.MaxPublishedAt) {
+		t.Errorf("Expected newest article %v, got %v", recorded.MaxPublishedAt, replayed.MaxPublishedAt)
+	}
+	if replayer.Unserved() != 0 {
+		t.Errorf("Expected every interaction to be replayed, %d left", replayer.Unserved())
+	}
+}
+
+func TestReplayingHTTPClient_Matching(t *testing.T) {
+	cassette := &Cassette{Interactions: []Interaction{
+		{
+			Request:  RecordedRequest{Method: "GET", URL: "https://newsapi.org/v2/everything?q=go&page=1&from=2024-01-01T00:00:00Z&apiKey=***"},
+			Response: RecordedResponse{StatusCode: http.StatusOK, Body: "first"},
+		},
+		{
+			Request:  RecordedRequest{Method: "GET", URL: "https://newsapi.org/v2/everything?q=go&page=1&from=2024-01-01T00:00:00Z"},
+			Response: RecordedResponse{StatusCode: http.StatusOK, Body: "second"},
+		},
+	}}
+	replayer := NewReplayingHTTPClient(cassette, "from")
+
+	read := func(rawURL string) (string, error) {
+		resp, err := replayer.Get(rawURL)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return string(body), nil
+	}
+
+	// Host, parameter order and ignored parameters do not matter; matches are served in order
+	for _, want := range []string{"first", "second", "second"} {
+		got, err := read("http://127.0.0.1:8080/v2/everything?page=1&from=2030-05-05T00:00:00Z&q=go")
+		if err != nil || got != want {
+			t.Errorf("Expected %q, got %q, %v", want, got, err)
+		}
+	}
+
+	for _, rawURL := range []string{
+		"https://newsapi.org/v2/everything?q=go&page=2",
+		"https://newsapi.org/v2/top-headlines?q=go&page=1",
+		"https://newsapi.org/v2/everything?q=go&page=1&language=en",
+	} {
+		if _, err := read(rawURL); err == nil {
+			t.Errorf("Expected no match for %s", rawURL)
+		}
+	}
+}
+
+func TestRecordingHTTPClient_RedactsHeaders(t *testing.T) {
+	inner := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: `{"status":"ok","echo":"` + secretTestKey + `"}`},
+	}}
+	cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+	recorder := NewRecordingHTTPClient(inner, cassettePath)
+
+	RegisterSecret(secretTestKey)
+	httpReq, _ := http.NewRequest(http.MethodGet, "https://newsapi.org/v2/everything?q=go", nil)
+	httpReq.Header.Set(APIKeyHeader, secretTestKey)
+
+	resp, err := recorder.Do(httpReq)
+	if err != nil {
+		t.Fatalf("Do() unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), secretTestKey) {
+		t.Errorf("Expected the caller to get the unredacted body, got %s", body)
+	}
+
+	cassette, err := LoadCassette(cassettePath)
+	if err != nil {
+		t.Fatalf("LoadCassette() unexpected error: %v", err)
+	}
+	recorded := cassette.Interactions[0]
+	if recorded.Request.Header.Get(APIKeyHeader) != RedactedValue {
+		t.Errorf("Expected the key header to be redacted, got %q", recorded.Request.Header.Get(APIKeyHeader))
+	}
+	if strings.Contains(recorded.Response.Body, secretTestKey) {
+		t.Errorf("Expected the recorded body to be redacted, got %s", recorded.Response.Body)
+	}
+}
@@ -0,0 +1,140 @@
+package newsapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpointDir is the subdirectory of OutputDir holding download checkpoints
+const checkpointDir = ".checkpoints"
+
+// Checkpoint records the progress of a download so an interrupted run can be resumed.
+// FailedPages lists pages up to LastCompletedPage that were skipped after a fetch or
+// save error; resuming retries them first. From and Watermark are the window start
+// and watermark the run resolved for a request without From, which a resumed run
+// keeps so that it pages through the same results.
+type Checkpoint struct {
+	Fingerprint       string    `json:"fingerprint"`
+	From              time.Time `json:"from,omitempty"`
+	Watermark         time.Time `json:"watermark,omitempty"`
+	LastCompletedPage int       `json:"last_completed_page"`
+	FailedPages       []int     `json:"failed_pages,omitempty"`
+	TotalResults      int       `json:"total_results"`
+	FilePaths         []string  `json:"file_paths"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// Fingerprint returns a stable identifier for the query a request describes.
+// The API key is excluded so that the same query run with another key resumes
+// the same checkpoint. Downloads fingerprint the request as the caller gave it,
+// before a missing From is resolved, so that a resume finds the checkpoint
+// however the watermark or the date has moved since.
+func (r *DownloadRequest) Fingerprint() string {
+	identity := struct {
+		Endpoint       Endpoint  `json:"endpoint"`
+		Query          string    `json:"query"`
+		SearchIn       []string  `json:"search_in"`
+		Country        string    `json:"country"`
+		Category       string    `json:"category"`
+		Sources        []string  `json:"sources"`
+		Domains        []string  `json:"domains"`
+		ExcludeDomains []string  `json:"exclude_domains"`
+		From           time.Time `json:"from"`
+		To             time.Time `json:"to"`
+		Language       string    `json:"language"`
+		SortBy         string    `json:"sort_by"`
+		PageSize       int       `json:"page_size"`
+		StartPage      int       `json:"start_page"`
+	}{
+		Endpoint:       r.EffectiveEndpoint(),
+		Query:          r.Query,
+		SearchIn:       r.SearchIn,
+		Country:        r.Country,
+		Category:       r.Category,
+		Sources:        r.Sources,
+		Domains:        r.Domains,
+		ExcludeDomains: r.ExcludeDomains,
+		From:           r.From.UTC(),
+		To:             r.To.UTC(),
+		Language:       r.Language,
+		SortBy:         r.SortBy,
+		PageSize:       r.PageSize,
+		StartPage:      r.StartPage,
+	}
+
+	return hashIdentity(identity)
+}
+
+// hashIdentity returns a short stable hash of the JSON encoding of identity
+func hashIdentity(identity interface{}) string {
+	// Marshalling a struct of plain values cannot fail
+	data, _ := json.Marshal(identity)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// CheckpointPath returns where the checkpoint for a request fingerprint is stored
+func CheckpointPath(baseOutputDir, fingerprint string) string {
+	return filepath.Join(baseOutputDir, checkpointDir, fingerprint+".json")
+}
+
+// LoadCheckpoint reads a checkpoint file. It returns nil without an error when no checkpoint exists.
+func LoadCheckpoint(filePath string) (*Checkpoint, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, &FileOperationError{Operation: "read file", FilePath: filePath, Cause: err}
+	}
+
+	var checkpoint Checkpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, &FileOperationError{Operation: "unmarshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	return &checkpoint, nil
+}
+
+// SaveCheckpoint writes a checkpoint file, replacing any previous version atomically
+// so that a crash mid-write never leaves a truncated checkpoint behind
+func SaveCheckpoint(filePath string, checkpoint *Checkpoint) error {
+	if checkpoint == nil {
+		return fmt.Errorf("checkpoint cannot be nil")
+	}
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &FileOperationError{Operation: "create directory", FilePath: dir, Cause: err}
+	}
+
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return &FileOperationError{Operation: "marshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return &FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err}
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return &FileOperationError{Operation: "rename file", FilePath: filePath, Cause: err}
+	}
+
+	return nil
+}
+
+// RemoveCheckpoint deletes a checkpoint file; a missing file is not an error
+func RemoveCheckpoint(filePath string) error {
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return &FileOperationError{Operation: "remove file", FilePath: filePath, Cause: err}
+	}
+	return nil
+}
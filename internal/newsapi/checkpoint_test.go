@@ -0,0 +1,73 @@
+package newsapi
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDownloadRequest_Fingerprint(t *testing.T) {
+	req := NewDownloadRequest("key-one", "us")
+	req.Query = "ai"
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	sameQueryOtherKey := *req
+	sameQueryOtherKey.APIKey = "key-two"
+
+	otherQuery := *req
+	otherQuery.Query = "climate"
+
+	if req.Fingerprint() != sameQueryOtherKey.Fingerprint() {
+		t.Error("Expected fingerprint to ignore the API key")
+	}
+
+	if req.Fingerprint() == otherQuery.Fingerprint() {
+		t.Error("Expected different queries to have different fingerprints")
+	}
+
+	if len(req.Fingerprint()) != 16 {
+		t.Errorf("Expected 16 character fingerprint, got '%s'", req.Fingerprint())
+	}
+}
+
+func TestCheckpointRoundTrip(t *testing.T) {
+	path := CheckpointPath(t.TempDir(), "abc123")
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil || loaded != nil {
+		t.Fatalf("Expected no checkpoint before saving, got %+v, %v", loaded, err)
+	}
+
+	checkpoint := &Checkpoint{
+		Fingerprint:       "abc123",
+		LastCompletedPage: 3,
+		TotalResults:      120,
+		FilePaths:         []string{"/tmp/a.json", "/tmp/b.json", "/tmp/c.json"},
+		UpdatedAt:         time.Now(),
+	}
+
+	if err := SaveCheckpoint(path, checkpoint); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	loaded, err = LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("Failed to load checkpoint: %v", err)
+	}
+
+	if loaded.LastCompletedPage != 3 || loaded.TotalResults != 120 || len(loaded.FilePaths) != 3 {
+		t.Errorf("Unexpected checkpoint: %+v", loaded)
+	}
+
+	if err := RemoveCheckpoint(path); err != nil {
+		t.Fatalf("Failed to remove checkpoint: %v", err)
+	}
+
+	if err := RemoveCheckpoint(path); err != nil {
+		t.Errorf("Removing a missing checkpoint should not fail: %v", err)
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*")); len(matches) != 0 {
+		t.Errorf("Expected checkpoint directory to be empty, found %v", matches)
+	}
+}
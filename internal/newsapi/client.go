@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -22,6 +23,28 @@ type HTTPClient interface {
 	GetWithContext(ctx context.Context, url string) (*http.Response, error)
 }
 
+// requestDoer is implemented by HTTPClients that can send a prepared request,
+// which the client needs to pass the API key in the X-Api-Key header
+type requestDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// doRequest sends req through client. A client that cannot send headers gets
+// the API key in the query string instead; Redact keeps it out of errors and logs.
+func doRequest(client HTTPClient, req *http.Request) (*http.Response, error) {
+	if doer, ok := client.(requestDoer); ok {
+		return doer.Do(req)
+	}
+
+	fullURL := *req.URL
+	if apiKey := req.Header.Get(APIKeyHeader); apiKey != "" {
+		query := fullURL.Query()
+		query.Set("apiKey", apiKey)
+		fullURL.RawQuery = query.Encode()
+	}
+	return client.GetWithContext(req.Context(), fullURL.String())
+}
+
 // defaultHTTPClient is a wrapper around the standard *http.Client
 // that implements our HTTPClient interface by adding a GetWithContext method.
 type defaultHTTPClient struct {
@@ -44,147 +67,251 @@ func (c *defaultHTTPClient) GetWithContext(ctx context.Context, url string) (*ht
 	return c.client.Do(req)
 }
 
-// RateLimiter manages API rate limiting.
-type RateLimiter struct {
-	remaining int
-	resetTime time.Time
-	limit     int
-	mutex     sync.RWMutex
+// Do sends a prepared request.
+func (c *defaultHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return c.client.Do(req)
 }
 
-// NewRateLimiter creates a new rate limiter.
-func NewRateLimiter() *RateLimiter {
-	return &RateLimiter{
-		remaining: 1000, // Default conservative value
-		resetTime: time.Now().Add(time.Hour),
-		limit:     1000,
-	}
+// NewsAPIClient wraps HTTP client with NewsAPI-specific functionality.
+type NewsAPIClient struct {
+	httpClient    HTTPClient
+	limiters      map[string]*RateLimiter
+	limitersMutex sync.Mutex
+	lastKey       string
+	keys          *KeyPool
+	config        *config.Config
+	baseURL       string
+	timeout       time.Duration
 }
 
-// UpdateFromHeaders updates the rate limiter from HTTP response headers.
-func (r *RateLimiter) UpdateFromHeaders(headers http.Header) {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-
-	if limitStr := headers.Get("X-RateLimit-Limit"); limitStr != "" {
-		if limit, err := strconv.Atoi(limitStr); err == nil {
-			r.limit = limit
-		}
+// NewNewsAPIClient creates a new NewsAPI client.
+// This function now uses the defaultHTTPClient wrapper to satisfy the HTTPClient interface.
+func NewNewsAPIClient(cfg *config.Config) *NewsAPIClient {
+	// Create a standard HTTP client.
+	client := &http.Client{
+		Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
 	}
+	// Wrap it to make it conform to our HTTPClient interface.
+	var httpClient HTTPClient = &defaultHTTPClient{client: client}
 
-	if remainingStr := headers.Get("X-RateLimit-Remaining"); remainingStr != "" {
-		if remaining, err := strconv.Atoi(remainingStr); err == nil {
-			r.remaining = remaining
-		}
+	// Replay responses from a local cache if one is configured.
+	if cfg.HTTPCacheDir != "" {
+		log.Printf("Caching API responses in %s", cfg.HTTPCacheDir)
+		cache := NewCachingHTTPClient(httpClient, cfg.HTTPCacheDir, time.Duration(cfg.HTTPCacheTTLSeconds)*time.Second)
+		cache.SetMaxResponseBytes(int64(cfg.MaxResponseBytes))
+		httpClient = cache
 	}
 
-	if resetStr := headers.Get("X-RateLimit-Reset"); resetStr != "" {
-		if resetUnix, err := strconv.ParseInt(resetStr, 10, 64); err == nil {
-			r.resetTime = time.Unix(resetUnix, 0)
-		}
+	return NewNewsAPIClientWithHTTPClient(cfg, httpClient)
+}
+
+// NewNewsAPIClientWithHTTPClient creates a client with a custom HTTP client (useful for testing).
+// If the config lists API keys, requests made with any of them rotate through a KeyPool.
+func NewNewsAPIClientWithHTTPClient(cfg *config.Config, httpClient HTTPClient) *NewsAPIClient {
+	client := &NewsAPIClient{
+		httpClient: httpClient,
+		limiters:   make(map[string]*RateLimiter),
+		config:     cfg,
+		baseURL:    cfg.BaseURL,
+		timeout:    time.Duration(cfg.TimeoutSeconds) * time.Second,
+	}
+	if len(cfg.APIKeys) > 0 {
+		client.keys = newKeyPool(client.limiterFor, cfg.APIKeys...)
 	}
+	return client
 }
 
-// WaitIfNeeded waits if we're close to hitting rate limits.
-func (r *RateLimiter) WaitIfNeeded(ctx context.Context) error {
-	r.mutex.RLock()
-	remaining := r.remaining
-	resetTime := r.resetTime
-	r.mutex.RUnlock()
+// limiterFor returns the rate limiter for apiKey, creating it from the config's
+// request budget on first use. Every request made with the key through this
+// client, including those of concurrent jobs sharing a downloader, draws from it.
+func (c *NewsAPIClient) limiterFor(apiKey string) *RateLimiter {
+	c.limitersMutex.Lock()
+	defer c.limitersMutex.Unlock()
 
-	// If we have plenty of requests remaining, don't wait.
-	if remaining > 10 {
-		return nil
+	c.lastKey = apiKey
+	if limiter, ok := c.limiters[apiKey]; ok {
+		return limiter
 	}
 
-	// If we're low on requests, wait until reset.
-	if remaining <= 5 && time.Now().Before(resetTime) {
-		waitDuration := time.Until(resetTime) + time.Second
-		select {
-		case <-time.After(waitDuration):
-			return nil
-		case <-ctx.Done():
-			return ctx.Err()
-		}
+	options := RateLimitOptions{
+		RequestsPerSecond: c.config.RequestsPerSecond,
+		RequestsPerDay:    c.config.RequestsPerDay,
+	}
+	if options.RequestsPerDay > 0 {
+		options.StatePath = RateLimitStatePath(c.config.OutputDir, apiKey)
+	}
+
+	limiter, err := NewBudgetRateLimiter(options)
+	if err != nil {
+		log.Printf("Failed to restore rate limit state, starting a new daily budget: %v", err)
+		limiter, _ = NewBudgetRateLimiter(RateLimitOptions{
+			RequestsPerSecond: options.RequestsPerSecond,
+			RequestsPerDay:    options.RequestsPerDay,
+		})
+		limiter.options.StatePath = options.StatePath
 	}
 
-	return nil
+	c.limiters[apiKey] = limiter
+	return limiter
 }
 
-// GetStatus returns the current rate limit status.
-func (r *RateLimiter) GetStatus() (remaining, limit int, resetTime time.Time) {
-	r.mutex.RLock()
-	defer r.mutex.RUnlock()
-	return r.remaining, r.limit, r.resetTime
+// SetKeyPool sets the keys to rotate through. A nil pool sends every request
+// with the key it was made with.
+func (c *NewsAPIClient) SetKeyPool(pool *KeyPool) {
+	c.keys = pool
 }
 
-// NewsAPIClient wraps HTTP client with NewsAPI-specific functionality.
-type NewsAPIClient struct {
-	httpClient  HTTPClient
-	rateLimiter *RateLimiter
-	config      *config.Config
-	baseURL     string
-	timeout     time.Duration
+// KeyUsage implements KeyUsageReporter. It returns nil without a key pool.
+func (c *NewsAPIClient) KeyUsage() []KeyUsage {
+	if c.keys == nil {
+		return nil
+	}
+	return c.keys.Usage()
 }
 
-// NewNewsAPIClient creates a new NewsAPI client.
-// This function now uses the defaultHTTPClient wrapper to satisfy the HTTPClient interface.
-func NewNewsAPIClient(cfg *config.Config) *NewsAPIClient {
-	// Create a standard HTTP client.
-	client := &http.Client{
-		Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second,
+// FetchNewsPage fetches a single page of news from the API.
+func (c *NewsAPIClient) FetchNewsPage(ctx context.Context, req *DownloadRequest, page int) (*NewsAPIResponse, *NewsAPILimits, error) {
+	// Build the URL.
+	fullURL, err := c.buildURL(req, page)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build URL: %w", err)
 	}
-	// Wrap it to make it conform to our HTTPClient interface.
-	httpClient := &defaultHTTPClient{client: client}
 
-	return &NewsAPIClient{
-		httpClient:  httpClient,
-		rateLimiter: NewRateLimiter(),
-		config:      cfg,
-		baseURL:     cfg.BaseURL,
-		timeout:     time.Duration(cfg.TimeoutSeconds) * time.Second,
+	body, limits, err := c.doRequest(ctx, fullURL, req.APIKey)
+	if err != nil {
+		return nil, limits, err
 	}
+	defer body.Close()
+
+	// Parse the response as it arrives.
+	newsResp, err := decodeNewsAPIResponse(body)
+	if err != nil {
+		return nil, limits, body.decodeError(err)
+	}
+
+	// Check for API-level errors.
+	if newsResp.IsError() {
+		apiErr := newsResp.ToError(http.StatusOK)
+		apiErr.URL = Redact(fullURL)
+		return nil, limits, apiErr
+	}
+
+	return newsResp, limits, nil
 }
 
-// NewNewsAPIClientWithHTTPClient creates a client with a custom HTTP client (useful for testing).
-func NewNewsAPIClientWithHTTPClient(cfg *config.Config, httpClient HTTPClient) *NewsAPIClient {
-	return &NewsAPIClient{
-		httpClient:  httpClient,
-		rateLimiter: NewRateLimiter(),
-		config:      cfg,
-		baseURL:     cfg.BaseURL,
-		timeout:     time.Duration(cfg.TimeoutSeconds) * time.Second,
+// FetchSources fetches the publishers NewsAPI indexes from /v2/top-headlines/sources.
+func (c *NewsAPIClient) FetchSources(ctx context.Context, req *SourcesRequest) ([]SourceInfo, *NewsAPILimits, error) {
+	if err := req.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid sources request: %w", err)
+	}
+
+	params := url.Values{}
+	if req.Category != "" {
+		params.Add("category", req.Category)
+	}
+	if req.Language != "" {
+		params.Add("language", req.Language)
+	}
+	if req.Country != "" {
+		params.Add("country", req.Country)
+	}
+	fullURL := c.endpointURL(string(EndpointTopHeadlines)+"/sources") + "?" + params.Encode()
+
+	body, limits, err := c.doRequest(ctx, fullURL, req.APIKey)
+	if err != nil {
+		return nil, limits, err
 	}
+	defer body.Close()
+
+	var sourcesResp SourcesResponse
+	if err := json.NewDecoder(body).Decode(&sourcesResp); err != nil {
+		return nil, limits, body.decodeError(err)
+	}
+
+	if sourcesResp.IsError() {
+		apiErr := sourcesResp.ToError(http.StatusOK)
+		apiErr.URL = Redact(fullURL)
+		return nil, limits, apiErr
+	}
+
+	return sourcesResp.Sources, limits, nil
 }
 
-// FetchNewsPage fetches a single page of news from the API.
-func (c *NewsAPIClient) FetchNewsPage(ctx context.Context, req *DownloadRequest, page int) (*NewsAPIResponse, *NewsAPILimits, error) {
-	// Wait for rate limiting if needed.
-	if err := c.rateLimiter.WaitIfNeeded(ctx); err != nil {
-		return nil, nil, fmt.Errorf("rate limit wait cancelled: %w", err)
+// doRequest performs a rate-limited GET and returns the body of a successful response,
+// which the caller must close. Requests made with a key from the client's pool move on to the pool's next key
+// when the current one runs out of quota, and fail once every key is exhausted.
+func (c *NewsAPIClient) doRequest(ctx context.Context, fullURL, apiKey string) (*responseBody, *NewsAPILimits, error) {
+	if c.keys == nil || !c.keys.Contains(apiKey) {
+		return c.send(ctx, fullURL, apiKey, c.limiterFor(apiKey))
 	}
 
-	// Build the URL.
-	fullURL, err := c.buildURL(req, page)
+	for {
+		key, err := c.keys.acquire()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		c.keys.record(key)
+		body, limits, err := c.send(ctx, fullURL, key.key, key.limiter)
+		if err == nil || c.keys.Len() == 1 {
+			return body, limits, err
+		}
+
+		var reset time.Time
+		if limits != nil {
+			reset = limits.Reset
+		}
+		until, exhausted := keyExhaustedUntil(err, reset, time.Duration(c.config.DefaultRateLimitDelaySeconds)*time.Second)
+		if !exhausted {
+			return body, limits, err
+		}
+
+		log.Printf("API %s is out of quota until %s, rotating to the next key", key.label, until.Format(time.RFC3339))
+		c.keys.markExhausted(key, until)
+	}
+}
+
+// send performs a single GET with apiKey, throttled by limiter.
+// The API key is sent in the X-Api-Key header, never in the URL.
+// Rate limiting and non-200 responses are converted to RateLimitError and NewsAPIError.
+// The body of a successful response is returned unread, capped at Config.MaxResponseBytes.
+func (c *NewsAPIClient) send(ctx context.Context, fullURL, apiKey string, limiter *RateLimiter) (*responseBody, *NewsAPILimits, error) {
+	// Make sure the key is masked wherever it might still surface.
+	RegisterSecret(apiKey)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, fullURL, nil)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to build URL: %w", err)
+		return nil, nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	httpReq.Header.Set(APIKeyHeader, apiKey)
+
+	// Wait for rate limiting if needed; a spent daily budget is reported as is.
+	// Responses replayed from a cache cost no quota, so they skip the wait.
+	if cache, ok := c.httpClient.(*CachingHTTPClient); !ok || !cache.Cached(httpReq) {
+		if err := limiter.WaitIfNeeded(ctx); err != nil {
+			if _, ok := err.(*BudgetExhaustedError); ok {
+				return nil, nil, err
+			}
+			return nil, nil, fmt.Errorf("rate limit wait cancelled: %w", err)
+		}
 	}
 
 	// Make the HTTP request.
-	resp, err := c.httpClient.GetWithContext(ctx, fullURL)
+	resp, err := doRequest(c.httpClient, httpReq)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to make HTTP request: %w", err)
 	}
-	defer resp.Body.Close()
+	body := newResponseBody(resp.Body, Redact(fullURL), int64(c.config.MaxResponseBytes))
 
 	// Update rate limiter from response headers.
-	c.rateLimiter.UpdateFromHeaders(resp.Header)
+	limiter.UpdateFromHeaders(resp.Header)
 
 	// Get current rate limits for return.
 	limits := c.extractRateLimits(resp.Header)
 
 	// Handle rate limiting.
 	if resp.StatusCode == http.StatusTooManyRequests {
+		body.Close()
 		retryAfter := time.Duration(c.config.DefaultRateLimitDelaySeconds) * time.Second
 		if time.Now().Before(limits.Reset) {
 			retryAfter = time.Until(limits.Reset) + time.Second
@@ -198,67 +325,96 @@ func (c *NewsAPIClient) FetchNewsPage(ctx context.Context, req *DownloadRequest,
 		}
 	}
 
-	// Read response body.
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, &limits, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	// Handle non-OK status codes.
+	// Handle non-OK status codes; error bodies are small, so read them whole.
 	if resp.StatusCode != http.StatusOK {
-		return c.handleErrorResponse(resp.StatusCode, body, fullURL)
+		defer body.Close()
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, &limits, fmt.Errorf("failed to read response body: %w", err)
+		}
+		return nil, &limits, c.handleErrorResponse(resp.StatusCode, data, Redact(fullURL))
 	}
 
-	// Parse the response.
-	var newsResp NewsAPIResponse
-	if err := json.Unmarshal(body, &newsResp); err != nil {
-		return nil, &limits, fmt.Errorf("failed to unmarshal JSON response: %w", err)
+	// Refuse a body that announces it is over the limit before reading any of it.
+	if body.limit > 0 && resp.ContentLength > body.limit {
+		body.Close()
+		return nil, &limits, &ResponseTooLargeError{Limit: body.limit, URL: body.url}
 	}
 
-	// Check for API-level errors.
-	if newsResp.IsError() {
-		apiErr := newsResp.ToError(resp.StatusCode)
-		apiErr.URL = fullURL
-		return nil, &limits, apiErr
-	}
-
-	return &newsResp, &limits, nil
+	return body, &limits, nil
 }
 
 // buildURL constructs the full URL for the API request.
+// Only the parameters supported by the request's endpoint are included.
 func (c *NewsAPIClient) buildURL(req *DownloadRequest, page int) (string, error) {
 	params := url.Values{}
+	endpoint := req.EffectiveEndpoint()
 
-	if req.Query != "" {
-		params.Add("q", req.Query)
-	}
-
-	if req.Country != "" {
-		params.Add("country", req.Country)
-	}
-
-	if req.Language != "" {
-		params.Add("language", req.Language)
-	}
-
-	if req.SortBy != "" {
-		params.Add("sortBy", req.SortBy)
+	switch endpoint {
+	case EndpointTopHeadlines:
+		if req.Country != "" {
+			params.Add("country", req.Country)
+		}
+		if req.Category != "" {
+			params.Add("category", req.Category)
+		}
+		if sources := joinList(req.Sources); sources != "" {
+			params.Add("sources", sources)
+		}
+		if req.Query != "" {
+			params.Add("q", req.Query)
+		}
+	case EndpointEverything:
+		if req.Query != "" {
+			params.Add("q", req.Query)
+		}
+		if searchIn := joinList(req.SearchIn); searchIn != "" {
+			params.Add("searchIn", searchIn)
+		}
+		if sources := joinList(req.Sources); sources != "" {
+			params.Add("sources", sources)
+		}
+		if domains := joinList(req.Domains); domains != "" {
+			params.Add("domains", domains)
+		}
+		if excludeDomains := joinList(req.ExcludeDomains); excludeDomains != "" {
+			params.Add("excludeDomains", excludeDomains)
+		}
+		if !req.From.IsZero() {
+			params.Add("from", req.From.UTC().Format("2006-01-02T15:04:05Z"))
+		}
+		if !req.To.IsZero() {
+			params.Add("to", req.To.UTC().Format("2006-01-02T15:04:05Z"))
+		}
+		if req.Language != "" {
+			params.Add("language", req.Language)
+		}
+		if req.SortBy != "" {
+			params.Add("sortBy", req.SortBy)
+		}
+	default:
+		return "", fmt.Errorf("unsupported endpoint '%s'", endpoint)
 	}
 
 	params.Add("pageSize", strconv.Itoa(req.PageSize))
 	params.Add("page", strconv.Itoa(page))
-	params.Add("apiKey", req.APIKey)
 
-	if !req.From.IsZero() {
-		params.Add("from", req.From.Format("2006-01-02T15:04:05Z"))
-	}
+	fullURL := c.endpointURL(string(endpoint)) + "?" + params.Encode()
+	return fullURL, nil
+}
 
-	if !req.To.IsZero() {
-		params.Add("to", req.To.Format("2006-01-02T15:04:05Z"))
+// endpointURL resolves an endpoint path against the configured base URL.
+// The base URL may point either at the API root (".../v2") or at one of the
+// search endpoints (".../v2/top-headlines"), as the default config does.
+func (c *NewsAPIClient) endpointURL(path string) string {
+	root := strings.TrimSuffix(c.baseURL, "/")
+	for _, endpoint := range []Endpoint{EndpointTopHeadlines, EndpointEverything} {
+		if strings.HasSuffix(root, "/"+string(endpoint)) {
+			root = strings.TrimSuffix(root, "/"+string(endpoint))
+			break
+		}
 	}
-
-	fullURL := c.baseURL + "?" + params.Encode()
-	return fullURL, nil
+	return root + "/" + path
 }
 
 // extractRateLimits extracts rate limit information from response headers.
@@ -287,13 +443,13 @@ func (c *NewsAPIClient) extractRateLimits(headers http.Header) NewsAPILimits {
 }
 
 // handleErrorResponse handles non-200 HTTP responses.
-func (c *NewsAPIClient) handleErrorResponse(statusCode int, body []byte, url string) (*NewsAPIResponse, *NewsAPILimits, error) {
+func (c *NewsAPIClient) handleErrorResponse(statusCode int, body []byte, url string) error {
 	var apiErrorResp NewsAPIResponse
 	if err := json.Unmarshal(body, &apiErrorResp); err != nil {
 		// If we can't parse the error response, return a generic error.
-		return nil, nil, &NewsAPIError{
+		return &NewsAPIError{
 			StatusCode: statusCode,
-			Message:    fmt.Sprintf("HTTP %d: %s", statusCode, string(body)),
+			Message:    Redact(fmt.Sprintf("HTTP %d: %s", statusCode, string(body))),
 			URL:        url,
 		}
 	}
@@ -302,28 +458,40 @@ func (c *NewsAPIClient) handleErrorResponse(statusCode int, body []byte, url str
 	apiErr := apiErrorResp.ToError(statusCode)
 	if apiErr != nil {
 		apiErr.URL = url
-		return nil, nil, apiErr
+		return apiErr
 	}
 
 	// Fallback error.
-	return nil, nil, &NewsAPIError{
+	return &NewsAPIError{
 		StatusCode: statusCode,
 		Message:    fmt.Sprintf("HTTP %d", statusCode),
 		URL:        url,
 	}
 }
 
-// GetRateLimitStatus returns the current rate limit status.
+// GetRateLimitStatus returns the current rate limit status, of the active key
+// when the client has a key pool, or zero values before any request was made.
 func (c *NewsAPIClient) GetRateLimitStatus() (remaining, limit int, resetTime time.Time) {
-	return c.rateLimiter.GetStatus()
+	if c.keys != nil && c.keys.Len() > 0 {
+		return c.keys.active().limiter.GetStatus()
+	}
+
+	c.limitersMutex.Lock()
+	limiter, ok := c.limiters[c.lastKey]
+	c.limitersMutex.Unlock()
+	if !ok {
+		return 0, 0, time.Time{}
+	}
+	return limiter.GetStatus()
 }
 
 // MockHTTPClient implements HTTPClient for testing.
 type MockHTTPClient struct {
-	responses map[string]*http.Response
-	errors    map[string]error
-	callCount map[string]int
-	mutex     sync.RWMutex
+	responses  map[string]*http.Response
+	errors     map[string]error
+	callCount  map[string]int
+	lastHeader http.Header
+	mutex      sync.RWMutex
 }
 
 // NewMockHTTPClient creates a new mock HTTP client.
@@ -354,6 +522,14 @@ func (m *MockHTTPClient) Get(url string) (*http.Response, error) {
 	return m.GetWithContext(context.Background(), url)
 }
 
+// Do sends a prepared request, recording its headers.
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.mutex.Lock()
+	m.lastHeader = req.Header.Clone()
+	m.mutex.Unlock()
+	return m.GetWithContext(req.Context(), req.URL.String())
+}
+
 // GetWithContext implements HTTPClient.GetWithContext.
 func (m *MockHTTPClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
 	m.mutex.Lock()
@@ -391,6 +567,13 @@ func (m *MockHTTPClient) GetCallCount(url string) int {
 	return m.callCount[url]
 }
 
+// LastHeader returns the headers of the most recent request sent through Do.
+func (m *MockHTTPClient) LastHeader() http.Header {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.lastHeader
+}
+
 // Reset clears all mock data.
 func (m *MockHTTPClient) Reset() {
 	m.mutex.Lock()
@@ -398,4 +581,5 @@ func (m *MockHTTPClient) Reset() {
 	m.responses = make(map[string]*http.Response)
 	m.errors = make(map[string]error)
 	m.callCount = make(map[string]int)
+	m.lastHeader = nil
 }
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 	"time"
@@ -233,3 +235,214 @@ func TestFetchNewsPage_HTTPError(t *testing.T) {
 		t.Errorf("Expected error message '%s', but got '%s'", expectedErrorMsg, err.Error())
 	}
 }
+
+// TestBuildURL_TopHeadlines tests that only top-headlines parameters are sent.
+func TestBuildURL_TopHeadlines(t *testing.T) {
+	cfg := config.DefaultConfig()
+	client := NewNewsAPIClientWithHTTPClient(cfg, NewMockHTTPClient())
+
+	req := NewDownloadRequest("test-key", "us")
+	req.Category = "technology"
+	req.Query = "ai"
+	req.Language = "en"
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	fullURL, err := client.buildURL(req, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	parsed, err := url.Parse(fullURL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL '%s': %v", fullURL, err)
+	}
+
+	if parsed.Path != "/v2/top-headlines" {
+		t.Errorf("Expected path '/v2/top-headlines', got '%s'", parsed.Path)
+	}
+
+	params := parsed.Query()
+	expected := map[string]string{
+		"country":  "us",
+		"category": "technology",
+		"q":        "ai",
+		"page":     "2",
+		"pageSize": "20",
+	}
+	for key, value := range expected {
+		if params.Get(key) != value {
+			t.Errorf("Expected param %s='%s', got '%s'", key, value, params.Get(key))
+		}
+	}
+
+	for _, key := range []string{"from", "to", "language", "sortBy", "domains"} {
+		if params.Has(key) {
+			t.Errorf("Expected param '%s' to be omitted for top-headlines", key)
+		}
+	}
+}
+
+// TestBuildURL_Everything tests that everything parameters are sent to /v2/everything.
+func TestBuildURL_Everything(t *testing.T) {
+	cfg := config.DefaultConfig()
+	client := NewNewsAPIClientWithHTTPClient(cfg, NewMockHTTPClient())
+
+	req := &DownloadRequest{
+		APIKey:         "test-key",
+		Endpoint:       EndpointEverything,
+		Query:          "bitcoin",
+		SearchIn:       []string{"title", "description"},
+		Domains:        []string{"bbc.co.uk", " techcrunch.com "},
+		ExcludeDomains: []string{"example.com"},
+		From:           time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		To:             time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC),
+		Language:       "en",
+		SortBy:         "popularity",
+		PageSize:       50,
+		StartPage:      1,
+	}
+
+	fullURL, err := client.buildURL(req, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	parsed, err := url.Parse(fullURL)
+	if err != nil {
+		t.Fatalf("Failed to parse URL '%s': %v", fullURL, err)
+	}
+
+	if parsed.Path != "/v2/everything" {
+		t.Errorf("Expected path '/v2/everything', got '%s'", parsed.Path)
+	}
+
+	params := parsed.Query()
+	expected := map[string]string{
+		"q":              "bitcoin",
+		"searchIn":       "title,description",
+		"domains":        "bbc.co.uk,techcrunch.com",
+		"excludeDomains": "example.com",
+		"from":           "2024-01-15T00:00:00Z",
+		"to":             "2024-01-16T00:00:00Z",
+		"language":       "en",
+		"sortBy":         "popularity",
+		"pageSize":       "50",
+	}
+	for key, value := range expected {
+		if params.Get(key) != value {
+			t.Errorf("Expected param %s='%s', got '%s'", key, value, params.Get(key))
+		}
+	}
+
+	if params.Has("country") {
+		t.Error("Expected param 'country' to be omitted for everything")
+	}
+}
+
+// TestEndpointURL tests resolving endpoints against different base URL shapes.
+func TestEndpointURL(t *testing.T) {
+	tests := []struct {
+		baseURL  string
+		path     string
+		expected string
+	}{
+		{"https://newsapi.org/v2/top-headlines", "everything", "https://newsapi.org/v2/everything"},
+		{"https://newsapi.org/v2/everything", "top-headlines", "https://newsapi.org/v2/top-headlines"},
+		{"https://newsapi.org/v2", "everything", "https://newsapi.org/v2/everything"},
+		{"http://localhost:8080/v2/", "top-headlines", "http://localhost:8080/v2/top-headlines"},
+	}
+
+	for _, tt := range tests {
+		cfg := config.DefaultConfig()
+		cfg.BaseURL = tt.baseURL
+		client := NewNewsAPIClientWithHTTPClient(cfg, NewMockHTTPClient())
+
+		if got := client.endpointURL(tt.path); got != tt.expected {
+			t.Errorf("endpointURL(%q) with base %q = %q, expected %q", tt.path, tt.baseURL, got, tt.expected)
+		}
+	}
+}
+
+// TestFetchSources_Success tests listing sources from the sources endpoint.
+func TestFetchSources_Success(t *testing.T) {
+	mockClient := NewMockHTTPClient()
+	responseBody := `{"status": "ok", "sources": [
+		{"id": "bbc-news", "name": "BBC News", "description": "Use BBC News for up-to-the-minute news.", "url": "https://www.bbc.co.uk/news", "category": "general", "language": "en", "country": "gb"},
+		{"id": "wired", "name": "Wired", "description": "Wired is a monthly American magazine.", "url": "https://www.wired.com", "category": "technology", "language": "en", "country": "us"}
+	]}`
+
+	expectedURL := "https://newsapi.org/v2/top-headlines/sources?language=en"
+	mockClient.SetResponse(expectedURL, &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(responseBody)),
+		Header:     http.Header{"X-Ratelimit-Remaining": []string{"42"}},
+	})
+
+	cfg := config.DefaultConfig()
+	client := NewNewsAPIClientWithHTTPClient(cfg, mockClient)
+
+	sources, limits, err := client.FetchSources(context.Background(), &SourcesRequest{APIKey: "test-key", Language: "en"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got: %v", err)
+	}
+
+	if mockClient.GetCallCount(expectedURL) != 1 {
+		t.Errorf("Expected one call to '%s'", expectedURL)
+	}
+
+	if len(sources) != 2 {
+		t.Fatalf("Expected 2 sources, but got %d", len(sources))
+	}
+
+	if sources[1].ID != "wired" || sources[1].Category != "technology" || sources[1].Country != "us" {
+		t.Errorf("Unexpected second source: %+v", sources[1])
+	}
+
+	if limits.Remaining != 42 {
+		t.Errorf("Expected remaining calls to be 42, but got %d", limits.Remaining)
+	}
+}
+
+// TestFetchSources_APIError tests that sources errors use NewsAPIError.
+func TestFetchSources_APIError(t *testing.T) {
+	mockClient := NewMockHTTPClient()
+	errorBody := `{"status": "error", "code": "apiKeyInvalid", "message": "Your API key is invalid or incorrect."}`
+	mockClient.SetResponse("*", &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Body:       ioutil.NopCloser(strings.NewReader(errorBody)),
+	})
+
+	cfg := config.DefaultConfig()
+	client := NewNewsAPIClientWithHTTPClient(cfg, mockClient)
+
+	_, _, err := client.FetchSources(context.Background(), &SourcesRequest{APIKey: "bad-key"})
+	if err == nil {
+		t.Fatal("Expected an error, but got nil")
+	}
+
+	apiErr, ok := err.(*NewsAPIError)
+	if !ok {
+		t.Fatalf("Expected error of type *NewsAPIError, but got %T", err)
+	}
+
+	if apiErr.Code != "apiKeyInvalid" {
+		t.Errorf("Expected code 'apiKeyInvalid', but got '%s'", apiErr.Code)
+	}
+}
+
+// TestFetchSources_InvalidRequest tests that filters are validated before any request is made.
+func TestFetchSources_InvalidRequest(t *testing.T) {
+	mockClient := NewMockHTTPClient()
+	cfg := config.DefaultConfig()
+	client := NewNewsAPIClientWithHTTPClient(cfg, mockClient)
+
+	_, _, err := client.FetchSources(context.Background(), &SourcesRequest{APIKey: "test-key", Category: "weather"})
+	if err == nil {
+		t.Fatal("Expected a validation error, but got nil")
+	}
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "category" {
+		t.Errorf("Expected category validation error, but got: %v", err)
+	}
+}
@@ -0,0 +1,359 @@
+package newsapi
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// dedupDir is the subdirectory of OutputDir holding the seen-article store
+const dedupDir = ".dedup"
+
+// trackingParams are query parameters that do not change which article a URL points to
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"cmpid":   true,
+	"ref":     true,
+	"ref_src": true,
+	"smid":    true,
+	"ocid":    true,
+	"ito":     true,
+}
+
+// DedupStore remembers which articles have already been processed
+type DedupStore interface {
+	// Seen reports whether key has been recorded before
+	Seen(key string) bool
+	// Add records keys as seen
+	Add(keys ...string) error
+	// Close releases any resources held by the store
+	Close() error
+}
+
+// CanonicalizeURL normalises an article URL so that trivially different links to the
+// same article compare equal: the scheme, "www." prefix, fragment, trailing slash and
+// tracking parameters are dropped and the remaining query parameters are sorted.
+func CanonicalizeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(raw)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	path := strings.TrimSuffix(parsed.EscapedPath(), "/")
+
+	query := parsed.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		lower := strings.ToLower(key)
+		if trackingParams[lower] || strings.HasPrefix(lower, "utm_") {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	canonical := host + path
+	if len(keys) > 0 {
+		params := make([]string, 0, len(keys))
+		for _, key := range keys {
+			values := query[key]
+			sort.Strings(values)
+			for _, value := range values {
+				params = append(params, url.QueryEscape(key)+"="+url.QueryEscape(value))
+			}
+		}
+		canonical += "?" + strings.Join(params, "&")
+	}
+
+	return canonical
+}
+
+// normalizeText lowercases s, drops punctuation and collapses whitespace
+func normalizeText(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// ArticleKey returns the deduplication key of an article: its canonical URL, or its
+// normalised title and source when it has no URL. Articles with neither return "".
+func ArticleKey(article Article) string {
+	if canonical := CanonicalizeURL(article.URL); canonical != "" {
+		return "url:" + canonical
+	}
+
+	title := normalizeText(article.Title)
+	if title == "" {
+		return ""
+	}
+
+	source := article.Source.ID
+	if source == "" {
+		source = article.Source.Name
+	}
+	return "title:" + title + "|" + normalizeText(source)
+}
+
+// MemoryDedupStore is a DedupStore that only lives for the lifetime of the process
+type MemoryDedupStore struct {
+	seen  map[string]bool
+	mutex sync.RWMutex
+}
+
+// NewMemoryDedupStore creates an empty in-memory dedup store
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{seen: make(map[string]bool)}
+}
+
+// Seen implements DedupStore.Seen
+func (s *MemoryDedupStore) Seen(key string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.seen[key]
+}
+
+// Add implements DedupStore.Add
+func (s *MemoryDedupStore) Add(keys ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, key := range keys {
+		s.seen[key] = true
+	}
+	return nil
+}
+
+// Close implements DedupStore.Close
+func (s *MemoryDedupStore) Close() error {
+	return nil
+}
+
+// dedupRecord is one line of the file store
+type dedupRecord struct {
+	Hash     string `json:"h"`
+	SeenUnix int64  `json:"t"`
+}
+
+// FileDedupStore is a DedupStore persisted as an append-only JSON lines file.
+// Keys are stored as hashes, and entries older than the retention period are
+// dropped when the store is opened.
+type FileDedupStore struct {
+	filePath string
+	file     *os.File
+	seen     map[string]int64
+	mutex    sync.RWMutex
+}
+
+// DedupStorePath returns the location of the file dedup store under baseOutputDir
+func DedupStorePath(baseOutputDir string) string {
+	return filepath.Join(baseOutputDir, dedupDir, "seen.jsonl")
+}
+
+// NewFileDedupStore opens (or creates) the dedup store at filePath, forgetting
+// articles seen longer ago than retention. A zero retention keeps everything.
+func NewFileDedupStore(filePath string, retention time.Duration) (*FileDedupStore, error) {
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, &FileOperationError{Operation: "create directory", FilePath: dir, Cause: err}
+	}
+
+	store := &FileDedupStore{
+		filePath: filePath,
+		seen:     make(map[string]int64),
+	}
+
+	pruned, err := store.load(retention)
+	if err != nil {
+		return nil, err
+	}
+
+	if pruned {
+		if err := store.compact(); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, &FileOperationError{Operation: "open file", FilePath: filePath, Cause: err}
+	}
+	store.file = file
+
+	return store, nil
+}
+
+// load reads the existing records, reporting whether any expired ones were skipped
+func (s *FileDedupStore) load(retention time.Duration) (bool, error) {
+	file, err := os.Open(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, &FileOperationError{Operation: "open file", FilePath: s.filePath, Cause: err}
+	}
+	defer file.Close()
+
+	cutoff := int64(0)
+	if retention > 0 {
+		cutoff = time.Now().Add(-retention).Unix()
+	}
+
+	pruned := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record dedupRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Hash == "" {
+			// Skip lines left half-written by a crash
+			pruned = true
+			continue
+		}
+		if record.SeenUnix < cutoff {
+			pruned = true
+			continue
+		}
+		s.seen[record.Hash] = record.SeenUnix
+	}
+
+	if err := scanner.Err(); err != nil {
+		return false, &FileOperationError{Operation: "read file", FilePath: s.filePath, Cause: err}
+	}
+
+	return pruned, nil
+}
+
+// compact rewrites the store file with only the retained records
+func (s *FileDedupStore) compact() error {
+	tmpPath := s.filePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return &FileOperationError{Operation: "create file", FilePath: tmpPath, Cause: err}
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for hash, seenUnix := range s.seen {
+		if err := encoder.Encode(dedupRecord{Hash: hash, SeenUnix: seenUnix}); err != nil {
+			file.Close()
+			return &FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err}
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return &FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err}
+	}
+	if err := file.Close(); err != nil {
+		return &FileOperationError{Operation: "close file", FilePath: tmpPath, Cause: err}
+	}
+
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		return &FileOperationError{Operation: "rename file", FilePath: s.filePath, Cause: err}
+	}
+	return nil
+}
+
+// hashKey shortens a dedup key to a fixed-size hash for storage
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Seen implements DedupStore.Seen
+func (s *FileDedupStore) Seen(key string) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	_, ok := s.seen[hashKey(key)]
+	return ok
+}
+
+// Add implements DedupStore.Add, appending new keys to the store file
+func (s *FileDedupStore) Add(keys ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return fmt.Errorf("dedup store is closed")
+	}
+
+	now := time.Now().Unix()
+	var buf strings.Builder
+	encoder := json.NewEncoder(&buf)
+	added := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		hash := hashKey(key)
+		if _, ok := s.seen[hash]; ok || added[hash] {
+			continue
+		}
+		if err := encoder.Encode(dedupRecord{Hash: hash, SeenUnix: now}); err != nil {
+			return err
+		}
+		added[hash] = true
+	}
+
+	if len(added) == 0 {
+		return nil
+	}
+
+	if _, err := s.file.WriteString(buf.String()); err != nil {
+		return &FileOperationError{Operation: "write file", FilePath: s.filePath, Cause: err}
+	}
+
+	for hash := range added {
+		s.seen[hash] = now
+	}
+	return nil
+}
+
+// Len returns the number of articles currently remembered
+func (s *FileDedupStore) Len() int {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return len(s.seen)
+}
+
+// Close implements DedupStore.Close
+func (s *FileDedupStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
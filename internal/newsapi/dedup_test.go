@@ -0,0 +1,130 @@
+package newsapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected bool
+	}{
+		{"scheme and www", "http://www.example.com/story", "https://example.com/story", true},
+		{"trailing slash and fragment", "https://example.com/story/#comments", "https://example.com/story", true},
+		{"tracking params", "https://example.com/story?utm_source=rss&id=7&fbclid=abc", "https://example.com/story?id=7", true},
+		{"param order", "https://example.com/story?b=2&a=1", "https://example.com/story?a=1&b=2", true},
+		{"host case", "https://EXAMPLE.com/story", "https://example.com/story", true},
+		{"different path", "https://example.com/story-1", "https://example.com/story-2", false},
+		{"different content param", "https://example.com/story?id=1", "https://example.com/story?id=2", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := CanonicalizeURL(tt.a), CanonicalizeURL(tt.b)
+			if (a == b) != tt.expected {
+				t.Errorf("CanonicalizeURL(%q) = %q, CanonicalizeURL(%q) = %q, expected equal=%v", tt.a, a, tt.b, b, tt.expected)
+			}
+		})
+	}
+
+	if CanonicalizeURL("  ") != "" {
+		t.Error("Expected empty canonical URL for blank input")
+	}
+}
+
+func TestArticleKey(t *testing.T) {
+	withURL := Article{URL: "https://www.example.com/a?utm_medium=x", Title: "Ignored"}
+	if key := ArticleKey(withURL); key != "url:example.com/a" {
+		t.Errorf("Expected URL based key, got '%s'", key)
+	}
+
+	first := Article{Title: "Markets Rally, Again!", Source: Source{Name: "Wire"}}
+	second := Article{Title: "  markets rally again ", Source: Source{Name: "wire"}}
+	if ArticleKey(first) == "" || ArticleKey(first) != ArticleKey(second) {
+		t.Errorf("Expected matching title keys, got '%s' and '%s'", ArticleKey(first), ArticleKey(second))
+	}
+
+	otherSource := Article{Title: "Markets Rally, Again!", Source: Source{Name: "Other"}}
+	if ArticleKey(first) == ArticleKey(otherSource) {
+		t.Error("Expected the source to be part of the title key")
+	}
+
+	if key := ArticleKey(Article{}); key != "" {
+		t.Errorf("Expected empty key for an empty article, got '%s'", key)
+	}
+}
+
+func TestFileDedupStore_Persistence(t *testing.T) {
+	path := DedupStorePath(t.TempDir())
+
+	store, err := NewFileDedupStore(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open dedup store: %v", err)
+	}
+
+	if err := store.Add("url:example.com/a", "url:example.com/b", "url:example.com/a"); err != nil {
+		t.Fatalf("Failed to add keys: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Failed to close dedup store: %v", err)
+	}
+
+	reopened, err := NewFileDedupStore(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to reopen dedup store: %v", err)
+	}
+	defer reopened.Close()
+
+	if !reopened.Seen("url:example.com/a") || !reopened.Seen("url:example.com/b") {
+		t.Error("Expected keys to survive reopening the store")
+	}
+
+	if reopened.Seen("url:example.com/c") {
+		t.Error("Expected unknown key to be unseen")
+	}
+
+	if reopened.Len() != 2 {
+		t.Errorf("Expected 2 stored keys, got %d", reopened.Len())
+	}
+}
+
+func TestFileDedupStore_Retention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.jsonl")
+
+	old, _ := json.Marshal(dedupRecord{Hash: hashKey("url:old"), SeenUnix: time.Now().Add(-48 * time.Hour).Unix()})
+	recent, _ := json.Marshal(dedupRecord{Hash: hashKey("url:recent"), SeenUnix: time.Now().Unix()})
+	content := string(old) + "\n" + string(recent) + "\n" + `{"h": "trunc`
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write store file: %v", err)
+	}
+
+	store, err := NewFileDedupStore(path, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open dedup store: %v", err)
+	}
+	defer store.Close()
+
+	if store.Seen("url:old") {
+		t.Error("Expected expired key to be forgotten")
+	}
+
+	if !store.Seen("url:recent") {
+		t.Error("Expected recent key to be kept")
+	}
+
+	// Expired and corrupt lines are compacted away
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read store file: %v", err)
+	}
+	var record dedupRecord
+	if err := json.Unmarshal(data, &record); err != nil || record.Hash != hashKey("url:recent") {
+		t.Errorf("Expected only the recent record after compaction, got %q", string(data))
+	}
+}
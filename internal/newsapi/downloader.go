@@ -2,106 +2,412 @@ package newsapi
 
 import (
 	"context"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"log"
-	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"go-news-agg/internal/config"
 	"go-news-agg/internal/kafka_producer"
+	"go-news-agg/internal/storage"
 	"go-news-agg/pkg/utils"
 )
 
-// NewsDownloader handles downloading news articles from NewsAPI
+// NewsDownloader handles downloading news articles from a Provider
 type NewsDownloader struct {
-	client    *NewsAPIClient
-	publisher kafka_producer.KafkaPublisher
-	config    *config.Config
+	provider    Provider
+	publisher   kafka_producer.KafkaPublisher
+	config      *config.Config
+	retryPolicy *RetryPolicy
+	dedup       DedupStore
+	pageWriter  PageWriter
+	compression utils.Compression
+	storage     storage.Storage
+	paths       *utils.FilePathGenerator
 }
 
 // NewNewsDownloader creates a new news downloader with the given dependencies
-func NewNewsDownloader(client *NewsAPIClient, publisher kafka_producer.KafkaPublisher, cfg *config.Config) *NewsDownloader {
+func NewNewsDownloader(provider Provider, publisher kafka_producer.KafkaPublisher, cfg *config.Config) *NewsDownloader {
 	return &NewsDownloader{
-		client:    client,
-		publisher: publisher,
-		config:    cfg,
+		provider:    provider,
+		publisher:   publisher,
+		config:      cfg,
+		retryPolicy: NewRetryPolicy(cfg.MaxRetries),
+		pageWriter:  pageWriterFor(cfg),
+		compression: compressionFor(cfg),
+		storage:     storage.NewLocal(cfg.OutputDir),
+		paths:       pathGeneratorFor(cfg),
 	}
 }
 
+// pageWriterFor returns the writer for the configured output format, falling back
+// to JSON for a format the config should not have let through
+func pageWriterFor(cfg *config.Config) PageWriter {
+	writer, err := NewPageWriter(cfg.OutputFormat)
+	if err != nil {
+		log.Printf("%v, saving pages as JSON", err)
+		return jsonPageWriter{}
+	}
+	return writer
+}
+
+// compressionFor returns the configured codec for saved pages, falling back to
+// none for a codec the config should not have let through
+func compressionFor(cfg *config.Config) utils.Compression {
+	compression, err := utils.ParseCompression(cfg.OutputCompression)
+	if err != nil {
+		log.Printf("%v, saving pages uncompressed", err)
+	}
+	return compression
+}
+
+// pathGeneratorFor returns a generator for the configured path template, falling
+// back to the default layout for a template the config should not have let through
+func pathGeneratorFor(cfg *config.Config) *utils.FilePathGenerator {
+	template, err := utils.ParsePathTemplate(cfg.OutputPathTemplate, cfg.OutputPathHive)
+	if err != nil {
+		log.Printf("%v, using the default path layout", err)
+	}
+	return utils.NewTemplateFilePathGenerator(nil, template)
+}
+
 // NewNewsDownloaderWithDefaults creates a news downloader with default dependencies
 func NewNewsDownloaderWithDefaults(cfg *config.Config) (*NewsDownloader, error) {
-	client := NewNewsAPIClient(cfg)
-	
+	return NewNewsDownloaderWithProvider(cfg, NewNewsAPIClient(cfg))
+}
+
+// NewNewsDownloaderWithProvider creates a news downloader for provider with the
+// default Kafka producer, dedup store and the configured storage
+func NewNewsDownloaderWithProvider(cfg *config.Config, provider Provider) (*NewsDownloader, error) {
+	pageStorage, err := storage.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage: %w", err)
+	}
+
 	producer, err := kafka_producer.NewProducer(cfg.KafkaBroker)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
 	}
 
+	var dedup DedupStore
+	if cfg.DedupEnabled {
+		retention := time.Duration(cfg.DedupRetentionDays) * 24 * time.Hour
+		store, err := NewFileDedupStore(DedupStorePath(cfg.OutputDir), retention)
+		if err != nil {
+			producer.Close()
+			return nil, fmt.Errorf("failed to open dedup store: %w", err)
+		}
+		dedup = store
+	}
+
 	return &NewsDownloader{
-		client:    client,
-		publisher: producer,
-		config:    cfg,
+		provider:    provider,
+		publisher:   producer,
+		config:      cfg,
+		retryPolicy: NewRetryPolicy(cfg.MaxRetries),
+		dedup:       dedup,
+		pageWriter:  pageWriterFor(cfg),
+		compression: compressionFor(cfg),
+		storage:     pageStorage,
+		paths:       pathGeneratorFor(cfg),
 	}, nil
 }
 
+// SetDedupStore sets the store used to skip articles that were already downloaded.
+// A nil store disables deduplication.
+func (d *NewsDownloader) SetDedupStore(store DedupStore) {
+	d.dedup = store
+}
+
+// SetStorage sets where pages are saved; NewNewsDownloader saves them under OutputDir
+func (d *NewsDownloader) SetStorage(pageStorage storage.Storage) {
+	d.storage = pageStorage
+}
+
 // DownloadAllNewsToFile fetches and saves news articles, and publishes their paths to Kafka
 func (d *NewsDownloader) DownloadAllNewsToFile(ctx context.Context, req *DownloadRequest) (*DownloadResult, error) {
-	startTime := time.Now()
-	
+	return d.downloadToFile(ctx, req, nil)
+}
+
+// downloadToFile is DownloadAllNewsToFile for a caller that may already have fetched
+// the request's first page, which is then saved instead of being fetched again
+func (d *NewsDownloader) downloadToFile(ctx context.Context, req *DownloadRequest, first *pageFetch) (*DownloadResult, error) {
+	fingerprint := req.Fingerprint()
+
+	// Default the window start to the query's watermark
+	req, watermark, err := d.resolveFrom(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// Validate the request
-	if err := req.Validate(); err != nil {
+	if err := d.provider.ValidateRequest(req); err != nil {
 		return nil, fmt.Errorf("invalid download request: %w", err)
 	}
 
+	return d.reportKeyUsage(d.download(ctx, req, fingerprint, watermark, nil, first))
+}
+
+// ResumeDownload continues an interrupted download of the same request from the page
+// after the last one recorded in its checkpoint. Pages and files from the earlier run
+// are merged into the returned result. Without a checkpoint it behaves like
+// DownloadAllNewsToFile.
+func (d *NewsDownloader) ResumeDownload(ctx context.Context, req *DownloadRequest) (*DownloadResult, error) {
+	fingerprint := req.Fingerprint()
+	checkpoint, err := LoadCheckpoint(CheckpointPath(d.config.OutputDir, fingerprint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint: %w", err)
+	}
+
+	if checkpoint != nil && checkpoint.Fingerprint != fingerprint {
+		log.Printf("Ignoring checkpoint for a different request (%s)", checkpoint.Fingerprint)
+		checkpoint = nil
+	}
+
+	// Continue the window the interrupted run resolved rather than resolving it anew
+	var watermark time.Time
+	if checkpoint != nil && req.From.IsZero() && !checkpoint.From.IsZero() {
+		resolved := *req
+		resolved.From = checkpoint.From
+		req, watermark = &resolved, checkpoint.Watermark
+	} else if req, watermark, err = d.resolveFrom(req); err != nil {
+		return nil, err
+	}
+
+	if err := d.provider.ValidateRequest(req); err != nil {
+		return nil, fmt.Errorf("invalid download request: %w", err)
+	}
+
+	if checkpoint == nil {
+		log.Printf("No checkpoint found for request %s, starting from page %d", fingerprint, req.StartPage)
+	}
+
+	return d.reportKeyUsage(d.download(ctx, req, fingerprint, watermark, checkpoint, nil))
+}
+
+// reportKeyUsage adds the provider's per-key usage to a download's result
+func (d *NewsDownloader) reportKeyUsage(result *DownloadResult, err error) (*DownloadResult, error) {
+	if reporter, ok := d.provider.(KeyUsageReporter); ok && result != nil {
+		result.KeyUsage = reporter.KeyUsage()
+	}
+	return result, err
+}
+
+// finishDownload tells a provider that keeps state per download that it has ended
+func (d *NewsDownloader) finishDownload(req *DownloadRequest, completed bool) error {
+	if finisher, ok := d.provider.(DownloadFinisher); ok {
+		return finisher.FinishDownload(req, completed)
+	}
+	return nil
+}
+
+// resolveFrom fills in the window start of a request that does not set From explicitly.
+// It uses the newest publication time seen by earlier runs of the same query, falling
+// back to the start of yesterday for a query that has never completed a run. The
+// watermark used, if any, is returned too. The caller's request is not modified.
+func (d *NewsDownloader) resolveFrom(req *DownloadRequest) (*DownloadRequest, time.Time, error) {
+	if !req.From.IsZero() {
+		return req, time.Time{}, nil
+	}
+
+	watermark, err := LoadWatermark(WatermarkPath(d.config.OutputDir, req.WatermarkKey()))
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to load watermark: %w", err)
+	}
+
+	resolved := *req
+	if watermark != nil && !watermark.PublishedAt.IsZero() {
+		resolved.From = watermark.PublishedAt
+		log.Printf("Fetching articles published since last run's watermark %s", resolved.From.Format(time.RFC3339))
+		return &resolved, watermark.PublishedAt, nil
+	}
+
+	resolved.From = defaultFrom(time.Now())
+	log.Printf("No watermark for this query yet, fetching articles since %s", resolved.From.Format(time.RFC3339))
+	return &resolved, time.Time{}, nil
+}
+
+// advanceWatermark moves the query's watermark forward to publishedAt.
+// The watermark never moves backwards, so re-running an old window is harmless.
+func (d *NewsDownloader) advanceWatermark(req *DownloadRequest, publishedAt time.Time) error {
+	if publishedAt.IsZero() {
+		return nil
+	}
+
+	queryKey := req.WatermarkKey()
+	path := WatermarkPath(d.config.OutputDir, queryKey)
+
+	current, err := LoadWatermark(path)
+	if err != nil {
+		return err
+	}
+
+	if current != nil && !publishedAt.After(current.PublishedAt) {
+		return nil
+	}
+
+	return SaveWatermark(path, &Watermark{
+		QueryKey:    queryKey,
+		PublishedAt: publishedAt,
+		UpdatedAt:   time.Now(),
+	})
+}
+
+// download runs the paging loop, starting after resumeFrom when it is not nil.
+// Progress is checkpointed under fingerprint, that of the request before its From
+// was resolved. Articles published at or before watermark, which an earlier run saved, are dropped.
+// first, when not nil, is a page already fetched and is used instead of fetching it.
+func (d *NewsDownloader) download(ctx context.Context, req *DownloadRequest, fingerprint string, watermark time.Time, resumeFrom *Checkpoint, first *pageFetch) (*DownloadResult, error) {
+	startTime := time.Now()
+	runID := utils.NewRunID(startTime)
+
 	result := &DownloadResult{
 		StartTime:       startTime,
 		FilePaths:       make([]string, 0),
 		PagesDownloaded: 0,
+		PageAttempts:    make(map[int]int),
 		Errors:          make([]error, 0),
+		From:            req.From,
 	}
 
+	checkpointPath := CheckpointPath(d.config.OutputDir, fingerprint)
+	checkpoint := &Checkpoint{Fingerprint: fingerprint, From: req.From, Watermark: watermark}
+
+	// A download that returns early releases the provider's state without keeping it
+	finished := false
+	defer func() {
+		if !finished {
+			d.finishDownload(req, false)
+		}
+	}()
+
 	currentPage := req.StartPage
 	totalPages := 1
 	totalArticlesFound := 0
+	totalsKnown := false
+
+	// Pages the interrupted run skipped after an error are retried before moving on
+	var retryPages []int
+
+	if resumeFrom != nil {
+		checkpoint = resumeFrom
+		retryPages = append([]int(nil), resumeFrom.FailedPages...)
+		currentPage = resumeFrom.LastCompletedPage + 1
+		totalArticlesFound = resumeFrom.TotalResults
+		totalPages, result.Truncated = d.pageCount(totalArticlesFound, req.PageSize)
+		totalsKnown = true
+
+		result.FilePaths = append(result.FilePaths, resumeFrom.FilePaths...)
+		result.PagesDownloaded = len(resumeFrom.FilePaths)
+		result.TotalArticles = totalArticlesFound
+		result.ResumedFromPage = currentPage
+
+		log.Printf("Resuming download at page %d of %d (%d files already written, %d failed pages to retry)",
+			currentPage, totalPages, len(resumeFrom.FilePaths), len(retryPages))
+	}
 
-	log.Printf("Starting news download for country=%s, query=%s, from=%s", 
-		req.Country, req.Query, req.From.Format("2006-01-02"))
+	// saveCheckpoint records progress so an interrupted run can resume after page
+	saveCheckpoint := func(page int, retried bool) {
+		if !retried {
+			checkpoint.LastCompletedPage = page
+		}
+		checkpoint.TotalResults = totalArticlesFound
+		checkpoint.FilePaths = append([]string(nil), result.FilePaths...)
+		checkpoint.UpdatedAt = time.Now()
+		if err := SaveCheckpoint(checkpointPath, checkpoint); err != nil {
+			log.Printf("Failed to save checkpoint: %v", err)
+			result.Errors = append(result.Errors, fmt.Errorf("checkpoint for page %d: %w", page, err))
+		}
+	}
 
-	for currentPage <= totalPages {
+	// skipPage records a page that failed so that resuming the download retries it;
+	// a retried page is still recorded from the earlier run
+	skipPage := func(page int, retried bool) {
+		if !retried {
+			checkpoint.FailedPages = append(checkpoint.FailedPages, page)
+		}
+		saveCheckpoint(page, retried)
+	}
+
+	log.Printf("Starting news download from %s for endpoint=%s, country=%s, query=%s, from=%s", 
+		d.provider.Name(), req.EffectiveEndpoint(), req.Country, req.Query, req.From.Format("2006-01-02"))
+
+	// Once the total is known, the remaining pages are fetched concurrently and
+	// handed back in page order
+	var pipeline <-chan pageFetch
+	pipelineCtx, cancelPipeline := context.WithCancel(ctx)
+	defer cancelPipeline()
+
+	for len(retryPages) > 0 || currentPage <= totalPages {
 		select {
 		case <-ctx.Done():
 			return result, fmt.Errorf("download cancelled: %w", ctx.Err())
 		default:
 		}
 
-		// Fetch the page
-		newsResp, limits, err := d.client.FetchNewsPage(ctx, req, currentPage)
-		if err != nil {
-			// Handle rate limiting by retrying
-			if rateLimitErr, ok := err.(*RateLimitError); ok {
-				log.Printf("Rate limit hit, waiting %v before retry", rateLimitErr.RetryAfter)
-				
-				select {
-				case <-time.After(rateLimitErr.RetryAfter):
-					continue // Retry the same page
-				case <-ctx.Done():
-					return result, fmt.Errorf("download cancelled during rate limit wait: %w", ctx.Err())
+		page, retried := currentPage, len(retryPages) > 0
+		if retried {
+			page = retryPages[0]
+			retryPages = retryPages[1:]
+		} else {
+			currentPage++
+		}
+
+		if !retried && pipeline == nil && totalsKnown && page < totalPages && d.config.Concurrency > 1 {
+			pipeline = d.fetchPages(pipelineCtx, req, page, totalPages)
+		}
+
+		var fetch pageFetch
+		if first != nil && first.page == page {
+			fetch, first = *first, nil
+		} else if !retried && pipeline != nil {
+			next, ok := <-pipeline
+			if !ok {
+				if ctx.Err() != nil {
+					return result, fmt.Errorf("download cancelled: %w", ctx.Err())
 				}
+				// The pipeline starts no new pages once a stop is requested
+				return result, fmt.Errorf("download stopped before page %d: %w", page, ErrStopped)
 			}
-			
+			fetch = next
+		} else {
+			select {
+			case <-stopChan(ctx):
+				return result, fmt.Errorf("download stopped before page %d: %w", page, ErrStopped)
+			default:
+			}
+			fetch = d.fetchPage(ctx, req, page)
+		}
+
+		result.TotalAttempts += fetch.attempts
+		result.PageAttempts[page] += fetch.attempts
+
+		newsResp, limits, err := fetch.resp, fetch.limits, fetch.err
+		if err != nil {
+			if errors.Is(err, ErrStopped) {
+				return result, fmt.Errorf("download stopped before page %d: %w", page, err)
+			}
+
 			// For other errors, record and continue or fail depending on severity
-			result.Errors = append(result.Errors, fmt.Errorf("page %d: %w", currentPage, err))
+			result.Errors = append(result.Errors, fmt.Errorf("page %d: %w", page, err))
 			
 			// For critical errors, fail immediately
 			if _, ok := err.(*NewsAPIError); ok {
-				return result, fmt.Errorf("API error on page %d: %w", currentPage, err)
+				return result, fmt.Errorf("API error on page %d: %w", page, err)
+			}
+			if _, ok := err.(*BudgetExhaustedError); ok {
+				return result, fmt.Errorf("request budget exhausted on page %d: %w", page, err)
 			}
 			
 			// For other errors, skip this page and continue
-			log.Printf("Error on page %d, skipping: %v", currentPage, err)
-			currentPage++
+			log.Printf("Error on page %d, skipping: %v", page, err)
+			skipPage(page, retried)
 			continue
 		}
 
@@ -111,48 +417,104 @@ func (d *NewsDownloader) DownloadAllNewsToFile(ctx context.Context, req *Downloa
 				limits.Limit, limits.Remaining, limits.Reset.Format(time.RFC3339))
 		}
 
-		// Save the page to file
-		filePath, err := d.savePageToFile(newsResp, req.Country, currentPage)
-		if err != nil {
-			result.Errors = append(result.Errors, fmt.Errorf("failed to save page %d: %w", currentPage, err))
-			currentPage++
-			continue
-		}
-
-		result.FilePaths = append(result.FilePaths, filePath)
-		result.PagesDownloaded++
-
-		log.Printf("Saved page %d to %s", currentPage, filePath)
-
-		// Publish file path to Kafka
-		if err := d.publishFilePath(ctx, filePath); err != nil {
-			// Log the error but don't fail the download
-			log.Printf("Failed to publish file path to Kafka: %v", err)
-			result.Errors = append(result.Errors, fmt.Errorf("kafka publish for %s: %w", filePath, err))
-		}
-
 		// Update totals on first page
-		if currentPage == req.StartPage {
+		if !totalsKnown {
 			totalArticlesFound = newsResp.TotalResults
-			totalPages = (totalArticlesFound + req.PageSize - 1) / req.PageSize
+			totalPages, result.Truncated = d.pageCount(totalArticlesFound, req.PageSize)
 			result.TotalArticles = totalArticlesFound
+			totalsKnown = true
 			
 			log.Printf("Total results found: %d, Estimated total pages: %d", 
 				totalArticlesFound, totalPages)
+			if result.Truncated {
+				log.Printf("Warning: only the first %d of %d results can be paged through; narrow the query or its time window to get the rest",
+					totalPages*req.PageSize, totalArticlesFound)
+			}
 		}
 
-		log.Printf("Progress: %d/%d pages completed", currentPage-req.StartPage+1, totalPages)
+		// Top-headlines ignores from, so articles saved by earlier runs come back
+		newsResp, older := filterWatermark(newsResp, watermark)
+		if older > 0 {
+			log.Printf("Dropped %d articles on page %d published before the watermark", older, page)
+		}
 
-		currentPage++
+		// Drop articles already seen on earlier pages or in earlier runs
+		pageResp, newKeys, skipped := d.filterDuplicates(newsResp)
+		result.DuplicatesSkipped += skipped
+
+		if pageResp.IsEmpty() && skipped+older > 0 {
+			log.Printf("All %d articles on page %d were already seen, nothing to save", skipped+older, page)
+		} else {
+			// Save the page to file
+			filePath, checksum, err := d.savePage(ctx, req, runID, result.StartTime, pageResp, page)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Errorf("failed to save page %d: %w", page, err))
+				skipPage(page, retried)
+				continue
+			}
 
-		// Add a small delay between requests to be respectful
-		if currentPage <= totalPages {
-			select {
-			case <-time.After(500 * time.Millisecond):
-			case <-ctx.Done():
-				return result, fmt.Errorf("download cancelled: %w", ctx.Err())
+			result.FilePaths = append(result.FilePaths, filePath)
+			result.PagesDownloaded++
+
+			for _, article := range pageResp.Articles {
+				if article.PublishedAt.After(result.MaxPublishedAt) {
+					result.MaxPublishedAt = article.PublishedAt
+				}
 			}
+
+			log.Printf("Saved page %d to %s (%d duplicates skipped)", page, filePath, skipped)
+
+			// Only remember articles once they are safely on disk
+			if d.dedup != nil {
+				if err := d.dedup.Add(newKeys...); err != nil {
+					log.Printf("Failed to record seen articles: %v", err)
+					result.Errors = append(result.Errors, fmt.Errorf("dedup store for page %d: %w", page, err))
+				}
+			}
+
+			// Publish file path to Kafka
+			if err := d.publishFilePath(ctx, filePath, checksum); err != nil {
+				// Log the error but don't fail the download
+				log.Printf("Failed to publish file path to Kafka: %v", err)
+				result.Errors = append(result.Errors, fmt.Errorf("kafka publish for %s: %w", filePath, err))
+			}
+		}
+
+		// Record progress so an interrupted run can resume after this page
+		if retried {
+			checkpoint.FailedPages = removePage(checkpoint.FailedPages, page)
 		}
+		saveCheckpoint(page, retried)
+
+		if retried {
+			log.Printf("Progress: retried page %d", page)
+		} else {
+			log.Printf("Progress: %d/%d pages completed", page-req.StartPage+1, totalPages)
+		}
+	}
+
+	// Let the provider keep what it served only once every page is saved
+	finished = true
+	if err := d.finishDownload(req, len(checkpoint.FailedPages) == 0); err != nil {
+		log.Printf("Failed to finish download with %s: %v", d.provider.Name(), err)
+		result.Errors = append(result.Errors, fmt.Errorf("finish download: %w", err))
+	}
+
+	// The download is complete, so there is nothing left to resume unless pages
+	// failed, which the checkpoint keeps for ResumeDownload to retry
+	if len(checkpoint.FailedPages) > 0 {
+		log.Printf("Pages %v failed; resume the download to retry them", checkpoint.FailedPages)
+	} else if err := RemoveCheckpoint(checkpointPath); err != nil {
+		log.Printf("Failed to remove checkpoint: %v", err)
+	}
+
+	// The next run of this query only needs articles published after this one's newest,
+	// unless pages were skipped: their articles may be older and must not fall behind From
+	if len(checkpoint.FailedPages) > 0 {
+		log.Printf("Not advancing the watermark past %d failed pages", len(checkpoint.FailedPages))
+	} else if err := d.advanceWatermark(req, result.MaxPublishedAt); err != nil {
+		log.Printf("Failed to save watermark: %v", err)
+		result.Errors = append(result.Errors, fmt.Errorf("watermark: %w", err))
 	}
 
 	result.EndTime = time.Now()
@@ -164,51 +526,340 @@ func (d *NewsDownloader) DownloadAllNewsToFile(ctx context.Context, req *Downloa
 	return result, nil
 }
 
-// savePageToFile saves a news page response to a JSON file
-func (d *NewsDownloader) savePageToFile(newsResp *NewsAPIResponse, country string, page int) (string, error) {
-	// Generate file path
-	fullOutputDir, fullJSONPath := utils.GenerateJSONFilePath(d.config.OutputDir, country, page)
+// removePage returns pages without page
+func removePage(pages []int, page int) []int {
+	kept := make([]int, 0, len(pages))
+	for _, p := range pages {
+		if p != page {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
 
-	// Create output directory structure if it doesn't exist
-	if err := os.MkdirAll(fullOutputDir, 0755); err != nil {
-		return "", &FileOperationError{
-			Operation: "create directory",
-			FilePath:  fullOutputDir,
-			Cause:     err,
+// pageCount returns how many pages of pageSize cover total results, limited to the
+// pages NewsAPI lets a single query reach. truncated reports whether results are cut off.
+func (d *NewsDownloader) pageCount(total, pageSize int) (pages int, truncated bool) {
+	pages = (total + pageSize - 1) / pageSize
+
+	limit := d.config.MaxPageableResults
+	if limit > 0 && total > limit {
+		maxPages := limit / pageSize
+		if maxPages < 1 {
+			maxPages = 1
+		}
+		if pages > maxPages {
+			return maxPages, true
+		}
+	}
+
+	return pages, false
+}
+
+// filterWatermark returns a copy of newsResp without articles published at or before
+// watermark, which the run that set it already saved, together with the number
+// dropped. Articles without a publication time are kept.
+func filterWatermark(newsResp *NewsAPIResponse, watermark time.Time) (*NewsAPIResponse, int) {
+	if watermark.IsZero() {
+		return newsResp, 0
+	}
+
+	filtered := *newsResp
+	filtered.Articles = make([]Article, 0, len(newsResp.Articles))
+	for _, article := range newsResp.Articles {
+		if article.PublishedAt.IsZero() || article.PublishedAt.After(watermark) {
+			filtered.Articles = append(filtered.Articles, article)
+		}
+	}
+
+	return &filtered, len(newsResp.Articles) - len(filtered.Articles)
+}
+
+// filterDuplicates returns a copy of newsResp without articles that were already seen,
+// either earlier on the same page or in the dedup store, together with the keys of the
+// articles that were kept and the number of articles dropped
+func (d *NewsDownloader) filterDuplicates(newsResp *NewsAPIResponse) (*NewsAPIResponse, []string, int) {
+	if d.dedup == nil {
+		return newsResp, nil, 0
+	}
+
+	filtered := *newsResp
+	filtered.Articles = make([]Article, 0, len(newsResp.Articles))
+	keys := make([]string, 0, len(newsResp.Articles))
+	onPage := make(map[string]bool, len(newsResp.Articles))
+	skipped := 0
+
+	for _, article := range newsResp.Articles {
+		key := ArticleKey(article)
+		if key == "" {
+			// Nothing to identify the article by, so keep it
+			filtered.Articles = append(filtered.Articles, article)
+			continue
+		}
+
+		if onPage[key] || d.dedup.Seen(key) {
+			skipped++
+			continue
+		}
+
+		onPage[key] = true
+		keys = append(keys, key)
+		filtered.Articles = append(filtered.Articles, article)
+	}
+
+	return &filtered, keys, skipped
+}
+
+// pageFetch is the outcome of fetching one page
+type pageFetch struct {
+	page     int
+	resp     *NewsAPIResponse
+	limits   *NewsAPILimits
+	attempts int
+	err      error
+}
+
+// fetchPage fetches a page, retrying transient failures and waiting out rate limits
+func (d *NewsDownloader) fetchPage(ctx context.Context, req *DownloadRequest, page int) pageFetch {
+	fetch := pageFetch{page: page}
+	for {
+		newsResp, limits, attempts, err := d.fetchPageWithRetry(ctx, req, page)
+		fetch.resp, fetch.limits, fetch.err = newsResp, limits, err
+		fetch.attempts += attempts
+
+		rateLimitErr, ok := err.(*RateLimitError)
+		if !ok {
+			return fetch
+		}
+
+		log.Printf("Rate limit hit on page %d, waiting %v before retry", page, rateLimitErr.RetryAfter)
+		select {
+		case <-time.After(rateLimitErr.RetryAfter):
+		case <-ctx.Done():
+			fetch.err = fmt.Errorf("download cancelled during rate limit wait: %w", ctx.Err())
+			return fetch
+		case <-stopChan(ctx):
+			fetch.err = fmt.Errorf("download stopped during rate limit wait: %w", ErrStopped)
+			return fetch
 		}
 	}
+}
+
+// fetchPages fetches pages first to last with up to Config.Concurrency workers and
+// delivers them in page order, so that pages are still deduplicated, saved,
+// published and checkpointed in sequence. Requests stay paced by the provider's
+// rate limiter, which every worker shares. Once a stop is requested no new pages
+// are started, and the channel closes after the pages in flight are delivered.
+func (d *NewsDownloader) fetchPages(ctx context.Context, req *DownloadRequest, first, last int) <-chan pageFetch {
+	workers := d.config.Concurrency
+	if count := last - first + 1; workers > count {
+		workers = count
+	}
+
+	pages := make(chan int)
+	fetched := make(chan pageFetch)
+	ordered := make(chan pageFetch)
+
+	go func() {
+		defer close(pages)
+		for page := first; page <= last; page++ {
+			select {
+			case <-stopChan(ctx):
+				return
+			default:
+			}
+
+			select {
+			case pages <- page:
+			case <-ctx.Done():
+				return
+			case <-stopChan(ctx):
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for page := range pages {
+				select {
+				case fetched <- d.fetchPage(ctx, req, page):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(fetched)
+	}()
+
+	// Hold pages that finish early until every page before them is delivered
+	go func() {
+		defer close(ordered)
+		pending := make(map[int]pageFetch)
+		next := first
+		for fetch := range fetched {
+			pending[fetch.page] = fetch
+			for {
+				ready, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				select {
+				case ordered <- ready:
+				case <-ctx.Done():
+					return
+				}
+				next++
+			}
+		}
+	}()
 
-	// Marshal the response to JSON
-	jsonData, err := json.MarshalIndent(newsResp, "", "  ")
+	return ordered
+}
+
+// fetchPageWithRetry fetches a page through the retry policy and returns the number of attempts made
+func (d *NewsDownloader) fetchPageWithRetry(ctx context.Context, req *DownloadRequest, page int) (*NewsAPIResponse, *NewsAPILimits, int, error) {
+	var (
+		newsResp *NewsAPIResponse
+		limits   *NewsAPILimits
+	)
+
+	attempts, err := d.retryPolicy.Do(ctx, func() error {
+		var fetchErr error
+		var fetched *Page
+		fetched, fetchErr = d.provider.FetchPage(ctx, req, page)
+		if fetched != nil {
+			limits = fetched.Limits
+		}
+		if fetchErr == nil {
+			newsResp = fetched.response()
+		}
+		if fetchErr != nil && IsRetryable(fetchErr) {
+			log.Printf("Transient error on page %d: %v", page, fetchErr)
+		}
+		return fetchErr
+	})
+
+	if attempts > 1 {
+		log.Printf("Page %d took %d attempts", page, attempts)
+	}
+
+	if err != nil {
+		return nil, limits, attempts, err
+	}
+	return newsResp, limits, attempts, nil
+}
+
+// savePage saves a news page response in the configured output format,
+// compressed with the configured codec, followed by a SHA-256 sidecar so that
+// consumers can verify it. It returns the page's storage URI and the
+// hex-encoded checksum. The key comes from the configured path template, with
+// the run's start time in UTC like its run ID, so the pages of a run that
+// crosses midnight still share their date and time placeholders.
+func (d *NewsDownloader) savePage(ctx context.Context, req *DownloadRequest, runID string, started time.Time, newsResp *NewsAPIResponse, page int) (string, string, error) {
+	// Generate the key; the codec's extension follows the format's, e.g. .json.gz
+	extension := d.pageWriter.Extension()
+	if codecExtension := d.compression.Extension(); codecExtension != "" {
+		extension += "." + codecExtension
+	}
+	// Only NewsAPI has endpoints; other providers leave {endpoint} empty, even
+	// though their requests carry NewDownloadRequest's default endpoint
+	var endpoint Endpoint
+	if d.provider.Name() == NewsAPIProviderName {
+		endpoint = req.EffectiveEndpoint()
+	}
+	_, relativePath := d.paths.GeneratePath("", utils.PathParams{
+		Provider:  d.provider.Name(),
+		Endpoint:  string(endpoint),
+		Country:   req.Country,
+		Query:     req.Query,
+		RunID:     runID,
+		Page:      page,
+		Extension: extension,
+		Time:      started.UTC(),
+	})
+	key := filepath.ToSlash(relativePath)
+	uri := d.storage.URI(key)
+
+	// Encode the page straight into the storage, hashing it on the way
+	reader, writer := io.Pipe()
+	hash := sha256.New()
+	encoded := make(chan struct{})
+	go func() {
+		defer close(encoded)
+		writer.CloseWithError(d.encodePage(io.MultiWriter(writer, hash), newsResp))
+	}()
+
+	err := d.storage.Put(ctx, key, reader)
+	reader.CloseWithError(err)
+	<-encoded
 	if err != nil {
-		return "", &FileOperationError{
-			Operation: "marshal JSON",
-			FilePath:  fullJSONPath,
+		return "", "", &FileOperationError{
+			Operation: "write file",
+			FilePath:  uri,
 			Cause:     err,
 		}
 	}
 
-	// Write the JSON to file
-	if err := ioutil.WriteFile(fullJSONPath, jsonData, 0644); err != nil {
-		return "", &FileOperationError{
-			Operation: "write file",
-			FilePath:  fullJSONPath,
+	// The page is only published once its sidecar is stored too; without one
+	// it is removed again, so a published page can always be verified
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	sidecarKey := key + "." + utils.ChecksumExtension
+	if err := d.storage.Put(ctx, sidecarKey, strings.NewReader(utils.FormatChecksum(checksum, key))); err != nil {
+		d.storage.Delete(ctx, key)
+		return "", "", &FileOperationError{
+			Operation: "write checksum",
+			FilePath:  d.storage.URI(sidecarKey),
 			Cause:     err,
 		}
 	}
 
-	return fullJSONPath, nil
+	return uri, checksum, nil
+}
+
+// encodePage writes the page to w through the compressor
+func (d *NewsDownloader) encodePage(w io.Writer, newsResp *NewsAPIResponse) error {
+	writer, err := utils.NewCompressingWriter(w, d.compression)
+	if err != nil {
+		return err
+	}
+
+	err = d.pageWriter.WritePage(writer, newsResp)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-// publishFilePath publishes a file path to Kafka
-func (d *NewsDownloader) publishFilePath(ctx context.Context, filePath string) error {
+// publishFilePath publishes the storage URI of a saved page to Kafka
+func (d *NewsDownloader) publishFilePath(ctx context.Context, filePath, checksum string) error {
 	if d.publisher == nil {
 		return fmt.Errorf("Kafka publisher not initialized")
 	}
 
 	log.Printf("Publishing file path to Kafka topic '%s'...", d.config.KafkaTopic)
 	
-	if err := d.publisher.PublishWithContext(ctx, d.config.KafkaBroker, d.config.KafkaTopic, filePath); err != nil {
+	// Publishers that support headers also say how the file is encoded
+	var err error
+	if publisher, ok := d.publisher.(kafka_producer.HeaderPublisher); ok {
+		headers := map[string]string{
+			HeaderFormat:      string(d.pageWriter.Format()),
+			HeaderCompression: string(d.compression),
+			HeaderSHA256:      checksum,
+		}
+		err = publisher.PublishWithHeaders(ctx, d.config.KafkaBroker, d.config.KafkaTopic, filePath, headers)
+	} else {
+		err = d.publisher.PublishWithContext(ctx, d.config.KafkaBroker, d.config.KafkaTopic, filePath)
+	}
+	if err != nil {
 		return &KafkaError{
 			Operation: "publish",
 			Topic:     d.config.KafkaTopic,
@@ -222,6 +873,12 @@ func (d *NewsDownloader) publishFilePath(ctx context.Context, filePath string) e
 
 // Close closes the downloader and releases resources
 func (d *NewsDownloader) Close() error {
+	if d.dedup != nil {
+		if err := d.dedup.Close(); err != nil {
+			log.Printf("Error closing dedup store: %v", err)
+		}
+	}
+
 	if d.publisher != nil {
 		return d.publisher.Close()
 	}
@@ -1,9 +1,27 @@
 package newsapi
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"go-news-agg/internal/config"
+	"go-news-agg/internal/fakes3"
+	"go-news-agg/internal/storage"
+	"go-news-agg/pkg/utils"
 )
 
 func TestNewsDownloader_DownloadAllNewsToFile(t *testing.T) {
@@ -22,4 +40,1003 @@ func TestNewsDownloader_DownloadAllNewsToFile(t *testing.T) {
 	if apiClient == nil {
 		t.Error("Expected non-nil API client")
 	}
-}
\ No newline at end of file
+}
+
+// stubResponse describes a canned HTTP response for sequenceHTTPClient
+type stubResponse struct {
+	status int
+	body   string
+	err    error
+}
+
+// sequenceHTTPClient serves canned responses in order, repeating the last one
+type sequenceHTTPClient struct {
+	mutex     sync.Mutex
+	responses []stubResponse
+	urls      []string
+	headers   []http.Header
+}
+
+func (s *sequenceHTTPClient) Get(url string) (*http.Response, error) {
+	return s.GetWithContext(context.Background(), url)
+}
+
+func (s *sequenceHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	s.mutex.Lock()
+	s.headers = append(s.headers, req.Header.Clone())
+	s.mutex.Unlock()
+	return s.GetWithContext(req.Context(), req.URL.String())
+}
+
+func (s *sequenceHTTPClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	next := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	s.urls = append(s.urls, url)
+
+	if next.err != nil {
+		return nil, next.err
+	}
+	return &http.Response{
+		StatusCode: next.status,
+		Body:       ioutil.NopCloser(strings.NewReader(next.body)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func (s *sequenceHTTPClient) calls() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return len(s.urls)
+}
+
+// recordingPublisher implements kafka_producer.HeaderPublisher in memory
+type recordingPublisher struct {
+	mutex    sync.Mutex
+	messages []string
+	headers  []map[string]string
+}
+
+func (p *recordingPublisher) Publish(broker, topic, message string) error {
+	return p.PublishWithContext(context.Background(), broker, topic, message)
+}
+
+func (p *recordingPublisher) PublishWithContext(ctx context.Context, broker, topic, message string) error {
+	return p.PublishWithHeaders(ctx, broker, topic, message, nil)
+}
+
+func (p *recordingPublisher) PublishWithHeaders(ctx context.Context, broker, topic, message string, headers map[string]string) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.messages = append(p.messages, message)
+	p.headers = append(p.headers, headers)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error {
+	return nil
+}
+
+// newTestDownloader builds a downloader writing to a temp dir with a fast retry policy
+func newTestDownloader(t *testing.T, httpClient HTTPClient) (*NewsDownloader, *recordingPublisher) {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.MaxRetries = 2
+	cfg.RequestsPerSecond = 0
+
+	publisher := &recordingPublisher{}
+	downloader := NewNewsDownloader(NewNewsAPIClientWithHTTPClient(cfg, httpClient), publisher, cfg)
+	downloader.retryPolicy.BaseDelay = time.Millisecond
+	downloader.retryPolicy.MaxDelay = time.Millisecond
+
+	return downloader, publisher
+}
+
+func mustMarshalResponse(t *testing.T, resp *NewsAPIResponse) string {
+	t.Helper()
+	data, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatalf("Failed to marshal response: %v", err)
+	}
+	return string(data)
+}
+
+func TestNewsDownloader_RetriesTransientErrors(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusInternalServerError, body: `{"status": "error", "code": "unexpectedError", "message": "boom"}`},
+		{status: http.StatusBadGateway, body: "bad gateway"},
+		{status: http.StatusOK, body: mustMarshalResponse(t, createMockNewsAPIResponse())},
+	}}
+
+	downloader, publisher := newTestDownloader(t, httpClient)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), NewDownloadRequest("test-key", "us"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.PagesDownloaded != 1 {
+		t.Errorf("Expected 1 page downloaded, got %d", result.PagesDownloaded)
+	}
+
+	if result.TotalAttempts != 3 || result.PageAttempts[1] != 3 {
+		t.Errorf("Expected 3 attempts for page 1, got total=%d page=%d", result.TotalAttempts, result.PageAttempts[1])
+	}
+
+	if len(publisher.messages) != 1 {
+		t.Errorf("Expected 1 published message, got %d", len(publisher.messages))
+	}
+}
+
+func TestNewsDownloader_DoesNotRetryClientErrors(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusUnauthorized, body: `{"status": "error", "code": "apiKeyInvalid", "message": "Your API key is invalid or incorrect."}`},
+	}}
+
+	downloader, _ := newTestDownloader(t, httpClient)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), NewDownloadRequest("bad-key", "us"))
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	if httpClient.calls() != 1 {
+		t.Errorf("Expected a single request, got %d", httpClient.calls())
+	}
+
+	if result.TotalAttempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", result.TotalAttempts)
+	}
+}
+
+func TestNewsDownloader_ResumeDownload(t *testing.T) {
+	page := createMockNewsAPIResponse()
+	page.TotalResults = 40
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, page)},
+	}}
+
+	downloader, publisher := newTestDownloader(t, httpClient)
+
+	req := NewDownloadRequest("test-key", "us")
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	checkpointPath := CheckpointPath(downloader.config.OutputDir, req.Fingerprint())
+
+	// Simulate an earlier run that stopped after page 1 of 2
+	if err := SaveCheckpoint(checkpointPath, &Checkpoint{
+		Fingerprint:       req.Fingerprint(),
+		LastCompletedPage: 1,
+		TotalResults:      40,
+		FilePaths:         []string{"/tmp/earlier_page1.json"},
+	}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	result, err := downloader.ResumeDownload(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if httpClient.calls() != 1 || !strings.Contains(httpClient.urls[0], "page=2") {
+		t.Errorf("Expected a single request for page 2, got %v", httpClient.urls)
+	}
+
+	if result.ResumedFromPage != 2 {
+		t.Errorf("Expected to resume from page 2, got %d", result.ResumedFromPage)
+	}
+
+	if result.PagesDownloaded != 2 || len(result.FilePaths) != 2 || result.FilePaths[0] != "/tmp/earlier_page1.json" {
+		t.Errorf("Expected earlier files to be merged into the result, got %v", result.FilePaths)
+	}
+
+	if len(publisher.messages) != 1 {
+		t.Errorf("Expected only the new page to be published, got %d messages", len(publisher.messages))
+	}
+
+	if checkpoint, _ := LoadCheckpoint(checkpointPath); checkpoint != nil {
+		t.Errorf("Expected checkpoint to be removed after a completed download, got %+v", checkpoint)
+	}
+}
+
+func TestNewsDownloader_ResumesWindowResolvedByInterruptedRun(t *testing.T) {
+	page := createMockNewsAPIResponse()
+	page.TotalResults = 40
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, page)},
+	}}
+	downloader, _ := newTestDownloader(t, httpClient)
+
+	// The earlier run had no From and resolved it on an earlier day
+	req := NewDownloadRequest("test-key", "")
+	req.Endpoint = EndpointEverything
+	req.Query = "bitcoin"
+	resolvedFrom := time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)
+	if err := SaveCheckpoint(CheckpointPath(downloader.config.OutputDir, req.Fingerprint()), &Checkpoint{
+		Fingerprint:       req.Fingerprint(),
+		From:              resolvedFrom,
+		LastCompletedPage: 1,
+		TotalResults:      40,
+		FilePaths:         []string{"/tmp/earlier_page1.json"},
+	}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	result, err := downloader.ResumeDownload(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.ResumedFromPage != 2 || len(result.FilePaths) != 2 {
+		t.Errorf("Expected to resume from page 2, got page %d and files %v", result.ResumedFromPage, result.FilePaths)
+	}
+	if httpClient.calls() != 1 || !strings.Contains(httpClient.urls[0], "from="+url.QueryEscape(resolvedFrom.Format(time.RFC3339))) {
+		t.Errorf("Expected page 2 of the interrupted run's window, got %v", httpClient.urls)
+	}
+}
+
+func TestNewsDownloader_KeepsCheckpointWhenInterrupted(t *testing.T) {
+	page := createMockNewsAPIResponse()
+	page.TotalResults = 40
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, page)},
+		{status: http.StatusUnauthorized, body: `{"status": "error", "code": "apiKeyExhausted", "message": "quota used up"}`},
+	}}
+
+	downloader, _ := newTestDownloader(t, httpClient)
+	req := NewDownloadRequest("test-key", "us")
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	if _, err := downloader.DownloadAllNewsToFile(context.Background(), req); err == nil {
+		t.Fatal("Expected an error on page 2, got nil")
+	}
+
+	checkpoint, err := LoadCheckpoint(CheckpointPath(downloader.config.OutputDir, req.Fingerprint()))
+	if err != nil || checkpoint == nil {
+		t.Fatalf("Expected a checkpoint after the interrupted run, got %+v, %v", checkpoint, err)
+	}
+
+	if checkpoint.LastCompletedPage != 1 || checkpoint.TotalResults != 40 || len(checkpoint.FilePaths) != 1 {
+		t.Errorf("Unexpected checkpoint: %+v", checkpoint)
+	}
+}
+
+// stoppingHTTPClient closes stop as soon as the first request is sent
+type stoppingHTTPClient struct {
+	*sequenceHTTPClient
+	stop chan struct{}
+	once sync.Once
+}
+
+func (s *stoppingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	s.once.Do(func() { close(s.stop) })
+	return s.sequenceHTTPClient.Do(req)
+}
+
+func TestNewsDownloader_StopFinishesInFlightPage(t *testing.T) {
+	page := createMockNewsAPIResponse()
+	page.TotalResults = 40
+
+	httpClient := &stoppingHTTPClient{
+		sequenceHTTPClient: &sequenceHTTPClient{responses: []stubResponse{
+			{status: http.StatusOK, body: mustMarshalResponse(t, page)},
+		}},
+		stop: make(chan struct{}),
+	}
+
+	downloader, publisher := newTestDownloader(t, httpClient)
+	req := NewDownloadRequest("test-key", "us")
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	ctx := WithStop(context.Background(), httpClient.stop)
+	result, err := downloader.DownloadAllNewsToFile(ctx, req)
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected ErrStopped, got %v", err)
+	}
+
+	// The page being fetched when the stop arrived is still saved and published
+	if httpClient.calls() != 1 {
+		t.Errorf("Expected 1 request, got %d", httpClient.calls())
+	}
+	if len(result.FilePaths) != 1 || len(publisher.messages) != 1 {
+		t.Errorf("Expected 1 saved and published page, got %d files and %d messages", len(result.FilePaths), len(publisher.messages))
+	}
+
+	checkpoint, err := LoadCheckpoint(CheckpointPath(downloader.config.OutputDir, req.Fingerprint()))
+	if err != nil || checkpoint == nil || checkpoint.LastCompletedPage != 1 {
+		t.Errorf("Expected a checkpoint at page 1, got %+v, %v", checkpoint, err)
+	}
+}
+
+func TestNewsDownloader_SkipsDuplicateArticles(t *testing.T) {
+	firstPage := createMockNewsAPIResponse()
+	firstPage.TotalResults = 4
+
+	// The second page repeats the first article under a tracking URL
+	secondPage := createMockNewsAPIResponse()
+	secondPage.TotalResults = 4
+	secondPage.Articles[0].URL = "https://www.example.com/article1?utm_source=feed"
+	secondPage.Articles[1].URL = "http://example.com/article3"
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, firstPage)},
+		{status: http.StatusOK, body: mustMarshalResponse(t, secondPage)},
+	}}
+
+	downloader, publisher := newTestDownloader(t, httpClient)
+	downloader.SetDedupStore(NewMemoryDedupStore())
+
+	// An explicit window, so the second run is not narrowed by the first's watermark
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+	req.From = time.Date(2023, 10, 27, 0, 0, 0, 0, time.UTC)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.DuplicatesSkipped != 1 {
+		t.Errorf("Expected 1 duplicate skipped, got %d", result.DuplicatesSkipped)
+	}
+
+	if len(result.FilePaths) != 2 || len(publisher.messages) != 2 {
+		t.Fatalf("Expected 2 saved and published pages, got %d files and %d messages", len(result.FilePaths), len(publisher.messages))
+	}
+
+	data, err := ioutil.ReadFile(result.FilePaths[1])
+	if err != nil {
+		t.Fatalf("Failed to read saved page: %v", err)
+	}
+
+	var saved NewsAPIResponse
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Failed to parse saved page: %v", err)
+	}
+
+	if len(saved.Articles) != 1 || saved.Articles[0].URL != "http://example.com/article3" {
+		t.Errorf("Expected only the new article on page 2, got %+v", saved.Articles)
+	}
+
+	// A second run over the same data saves nothing new
+	httpClient.responses = []stubResponse{{status: http.StatusOK, body: mustMarshalResponse(t, firstPage)}}
+	req.PageSize = 4
+
+	result, err = downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error on second run, got: %v", err)
+	}
+
+	if result.DuplicatesSkipped != 2 || len(result.FilePaths) != 0 {
+		t.Errorf("Expected all articles skipped on second run, got %d skipped and %d files", result.DuplicatesSkipped, len(result.FilePaths))
+	}
+}
+
+func TestNewsDownloader_UsesWatermarkForFrom(t *testing.T) {
+	page := createMockNewsAPIResponse()
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, page)},
+	}}
+
+	downloader, _ := newTestDownloader(t, httpClient)
+
+	req := &DownloadRequest{
+		APIKey:    "test-key",
+		Endpoint:  EndpointEverything,
+		Query:     "test",
+		PageSize:  20,
+		StartPage: 1,
+	}
+
+	// First run has no watermark and falls back to the start of yesterday
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !result.From.Equal(defaultFrom(time.Now())) {
+		t.Errorf("Expected first run to start at %v, got %v", defaultFrom(time.Now()), result.From)
+	}
+
+	newest := time.Date(2023, 10, 27, 11, 0, 0, 0, time.UTC)
+	if !result.MaxPublishedAt.Equal(newest) {
+		t.Errorf("Expected max published time %v, got %v", newest, result.MaxPublishedAt)
+	}
+
+	if !req.From.IsZero() {
+		t.Error("Expected the caller's request to be left unchanged")
+	}
+
+	// Second run starts at the watermark left by the first
+	if _, err := downloader.DownloadAllNewsToFile(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error on second run, got: %v", err)
+	}
+
+	if !strings.Contains(httpClient.urls[1], "from=2023-10-27T11%3A00%3A00Z") {
+		t.Errorf("Expected second run to request from the watermark, got %s", httpClient.urls[1])
+	}
+
+	// An explicit From overrides the watermark
+	override := *req
+	override.From = time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := downloader.DownloadAllNewsToFile(context.Background(), &override); err != nil {
+		t.Fatalf("Expected no error on override run, got: %v", err)
+	}
+
+	if !strings.Contains(httpClient.urls[2], "from=2023-10-01T00%3A00%3A00Z") {
+		t.Errorf("Expected explicit from to be used, got %s", httpClient.urls[2])
+	}
+}
+
+func TestNewsDownloader_TopHeadlinesDropsArticlesBeforeWatermark(t *testing.T) {
+	firstRun := createMockNewsAPIResponse()
+
+	// Top-headlines ignores from and returns the first run's articles again
+	secondRun := createMockNewsAPIResponse()
+	secondRun.Articles = append(secondRun.Articles, Article{
+		Title:       "Newer article",
+		URL:         "http://example.com/article3",
+		PublishedAt: time.Date(2023, 10, 27, 12, 0, 0, 0, time.UTC),
+	})
+	secondRun.TotalResults = 3
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, firstRun)},
+		{status: http.StatusOK, body: mustMarshalResponse(t, secondRun)},
+		{status: http.StatusOK, body: mustMarshalResponse(t, secondRun)},
+	}}
+	downloader, publisher := newTestDownloader(t, httpClient)
+
+	req := NewDownloadRequest("test-key", "us")
+	if _, err := downloader.DownloadAllNewsToFile(context.Background(), req); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error on second run, got: %v", err)
+	}
+	if strings.Contains(httpClient.urls[1], "from=") {
+		t.Errorf("Expected top-headlines to be requested without from, got %s", httpClient.urls[1])
+	}
+
+	if len(result.FilePaths) != 1 {
+		t.Fatalf("Expected 1 saved page, got %v", result.FilePaths)
+	}
+	data, err := ioutil.ReadFile(result.FilePaths[0])
+	if err != nil {
+		t.Fatalf("Failed to read saved page: %v", err)
+	}
+	var saved NewsAPIResponse
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Failed to parse saved page: %v", err)
+	}
+	if len(saved.Articles) != 1 || saved.Articles[0].Title != "Newer article" {
+		t.Errorf("Expected only the article newer than the watermark, got %+v", saved.Articles)
+	}
+
+	// Nothing is new on a third run, so nothing is saved or published
+	result, err = downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error on third run, got: %v", err)
+	}
+	if len(result.FilePaths) != 0 || len(publisher.messages) != 2 {
+		t.Errorf("Expected no new pages, got %v and %d messages in total", result.FilePaths, len(publisher.messages))
+	}
+}
+
+func TestNewsDownloader_KeepsWatermarkAfterFailedPages(t *testing.T) {
+	httpClient := &pagedHTTPClient{total: 6, failPage: 2}
+	downloader, _ := newTestDownloader(t, httpClient)
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("Expected an error for page 2, got %v", result.Errors)
+	}
+
+	// Page 3 was saved, but page 2's articles would fall behind a watermark at its newest
+	if newest := time.Date(2024, 1, 15, 0, 3, 1, 0, time.UTC); !result.MaxPublishedAt.Equal(newest) {
+		t.Errorf("Expected newest saved article at %v, got %v", newest, result.MaxPublishedAt)
+	}
+	watermark, err := LoadWatermark(WatermarkPath(downloader.config.OutputDir, req.WatermarkKey()))
+	if err != nil || watermark != nil {
+		t.Errorf("Expected no watermark after a run with failed pages, got %+v, %v", watermark, err)
+	}
+}
+
+func TestNewsDownloader_FailsFastOnceDailyBudgetIsSpent(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.RequestsPerSecond = 0
+	cfg.RequestsPerDay = 1
+
+	httpClient := &pagedHTTPClient{total: 6}
+	downloader := NewNewsDownloader(NewNewsAPIClientWithHTTPClient(cfg, httpClient), nil, cfg)
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+
+	done := make(chan struct{})
+	var result *DownloadResult
+	var err error
+	go func() {
+		defer close(done)
+		result, err = downloader.DownloadAllNewsToFile(context.Background(), req)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the download to give up once the budget was spent, not wait for it to reset")
+	}
+
+	var budgetErr *BudgetExhaustedError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("Expected a BudgetExhaustedError, got %v", err)
+	}
+	if !result.ReportsError(err) {
+		t.Errorf("Expected the result to report the spent budget, got %v", result.Errors)
+	}
+	if result.PagesDownloaded != 1 {
+		t.Errorf("Expected the first page within budget to be saved, got %d", result.PagesDownloaded)
+	}
+}
+
+// pagedHTTPClient serves numbered pages of a fixed-size result set. Later pages
+// answer faster, so concurrent fetches finish out of order.
+type pagedHTTPClient struct {
+	mutex     sync.Mutex
+	total     int
+	failPage  int
+	inFlight  int
+	maxActive int
+	onRequest func(page int)
+}
+
+func (p *pagedHTTPClient) Get(url string) (*http.Response, error) {
+	return p.GetWithContext(context.Background(), url)
+}
+
+func (p *pagedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return p.GetWithContext(req.Context(), req.URL.String())
+}
+
+func (p *pagedHTTPClient) GetWithContext(ctx context.Context, rawURL string) (*http.Response, error) {
+	parsed, _ := url.Parse(rawURL)
+	page, _ := strconv.Atoi(parsed.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(parsed.Query().Get("pageSize"))
+
+	p.mutex.Lock()
+	p.inFlight++
+	if p.inFlight > p.maxActive {
+		p.maxActive = p.inFlight
+	}
+	p.mutex.Unlock()
+	defer func() {
+		p.mutex.Lock()
+		p.inFlight--
+		p.mutex.Unlock()
+	}()
+
+	if p.onRequest != nil {
+		p.onRequest(page)
+	}
+	time.Sleep(time.Duration(10-page) * 5 * time.Millisecond)
+
+	if page == p.failPage {
+		return nil, errors.New("connection reset")
+	}
+
+	resp := &NewsAPIResponse{Status: "ok", TotalResults: p.total}
+	for i := 0; i < pageSize; i++ {
+		resp.Articles = append(resp.Articles, Article{
+			Title:       fmt.Sprintf("Page %d article %d", page, i),
+			URL:         fmt.Sprintf("https://example.com/%d/%d", page, i),
+			PublishedAt: time.Date(2024, 1, 15, 0, page, i, 0, time.UTC),
+		})
+	}
+	body, _ := json.Marshal(resp)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader(string(body))),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func (p *pagedHTTPClient) maxConcurrent() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.maxActive
+}
+
+func TestNewsDownloader_FetchesPagesConcurrentlyInOrder(t *testing.T) {
+	httpClient := &pagedHTTPClient{total: 10}
+	downloader, publisher := newTestDownloader(t, httpClient)
+	downloader.config.Concurrency = 3
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.PagesDownloaded != 5 || result.TotalAttempts != 5 {
+		t.Errorf("Expected 5 pages in 5 attempts, got %d pages in %d attempts", result.PagesDownloaded, result.TotalAttempts)
+	}
+
+	if active := httpClient.maxConcurrent(); active < 2 || active > 3 {
+		t.Errorf("Expected between 2 and 3 concurrent requests, got %d", active)
+	}
+
+	// Files are written and published in page order even though later pages finish first
+	if len(publisher.messages) != 5 {
+		t.Fatalf("Expected 5 published pages, got %d", len(publisher.messages))
+	}
+	for i, path := range result.FilePaths {
+		if !strings.HasSuffix(path, fmt.Sprintf("_page%d.json", i+1)) || publisher.messages[i] != path {
+			t.Errorf("Expected page %d at position %d, got file %s and message %s", i+1, i, path, publisher.messages[i])
+		}
+	}
+}
+
+func TestNewsDownloader_ConcurrentPageErrorsAreAggregated(t *testing.T) {
+	httpClient := &pagedHTTPClient{total: 10, failPage: 3}
+	downloader, _ := newTestDownloader(t, httpClient)
+	downloader.config.Concurrency = 4
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if result.PagesDownloaded != 4 {
+		t.Errorf("Expected the 4 other pages to be saved, got %d", result.PagesDownloaded)
+	}
+
+	if len(result.Errors) != 1 || !strings.HasPrefix(result.Errors[0].Error(), "page 3:") {
+		t.Errorf("Expected a single error for page 3, got %v", result.Errors)
+	}
+}
+
+func TestNewsDownloader_ConcurrentStopKeepsCheckpointContiguous(t *testing.T) {
+	stop := make(chan struct{})
+	var once sync.Once
+	httpClient := &pagedHTTPClient{total: 20, onRequest: func(page int) {
+		if page == 2 {
+			once.Do(func() { close(stop) })
+		}
+	}}
+	downloader, _ := newTestDownloader(t, httpClient)
+	downloader.config.Concurrency = 2
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	result, err := downloader.DownloadAllNewsToFile(WithStop(context.Background(), stop), req)
+	if !errors.Is(err, ErrStopped) {
+		t.Fatalf("Expected ErrStopped, got %v", err)
+	}
+
+	if len(result.FilePaths) < 2 || len(result.FilePaths) >= 10 {
+		t.Errorf("Expected the pages in flight to be saved and the rest skipped, got %d files", len(result.FilePaths))
+	}
+
+	checkpoint, err := LoadCheckpoint(CheckpointPath(downloader.config.OutputDir, req.Fingerprint()))
+	if err != nil || checkpoint == nil || checkpoint.LastCompletedPage != len(result.FilePaths) {
+		t.Errorf("Expected a checkpoint at page %d, got %+v, %v", len(result.FilePaths), checkpoint, err)
+	}
+}
+
+func TestNewsDownloader_ResumeRetriesFailedPages(t *testing.T) {
+	var (
+		mutex     sync.Mutex
+		requested []int
+	)
+	httpClient := &pagedHTTPClient{total: 6, failPage: 2, onRequest: func(page int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		requested = append(requested, page)
+	}}
+	downloader, _ := newTestDownloader(t, httpClient)
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	checkpointPath := CheckpointPath(downloader.config.OutputDir, req.Fingerprint())
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if result.PagesDownloaded != 2 {
+		t.Errorf("Expected pages 1 and 3 to be saved, got %d pages", result.PagesDownloaded)
+	}
+
+	// The skipped page stays in the checkpoint even though later pages completed
+	checkpoint, err := LoadCheckpoint(checkpointPath)
+	if err != nil || checkpoint == nil {
+		t.Fatalf("Expected a checkpoint after a run with a failed page, got %+v, %v", checkpoint, err)
+	}
+	if checkpoint.LastCompletedPage != 3 || len(checkpoint.FailedPages) != 1 || checkpoint.FailedPages[0] != 2 {
+		t.Errorf("Expected page 2 to be recorded as failed, got %+v", checkpoint)
+	}
+
+	httpClient.failPage = 0
+	mutex.Lock()
+	requested = nil
+	mutex.Unlock()
+	result, err = downloader.ResumeDownload(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(requested) != 1 || requested[0] != 2 {
+		t.Errorf("Expected only page 2 to be fetched again, got %v", requested)
+	}
+	if result.PagesDownloaded != 3 || len(result.FilePaths) != 3 || !strings.HasSuffix(result.FilePaths[2], "_page2.json") {
+		t.Errorf("Expected the retried page to be merged into the result, got %v", result.FilePaths)
+	}
+	if checkpoint, _ := LoadCheckpoint(checkpointPath); checkpoint != nil {
+		t.Errorf("Expected checkpoint to be removed once every page is saved, got %+v", checkpoint)
+	}
+}
+
+func TestNewsDownloader_WritesPagesWithChecksums(t *testing.T) {
+	provider := &staticProvider{articles: outputTestPage().Articles}
+	downloader, publisher := newTestDownloader(t, nil)
+	downloader.provider = provider
+
+	req := &DownloadRequest{PageSize: 1, StartPage: 1, From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result.FilePaths) != 2 || len(publisher.messages) != 2 {
+		t.Fatalf("Expected 2 files published, got %v", result.FilePaths)
+	}
+
+	for i, path := range result.FilePaths {
+		if err := utils.VerifyChecksum(path); err != nil {
+			t.Errorf("Expected %s to match its sidecar, got %v", path, err)
+		}
+
+		checksum, _ := utils.FileChecksum(path)
+		if publisher.headers[i][HeaderSHA256] != checksum {
+			t.Errorf("Expected the message for %s to carry checksum %s, got %v", path, checksum, publisher.headers[i])
+		}
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(result.FilePaths[0]), "*.tmp")); len(matches) != 0 {
+		t.Errorf("Expected no temporary files left behind, got %v", matches)
+	}
+}
+
+// failingPageWriter writes part of a page, then fails as a full disk would
+type failingPageWriter struct{}
+
+func (failingPageWriter) Format() OutputFormat { return FormatJSON }
+func (failingPageWriter) Extension() string    { return "json" }
+
+func (failingPageWriter) WritePage(w io.Writer, resp *NewsAPIResponse) error {
+	io.WriteString(w, `{"status": "ok", "articles": [`)
+	return errors.New("no space left on device")
+}
+
+func TestNewsDownloader_FailedWritesLeaveNoFile(t *testing.T) {
+	provider := &staticProvider{articles: outputTestPage().Articles}
+	downloader, publisher := newTestDownloader(t, nil)
+	downloader.provider = provider
+	downloader.pageWriter = failingPageWriter{}
+
+	req := &DownloadRequest{PageSize: 1, StartPage: 1, From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected failed pages to be skipped, got: %v", err)
+	}
+
+	var fileErr *FileOperationError
+	if len(result.Errors) != 2 || !errors.As(result.Errors[0], &fileErr) {
+		t.Fatalf("Expected a write error for each page, got %v", result.Errors)
+	}
+	if len(result.FilePaths) != 0 || len(publisher.messages) != 0 {
+		t.Errorf("Expected nothing saved or published, got %v and %v", result.FilePaths, publisher.messages)
+	}
+
+	// Neither the partial page nor its temporary file is left in the dated page directories
+	if matches, _ := filepath.Glob(filepath.Join(downloader.config.OutputDir, "[0-9]*", "*", "*")); len(matches) != 0 {
+		t.Errorf("Unexpected files left behind: %v", matches)
+	}
+}
+
+// sidecarFailingStorage stores pages but fails to store their sidecars,
+// recording the order of its calls and whether pages were streamed
+type sidecarFailingStorage struct {
+	storage.Storage
+	calls    []string
+	buffered bool
+}
+
+func (s *sidecarFailingStorage) Put(ctx context.Context, key string, body io.Reader) error {
+	s.calls = append(s.calls, "put "+key)
+	if strings.HasSuffix(key, "."+utils.ChecksumExtension) {
+		return errors.New("connection reset by peer")
+	}
+	if _, ok := body.(*io.PipeReader); !ok {
+		s.buffered = true
+	}
+	return s.Storage.Put(ctx, key, body)
+}
+
+func (s *sidecarFailingStorage) Delete(ctx context.Context, key string) error {
+	s.calls = append(s.calls, "delete "+key)
+	return s.Storage.Delete(ctx, key)
+}
+
+func TestNewsDownloader_RemovesPageWithoutSidecar(t *testing.T) {
+	provider := &staticProvider{articles: outputTestPage().Articles[:1]}
+	downloader, publisher := newTestDownloader(t, nil)
+	downloader.provider = provider
+	pageStorage := &sidecarFailingStorage{Storage: storage.NewLocal(downloader.config.OutputDir)}
+	downloader.SetStorage(pageStorage)
+
+	req := &DownloadRequest{PageSize: 1, StartPage: 1, From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the failed page to be skipped, got: %v", err)
+	}
+	if len(result.Errors) != 1 || len(publisher.messages) != 0 {
+		t.Fatalf("Expected one write error and nothing published, got %v and %v", result.Errors, publisher.messages)
+	}
+	if pageStorage.buffered {
+		t.Error("Expected the page to be streamed into the storage")
+	}
+
+	// The page is stored first, then removed again once its sidecar fails
+	if len(pageStorage.calls) != 3 ||
+		!strings.HasSuffix(pageStorage.calls[0], ".json") ||
+		pageStorage.calls[1] != pageStorage.calls[0]+"."+utils.ChecksumExtension ||
+		pageStorage.calls[2] != "delete "+strings.TrimPrefix(pageStorage.calls[0], "put ") {
+		t.Errorf("Expected the page put, the sidecar put and the page delete, got %v", pageStorage.calls)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(downloader.config.OutputDir, "[0-9]*", "*", "*")); len(matches) != 0 {
+		t.Errorf("Unexpected files left behind: %v", matches)
+	}
+}
+
+func TestNewsDownloader_SavesToS3Storage(t *testing.T) {
+	server := fakes3.NewTestServer(fakes3.Options{AccessKeyID: "minio"})
+	defer server.Close()
+
+	pageStorage, err := storage.NewS3(storage.S3Options{
+		Endpoint:        server.URL,
+		Bucket:          "news-pages",
+		Prefix:          "raw",
+		PathStyle:       true,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := &staticProvider{articles: outputTestPage().Articles}
+	downloader, publisher := newTestDownloader(t, nil)
+	downloader.provider = provider
+	downloader.SetStorage(pageStorage)
+
+	req := &DownloadRequest{PageSize: 1, StartPage: 1, From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Storage URIs are published instead of local paths
+	if len(publisher.messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %v", publisher.messages)
+	}
+	for i, uri := range publisher.messages {
+		if !strings.HasPrefix(uri, "s3://news-pages/raw/") || uri != result.FilePaths[i] {
+			t.Errorf("Expected an s3:// URI, got %s", uri)
+		}
+
+		key := strings.TrimPrefix(uri, "s3://news-pages/")
+		data, ok := server.API.Object("news-pages", key)
+		if !ok {
+			t.Fatalf("Expected %s in the bucket, got %v", key, server.API.Keys("news-pages"))
+		}
+		sidecar, _ := server.API.Object("news-pages", key+"."+utils.ChecksumExtension)
+		sum := sha256.Sum256(data)
+		if !strings.HasPrefix(string(sidecar), hex.EncodeToString(sum[:])+"  ") {
+			t.Errorf("Expected the sidecar of %s to hold its checksum, got %q", key, sidecar)
+		}
+	}
+}
+
+func TestNewsDownloader_PathTemplateKeepsRunsApart(t *testing.T) {
+	downloader, _ := newTestDownloader(t, nil)
+	downloader.provider = &staticProvider{articles: outputTestPage().Articles}
+	downloader.config.OutputPathTemplate = "{provider}/{country|query-slug}/{yyyy}/{run_id}_p{page}.{ext}"
+	downloader.paths = pathGeneratorFor(downloader.config)
+
+	// Two runs of the same query in the same second write different files
+	var paths []string
+	for run := 0; run < 2; run++ {
+		req := &DownloadRequest{Query: "Climate Change", PageSize: 2, StartPage: 1, From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+		result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Run %d: expected no error, got: %v", run+1, err)
+		}
+		if len(result.FilePaths) != 1 {
+			t.Fatalf("Run %d: expected 1 file, got %v", run+1, result.FilePaths)
+		}
+		paths = append(paths, result.FilePaths[0])
+	}
+
+	prefix := filepath.Join(downloader.config.OutputDir, "static", "climate-change") + string(filepath.Separator)
+	for _, path := range paths {
+		if !strings.HasPrefix(path, prefix) || !strings.HasSuffix(path, "_p1.json") {
+			t.Errorf("Expected %s to follow the template", path)
+		}
+	}
+	if paths[0] == paths[1] {
+		t.Errorf("Expected different files for the two runs, got %s twice", paths[0])
+	}
+}
+
+func TestNewsDownloader_RunAcrossMidnightStaysInOnePartition(t *testing.T) {
+	// The clock crosses midnight while the run fetches its second page
+	clock := utils.NewMockTimeProvider(time.Date(2024, 1, 15, 23, 59, 59, 0, time.UTC))
+	httpClient := &pagedHTTPClient{total: 6, onRequest: func(page int) {
+		if page == 2 {
+			clock.SetTime(time.Date(2024, 1, 16, 0, 0, 1, 0, time.UTC))
+		}
+	}}
+	downloader, _ := newTestDownloader(t, httpClient)
+	template, err := utils.ParsePathTemplate("{date}/{hh}/{run_id}_p{page}.{ext}", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloader.paths = utils.NewTemplateFilePathGenerator(clock, template)
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(result.FilePaths) != 3 {
+		t.Fatalf("Expected 3 files, got %v", result.FilePaths)
+	}
+
+	// Every page is filed under the run's start in UTC, like its run ID
+	started := result.StartTime.UTC()
+	dir := filepath.Join(downloader.config.OutputDir, "date="+started.Format("2006-01-02"), "hour="+started.Format("15"))
+	for _, path := range result.FilePaths {
+		if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), started.Format("20060102-150405")) {
+			t.Errorf("Expected %s under %s, named after the run's start", path, dir)
+		}
+	}
+}
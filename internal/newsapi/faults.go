@@ -0,0 +1,227 @@
+package newsapi
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FaultKind identifies a failure a FaultInjectingHTTPClient can inject
+type FaultKind string
+
+const (
+	// FaultLatency delays the request by Fault.Latency, then sends it
+	FaultLatency FaultKind = "latency"
+	// FaultTimeout waits Fault.Latency, then fails with a network timeout
+	FaultTimeout FaultKind = "timeout"
+	// FaultConnectionError fails with a connection reset before any response
+	FaultConnectionError FaultKind = "connection_error"
+	// FaultTruncatedBody sends the request, then cuts the response body off halfway
+	FaultTruncatedBody FaultKind = "truncated_body"
+	// FaultMalformedJSON answers 200 with a body that is not valid JSON
+	FaultMalformedJSON FaultKind = "malformed_json"
+	// FaultRateLimited answers 429 rateLimited
+	FaultRateLimited FaultKind = "rate_limited"
+	// FaultServerError answers 500 unexpectedError
+	FaultServerError FaultKind = "server_error"
+)
+
+// Fault describes one failure to inject and when
+type Fault struct {
+	Kind FaultKind
+	// Probability is the chance the fault fires on a matching request; zero means always
+	Probability float64
+	// Pages limits the fault to requests for these page numbers; empty matches every request
+	Pages []int
+	// Times caps how often the fault fires; zero means no limit
+	Times int
+	// Latency is the delay of FaultLatency and how long FaultTimeout hangs before failing
+	Latency time.Duration
+}
+
+// FaultInjectingHTTPClient wraps another HTTPClient and makes some of its requests
+// fail, to test how downloads behave under partial failure. For each request the
+// faults are considered in order and the first one that fires is injected;
+// requests no fault fires for are passed through unchanged. Probabilities are
+// drawn from a seeded source so runs can be reproduced.
+type FaultInjectingHTTPClient struct {
+	inner    HTTPClient
+	faults   []Fault
+	fired    []int
+	rand     *rand.Rand
+	injected map[FaultKind]int
+	mutex    sync.Mutex
+}
+
+// NewFaultInjectingHTTPClient injects faults into the requests sent through inner,
+// drawing probabilities from seed
+func NewFaultInjectingHTTPClient(inner HTTPClient, seed int64, faults ...Fault) *FaultInjectingHTTPClient {
+	return &FaultInjectingHTTPClient{
+		inner:    inner,
+		faults:   faults,
+		fired:    make([]int, len(faults)),
+		rand:     rand.New(rand.NewSource(seed)),
+		injected: make(map[FaultKind]int),
+	}
+}
+
+// Get implements the HTTPClient interface.
+func (f *FaultInjectingHTTPClient) Get(url string) (*http.Response, error) {
+	return f.GetWithContext(context.Background(), url)
+}
+
+// GetWithContext implements the HTTPClient interface.
+func (f *FaultInjectingHTTPClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return f.Do(req)
+}
+
+// Do sends a prepared request.
+func (f *FaultInjectingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	fault := f.pick(req)
+	if fault == nil {
+		return doRequest(f.inner, req)
+	}
+
+	switch fault.Kind {
+	case FaultLatency:
+		if err := sleepContext(req.Context(), fault.Latency); err != nil {
+			return nil, err
+		}
+		return doRequest(f.inner, req)
+
+	case FaultTimeout:
+		if err := sleepContext(req.Context(), fault.Latency); err != nil {
+			return nil, err
+		}
+		return nil, &url.Error{Op: req.Method, URL: Redact(req.URL.String()), Err: injectedTimeoutError{}}
+
+	case FaultConnectionError:
+		return nil, &url.Error{Op: req.Method, URL: Redact(req.URL.String()), Err: &net.OpError{
+			Op:  "read",
+			Net: "tcp",
+			Err: os.NewSyscallError("read", syscall.ECONNRESET),
+		}}
+
+	case FaultTruncatedBody:
+		resp, err := doRequest(f.inner, req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body[:len(body)/2]), errorReader{io.ErrUnexpectedEOF}))
+		resp.ContentLength = int64(len(body))
+		return resp, nil
+
+	case FaultMalformedJSON:
+		return injectedResponse(req, http.StatusOK, `{"status": "ok", "totalResults": 3, "articles": [{"title": `), nil
+
+	case FaultRateLimited:
+		return injectedResponse(req, http.StatusTooManyRequests,
+			`{"status": "error", "code": "rateLimited", "message": "Injected fault: too many requests."}`), nil
+
+	case FaultServerError:
+		return injectedResponse(req, http.StatusInternalServerError,
+			`{"status": "error", "code": "unexpectedError", "message": "Injected fault: something went wrong."}`), nil
+	}
+
+	return nil, fmt.Errorf("unknown fault kind %q", fault.Kind)
+}
+
+// Injected returns how many faults of each kind have been injected
+func (f *FaultInjectingHTTPClient) Injected() map[FaultKind]int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	injected := make(map[FaultKind]int, len(f.injected))
+	for kind, count := range f.injected {
+		injected[kind] = count
+	}
+	return injected
+}
+
+// pick returns the fault to inject into req, or nil to send it unchanged
+func (f *FaultInjectingHTTPClient) pick(req *http.Request) *Fault {
+	page, _ := strconv.Atoi(req.URL.Query().Get("page"))
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for i := range f.faults {
+		fault := &f.faults[i]
+		if fault.Times > 0 && f.fired[i] >= fault.Times {
+			continue
+		}
+		if len(fault.Pages) > 0 && !containsPage(fault.Pages, page) {
+			continue
+		}
+		if fault.Probability > 0 && f.rand.Float64() >= fault.Probability {
+			continue
+		}
+
+		f.fired[i]++
+		f.injected[fault.Kind]++
+		return fault
+	}
+	return nil
+}
+
+// containsPage reports whether page is one of pages
+func containsPage(pages []int, page int) bool {
+	for _, candidate := range pages {
+		if candidate == page {
+			return true
+		}
+	}
+	return false
+}
+
+// injectedResponse builds a response with status and a JSON body
+func injectedResponse(req *http.Request, status int, body string) *http.Response {
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json; charset=utf-8")
+
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// injectedTimeoutError is the net.Error returned by FaultTimeout
+type injectedTimeoutError struct{}
+
+func (injectedTimeoutError) Error() string   { return "injected fault: i/o timeout" }
+func (injectedTimeoutError) Timeout() bool   { return true }
+func (injectedTimeoutError) Temporary() bool { return true }
+
+// errorReader fails every read with err
+type errorReader struct {
+	err error
+}
+
+func (r errorReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
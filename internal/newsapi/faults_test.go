@@ -0,0 +1,220 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newFaultyDownloader builds a downloader over 10 articles in pages of 2, with
+// faults injected into its requests
+func newFaultyDownloader(t *testing.T, faults ...Fault) (*NewsDownloader, *FaultInjectingHTTPClient, *DownloadRequest) {
+	t.Helper()
+
+	injector := NewFaultInjectingHTTPClient(&pagedHTTPClient{total: 10}, 1, faults...)
+	downloader, _ := newTestDownloader(t, injector)
+	downloader.config.DefaultRateLimitDelaySeconds = 0
+
+	req := NewDownloadRequest("test-key", "us")
+	req.PageSize = 2
+	req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	return downloader, injector, req
+}
+
+func TestFaultInjectingHTTPClient_Faults(t *testing.T) {
+	inner := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: `{"status": "ok", "totalResults": 0, "articles": []}`},
+	}}
+	injector := NewFaultInjectingHTTPClient(inner, 1,
+		Fault{Kind: FaultServerError, Pages: []int{2}, Times: 1},
+		Fault{Kind: FaultTruncatedBody, Pages: []int{3}},
+		Fault{Kind: FaultConnectionError, Pages: []int{4}},
+		Fault{Kind: FaultTimeout, Pages: []int{5}, Latency: time.Millisecond},
+	)
+
+	status := func(page string) (int, string, error) {
+		resp, err := injector.Get("https://newsapi.org/v2/everything?q=go&page=" + page)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body), err
+	}
+
+	if code, _, err := status("1"); code != http.StatusOK || err != nil {
+		t.Errorf("Expected page 1 to pass through, got %d, %v", code, err)
+	}
+	if code, _, _ := status("2"); code != http.StatusInternalServerError {
+		t.Errorf("Expected a 500 for page 2, got %d", code)
+	}
+	if code, _, _ := status("2"); code != http.StatusOK {
+		t.Errorf("Expected page 2 to recover once Times is used up, got %d", code)
+	}
+
+	if _, body, err := status("3"); !IsRetryable(err) || strings.HasSuffix(body, "}") {
+		t.Errorf("Expected a retryable cut-off body for page 3, got %q, %v", body, err)
+	}
+	if _, _, err := status("4"); !IsRetryable(err) {
+		t.Errorf("Expected a retryable connection error for page 4, got %v", err)
+	}
+	if _, _, err := status("5"); !IsRetryable(err) || !strings.Contains(err.Error(), "timeout") {
+		t.Errorf("Expected a retryable timeout for page 5, got %v", err)
+	}
+
+	injected := injector.Injected()
+	if injected[FaultServerError] != 1 || injected[FaultTruncatedBody] != 1 || injected[FaultConnectionError] != 1 || injected[FaultTimeout] != 1 {
+		t.Errorf("Unexpected injected counts: %v", injected)
+	}
+}
+
+func TestFaultInjectingHTTPClient_ProbabilityIsSeeded(t *testing.T) {
+	fired := func(seed int64) []bool {
+		injector := NewFaultInjectingHTTPClient(&sequenceHTTPClient{responses: []stubResponse{{status: http.StatusOK}}}, seed,
+			Fault{Kind: FaultServerError, Probability: 0.5})
+		var outcomes []bool
+		for i := 0; i < 50; i++ {
+			resp, _ := injector.Get("https://newsapi.org/v2/everything?q=go")
+			outcomes = append(outcomes, resp.StatusCode == http.StatusInternalServerError)
+		}
+		return outcomes
+	}
+
+	first, second := fired(42), fired(42)
+	count := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Request %d differs between runs with the same seed", i+1)
+		}
+		if first[i] {
+			count++
+		}
+	}
+	if count < 10 || count > 40 {
+		t.Errorf("Expected about half of 50 requests to fail, got %d", count)
+	}
+}
+
+func TestNewsDownloader_RetriesInjectedTransientFaults(t *testing.T) {
+	downloader, injector, req := newFaultyDownloader(t,
+		Fault{Kind: FaultServerError, Pages: []int{1}, Times: 1},
+		Fault{Kind: FaultConnectionError, Pages: []int{2}, Times: 2},
+		Fault{Kind: FaultTimeout, Pages: []int{3}, Times: 1, Latency: time.Millisecond},
+		Fault{Kind: FaultTruncatedBody, Pages: []int{4}, Times: 1},
+		Fault{Kind: FaultLatency, Pages: []int{5}, Latency: 20 * time.Millisecond},
+	)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the download to recover, got: %v", err)
+	}
+
+	if result.PagesDownloaded != 5 || len(result.Errors) != 0 {
+		t.Errorf("Expected all 5 pages without errors, got %d pages and %v", result.PagesDownloaded, result.Errors)
+	}
+
+	expected := map[int]int{1: 2, 2: 3, 3: 2, 4: 2, 5: 1}
+	for page, attempts := range expected {
+		if result.PageAttempts[page] != attempts {
+			t.Errorf("Expected %d attempts for page %d, got %d", attempts, page, result.PageAttempts[page])
+		}
+	}
+	if injector.Injected()[FaultLatency] != 1 {
+		t.Errorf("Expected page 5 to be delayed once, got %v", injector.Injected())
+	}
+}
+
+func TestNewsDownloader_WaitsOutInjectedRateLimits(t *testing.T) {
+	downloader, _, req := newFaultyDownloader(t,
+		Fault{Kind: FaultRateLimited, Pages: []int{3}, Times: 2},
+	)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the download to wait out the rate limit, got: %v", err)
+	}
+
+	if result.PagesDownloaded != 5 || result.PageAttempts[3] != 3 {
+		t.Errorf("Expected 5 pages with 3 attempts at page 3, got %d pages and %v", result.PagesDownloaded, result.PageAttempts)
+	}
+}
+
+func TestNewsDownloader_SkipsPagesWithMalformedBodies(t *testing.T) {
+	downloader, _, req := newFaultyDownloader(t,
+		Fault{Kind: FaultMalformedJSON, Pages: []int{2}},
+		Fault{Kind: FaultConnectionError, Pages: []int{4}},
+	)
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected bad pages to be skipped, got: %v", err)
+	}
+
+	if result.PagesDownloaded != 3 || len(result.Errors) != 2 {
+		t.Fatalf("Expected 3 saved pages and 2 errors, got %d pages and %v", result.PagesDownloaded, result.Errors)
+	}
+	if !strings.HasPrefix(result.Errors[0].Error(), "page 2:") || !strings.HasPrefix(result.Errors[1].Error(), "page 4:") {
+		t.Errorf("Expected errors for pages 2 and 4 in order, got %v", result.Errors)
+	}
+
+	// Malformed JSON is not retried; a dropped connection is, until the budget runs out
+	if result.PageAttempts[2] != 1 || result.PageAttempts[4] != downloader.config.MaxRetries+1 {
+		t.Errorf("Unexpected attempts: %v", result.PageAttempts)
+	}
+}
+
+func TestNewsDownloader_FailsOnPersistentServerErrors(t *testing.T) {
+	downloader, _, req := newFaultyDownloader(t,
+		Fault{Kind: FaultServerError, Pages: []int{3}},
+	)
+	downloader.config.Concurrency = 1
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	var apiErr *NewsAPIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Expected the download to fail with a 500, got %v", err)
+	}
+
+	if result.PagesDownloaded != 2 || result.PageAttempts[3] != downloader.config.MaxRetries+1 {
+		t.Errorf("Expected 2 pages saved and page 3 retried to the limit, got %d pages and %v", result.PagesDownloaded, result.PageAttempts)
+	}
+
+	// The pages before the failure can be resumed from
+	checkpoint, err := LoadCheckpoint(CheckpointPath(downloader.config.OutputDir, req.Fingerprint()))
+	if err != nil || checkpoint == nil || checkpoint.LastCompletedPage != 2 {
+		t.Errorf("Expected a checkpoint at page 2, got %+v, %v", checkpoint, err)
+	}
+}
+
+func TestNewsDownloader_SurvivesRandomFaults(t *testing.T) {
+	for seed := int64(1); seed <= 5; seed++ {
+		injector := NewFaultInjectingHTTPClient(&pagedHTTPClient{total: 20}, seed,
+			Fault{Kind: FaultLatency, Probability: 0.3, Latency: 5 * time.Millisecond},
+			Fault{Kind: FaultConnectionError, Probability: 0.2},
+			Fault{Kind: FaultTimeout, Probability: 0.1},
+			Fault{Kind: FaultTruncatedBody, Probability: 0.1},
+			Fault{Kind: FaultMalformedJSON, Probability: 0.05},
+		)
+		downloader, publisher := newTestDownloader(t, injector)
+
+		req := NewDownloadRequest("test-key", "us")
+		req.PageSize = 2
+		req.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+		result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Seed %d: expected transport faults never to fail the download, got: %v", seed, err)
+		}
+
+		// Every page is either saved and published or reported as an error
+		if result.PagesDownloaded+len(result.Errors) != 10 || len(publisher.messages) != result.PagesDownloaded {
+			t.Errorf("Seed %d: %d pages saved, %d published and %d errors do not add up to 10 pages",
+				seed, result.PagesDownloaded, len(publisher.messages), len(result.Errors))
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package newsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CacheHeader is set on responses served from a CachingHTTPClient's cache
+const CacheHeader = "X-From-Cache"
+
+// cacheEntry is a stored response
+type cacheEntry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	StoredAt   time.Time   `json:"stored_at"`
+}
+
+// CachingHTTPClient is an HTTPClient that keeps successful GET responses in a
+// local directory, so repeated runs during development replay them instead of
+// spending API quota. Entries are keyed by the redacted URL; the API key travels
+// in a header and is never part of the key or the stored entry.
+type CachingHTTPClient struct {
+	inner    HTTPClient
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	now      func() time.Time
+}
+
+// NewCachingHTTPClient wraps inner with a cache in dir. Entries older than ttl
+// are refetched; a ttl of zero keeps them until they are deleted.
+func NewCachingHTTPClient(inner HTTPClient, dir string, ttl time.Duration) *CachingHTTPClient {
+	return &CachingHTTPClient{
+		inner: inner,
+		dir:   dir,
+		ttl:   ttl,
+		now:   time.Now,
+	}
+}
+
+// SetMaxResponseBytes caps the bodies the cache reads, stores and replays, like
+// Config.MaxResponseBytes caps responses from the API; zero or less disables the cap
+func (c *CachingHTTPClient) SetMaxResponseBytes(limit int64) {
+	c.maxBytes = limit
+}
+
+// Get implements the HTTPClient interface.
+func (c *CachingHTTPClient) Get(url string) (*http.Response, error) {
+	return c.GetWithContext(context.Background(), url)
+}
+
+// GetWithContext implements the HTTPClient interface.
+func (c *CachingHTTPClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// Do sends a prepared request. Only GET requests are cached, and
+// only responses with status 200 are stored.
+func (c *CachingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return doRequest(c.inner, req)
+	}
+
+	path := c.entryPath(req.URL.String())
+	if entry := c.load(path); entry != nil {
+		return entry.response(req, c.maxBytes), nil
+	}
+
+	resp, err := doRequest(c.inner, req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := ioutil.ReadAll(newResponseBody(resp.Body, Redact(req.URL.String()), c.maxBytes))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	entry := &cacheEntry{
+		URL:        Redact(req.URL.String()),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		StoredAt:   c.now(),
+	}
+	if err := c.store(path, entry); err != nil {
+		// The response is still good, it just will not be replayed
+		log.Printf("Failed to cache response: %v", err)
+	}
+
+	return resp, nil
+}
+
+// Cached reports whether req would be answered from the cache
+func (c *CachingHTTPClient) Cached(req *http.Request) bool {
+	return req.Method == http.MethodGet && c.load(c.entryPath(req.URL.String())) != nil
+}
+
+// entryPath returns the file holding the entry for rawURL
+func (c *CachingHTTPClient) entryPath(rawURL string) string {
+	sum := sha256.Sum256([]byte(Redact(rawURL)))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:16])+".json")
+}
+
+// load returns the entry at path, or nil if there is none or it has expired
+func (c *CachingHTTPClient) load(path string) *cacheEntry {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		log.Printf("Ignoring unreadable cache entry: %v", &FileOperationError{Operation: "unmarshal JSON", FilePath: path, Cause: err})
+		return nil
+	}
+
+	if c.ttl > 0 && c.now().Sub(entry.StoredAt) > c.ttl {
+		return nil
+	}
+
+	return &entry
+}
+
+// store writes entry to path, replacing any previous version atomically
+func (c *CachingHTTPClient) store(path string, entry *cacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return &FileOperationError{Operation: "create directory", FilePath: c.dir, Cause: err}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return &FileOperationError{Operation: "marshal JSON", FilePath: path, Cause: err}
+	}
+
+	tmpPath := path + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return &FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err}
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return &FileOperationError{Operation: "rename file", FilePath: path, Cause: err}
+	}
+
+	return nil
+}
+
+// response rebuilds the stored response, its body capped at maxBytes like a
+// live one. Rate limit headers are dropped, since they describe the quota at the
+// time the entry was stored.
+func (e *cacheEntry) response(req *http.Request, maxBytes int64) *http.Response {
+	header := make(http.Header)
+	for name, values := range e.Header {
+		if !strings.HasPrefix(http.CanonicalHeaderKey(name), "X-Ratelimit-") {
+			header[name] = values
+		}
+	}
+	header.Set(CacheHeader, "1")
+
+	return &http.Response{
+		Status:        http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Header:        header,
+		Body:          newResponseBody(ioutil.NopCloser(bytes.NewReader(e.Body)), e.URL, maxBytes),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
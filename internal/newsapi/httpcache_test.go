@@ -0,0 +1,151 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/config"
+)
+
+func TestCachingHTTPClient_ReplaysResponses(t *testing.T) {
+	inner := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: `{"status":"ok","totalResults":0,"articles":[]}`},
+	}}
+	cache := NewCachingHTTPClient(inner, t.TempDir(), time.Hour)
+
+	for i := 0; i < 2; i++ {
+		resp, err := cache.Get("https://newsapi.org/v2/everything?q=go")
+		if err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if !strings.Contains(string(body), `"status":"ok"`) {
+			t.Errorf("Unexpected body on call %d: %s", i+1, body)
+		}
+		if cached := resp.Header.Get(CacheHeader) != ""; cached != (i == 1) {
+			t.Errorf("Call %d: expected cached=%v, got %v", i+1, i == 1, cached)
+		}
+	}
+
+	if inner.calls() != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", inner.calls())
+	}
+
+	// A different URL is a different entry
+	cache.Get("https://newsapi.org/v2/everything?q=rust")
+	if inner.calls() != 2 {
+		t.Errorf("Expected 2 upstream requests, got %d", inner.calls())
+	}
+}
+
+func TestCachingHTTPClient_ExpiresEntries(t *testing.T) {
+	inner := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: `{"status":"ok"}`},
+	}}
+	cache := NewCachingHTTPClient(inner, t.TempDir(), time.Minute)
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	cache.Get("https://newsapi.org/v2/everything?q=go")
+	now = now.Add(30 * time.Second)
+	cache.Get("https://newsapi.org/v2/everything?q=go")
+	if inner.calls() != 1 {
+		t.Errorf("Expected a fresh entry to be replayed, got %d upstream requests", inner.calls())
+	}
+
+	now = now.Add(time.Minute)
+	cache.Get("https://newsapi.org/v2/everything?q=go")
+	if inner.calls() != 2 {
+		t.Errorf("Expected an expired entry to be refetched, got %d upstream requests", inner.calls())
+	}
+}
+
+func TestCachingHTTPClient_CapsBodies(t *testing.T) {
+	body := `{"status":"ok","totalResults":0,"articles":[]}`
+	inner := &sequenceHTTPClient{responses: []stubResponse{{status: http.StatusOK, body: body}}}
+	dir := t.TempDir()
+
+	// An oversized response is refused and not stored
+	limited := NewCachingHTTPClient(inner, dir, time.Hour)
+	limited.SetMaxResponseBytes(int64(len(body) - 1))
+	var tooLarge *ResponseTooLargeError
+	if _, err := limited.Get("https://newsapi.org/v2/everything?q=go"); !errors.As(err, &tooLarge) {
+		t.Fatalf("Expected a ResponseTooLargeError, got %v", err)
+	}
+	if entries, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(entries) != 0 {
+		t.Errorf("Expected nothing cached, got %v", entries)
+	}
+
+	// An entry stored without a cap is still capped when it is replayed
+	if _, err := NewCachingHTTPClient(inner, dir, time.Hour).Get("https://newsapi.org/v2/everything?q=go"); err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	resp, err := limited.Get("https://newsapi.org/v2/everything?q=go")
+	if err != nil || resp.Header.Get(CacheHeader) == "" {
+		t.Fatalf("Expected the entry to be replayed, got %v", err)
+	}
+	defer resp.Body.Close()
+	if _, err := ioutil.ReadAll(resp.Body); !errors.As(err, &tooLarge) {
+		t.Errorf("Expected the replayed body to be capped, got %v", err)
+	}
+}
+
+func TestCachingHTTPClient_SkipsErrorsAndRedactsURLs(t *testing.T) {
+	dir := t.TempDir()
+	inner := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rateLimited"}`},
+		{status: http.StatusOK, body: `{"status":"ok"}`},
+	}}
+	cache := NewCachingHTTPClient(inner, dir, 0)
+
+	rawURL := "https://newsapi.org/v2/everything?q=go&apiKey=" + secretTestKey
+	for i := 0; i < 3; i++ {
+		resp, err := cache.Get(rawURL)
+		if err != nil {
+			t.Fatalf("Get() unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if inner.calls() != 2 {
+		t.Errorf("Expected the 429 to be retried upstream and the 200 replayed, got %d upstream requests", inner.calls())
+	}
+
+	entries, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 cache entry, got %d", len(entries))
+	}
+	data, _ := ioutil.ReadFile(entries[0])
+	if strings.Contains(string(data), secretTestKey) {
+		t.Errorf("Cache entry contains the API key: %s", data)
+	}
+}
+
+func TestNewsAPIClient_CacheHitsSkipRateLimiter(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.RequestsPerDay = 1
+
+	inner := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, createMockNewsAPIResponse())},
+	}}
+	client := NewNewsAPIClientWithHTTPClient(cfg, NewCachingHTTPClient(inner, t.TempDir(), time.Hour))
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest("test-key", "us"), 1); err != nil {
+			t.Fatalf("Fetch %d: expected cached responses to stay within budget, got %v", i+1, err)
+		}
+	}
+
+	if inner.calls() != 1 {
+		t.Errorf("Expected 1 upstream request, got %d", inner.calls())
+	}
+}
@@ -0,0 +1,233 @@
+package newsapi
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// CodeRateLimited is the NewsAPI error code for a key over its request quota
+	CodeRateLimited = "rateLimited"
+	// CodeAPIKeyExhausted is the NewsAPI error code for a key with no requests left
+	CodeAPIKeyExhausted = "apiKeyExhausted"
+	// CodeAPIKeysExhausted is returned once every key in a pool is exhausted
+	CodeAPIKeysExhausted = "apiKeysExhausted"
+
+	// defaultKeyExhaustedDelay is how long an exhausted key is rested when the
+	// response does not say when its quota resets
+	defaultKeyExhaustedDelay = 24 * time.Hour
+)
+
+// KeyUsage reports how one key of a KeyPool was used. The key itself is never
+// included, only a label safe to print.
+type KeyUsage struct {
+	Key            string    `json:"key"`
+	Requests       int       `json:"requests"`
+	RateLimited    int       `json:"rate_limited"`
+	Remaining      int       `json:"remaining"`
+	ExhaustedUntil time.Time `json:"exhausted_until,omitempty"`
+}
+
+// KeyUsageReporter is implemented by providers that can report per-key usage
+type KeyUsageReporter interface {
+	KeyUsage() []KeyUsage
+}
+
+// pooledKey is the state of one key in a KeyPool
+type pooledKey struct {
+	label          string
+	key            string
+	limiter        *RateLimiter
+	requests       int
+	rateLimited    int
+	exhaustedUntil time.Time
+}
+
+// KeyPool holds several NewsAPI keys and rotates to the next one when the
+// current key runs out of quota. Each key has its own RateLimiter.
+type KeyPool struct {
+	keys    []*pooledKey
+	current int
+	mutex   sync.Mutex
+}
+
+// NewKeyPool creates a pool of the given keys, used in order. Blank and
+// duplicate keys are ignored. Every key is registered for redaction.
+func NewKeyPool(keys ...string) *KeyPool {
+	return newKeyPool(func(string) *RateLimiter { return NewRateLimiter() }, keys...)
+}
+
+// newKeyPool creates a pool whose keys use the limiters returned by limiterFor
+func newKeyPool(limiterFor func(key string) *RateLimiter, keys ...string) *KeyPool {
+	pool := &KeyPool{}
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		RegisterSecret(key)
+		pool.keys = append(pool.keys, &pooledKey{
+			label:   fmt.Sprintf("key %d (%s)", len(pool.keys)+1, maskKey(key)),
+			key:     key,
+			limiter: limiterFor(key),
+		})
+	}
+	return pool
+}
+
+// Len returns the number of keys in the pool
+func (p *KeyPool) Len() int {
+	return len(p.keys)
+}
+
+// Contains reports whether key is one of the pool's keys
+func (p *KeyPool) Contains(key string) bool {
+	for _, k := range p.keys {
+		if k.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// acquire returns the key to use for the next request. The current key is kept
+// until it is exhausted; keys that are close to their rate limit are passed
+// over while another key has requests to spare.
+func (p *KeyPool) acquire() (*pooledKey, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := time.Now()
+	fallback := -1
+	for i := 0; i < len(p.keys); i++ {
+		index := (p.current + i) % len(p.keys)
+		k := p.keys[index]
+		if now.Before(k.exhaustedUntil) {
+			continue
+		}
+
+		remaining, _, resetTime := k.limiter.GetStatus()
+		if remaining <= 5 && now.Before(resetTime) {
+			if fallback < 0 {
+				fallback = index
+			}
+			continue
+		}
+
+		p.current = index
+		return k, nil
+	}
+
+	if fallback >= 0 {
+		p.current = fallback
+		return p.keys[fallback], nil
+	}
+
+	return nil, p.exhaustedError()
+}
+
+// exhaustedError describes a pool with no usable key left. Callers hold the mutex.
+func (p *KeyPool) exhaustedError() error {
+	var earliest time.Time
+	for _, k := range p.keys {
+		if earliest.IsZero() || k.exhaustedUntil.Before(earliest) {
+			earliest = k.exhaustedUntil
+		}
+	}
+
+	return &NewsAPIError{
+		StatusCode: http.StatusTooManyRequests,
+		Code:       CodeAPIKeysExhausted,
+		Message:    fmt.Sprintf("all %d API keys are exhausted, the first is available again at %s", len(p.keys), earliest.Format(time.RFC3339)),
+	}
+}
+
+// record counts a request made with k
+func (p *KeyPool) record(k *pooledKey) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	k.requests++
+}
+
+// markExhausted rests k until the given time and moves on to the next key
+func (p *KeyPool) markExhausted(k *pooledKey, until time.Time) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	k.rateLimited++
+	k.exhaustedUntil = until
+	for i, candidate := range p.keys {
+		if candidate == k {
+			p.current = (i + 1) % len(p.keys)
+			break
+		}
+	}
+}
+
+// active returns the key currently in use
+func (p *KeyPool) active() *pooledKey {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.keys[p.current]
+}
+
+// Usage returns a snapshot of how each key has been used
+func (p *KeyPool) Usage() []KeyUsage {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	usage := make([]KeyUsage, 0, len(p.keys))
+	for _, k := range p.keys {
+		remaining, _, _ := k.limiter.GetStatus()
+		entry := KeyUsage{
+			Key:         k.label,
+			Requests:    k.requests,
+			RateLimited: k.rateLimited,
+			Remaining:   remaining,
+		}
+		if time.Now().Before(k.exhaustedUntil) {
+			entry.ExhaustedUntil = k.exhaustedUntil
+		}
+		usage = append(usage, entry)
+	}
+	return usage
+}
+
+// keyExhaustedUntil reports whether err means the key that caused it has run out
+// of quota, and if so until when it should be rested. reset is the quota reset
+// time reported with the response, if any.
+func keyExhaustedUntil(err error, reset time.Time, defaultDelay time.Duration) (time.Time, bool) {
+	now := time.Now()
+	switch e := err.(type) {
+	case *BudgetExhaustedError:
+		return e.ResetTime, true
+	case *RateLimitError:
+		if e.ResetTime.After(now) {
+			return e.ResetTime, true
+		}
+		return now.Add(e.RetryAfter), true
+	case *NewsAPIError:
+		if e.Code != CodeRateLimited && e.Code != CodeAPIKeyExhausted {
+			return time.Time{}, false
+		}
+		if reset.After(now) {
+			return reset, true
+		}
+		if e.Code == CodeAPIKeyExhausted {
+			return now.Add(defaultKeyExhaustedDelay), true
+		}
+		return now.Add(defaultDelay), true
+	}
+	return time.Time{}, false
+}
+
+// maskKey shortens a key to its last four characters for display
+func maskKey(key string) string {
+	if len(key) < 8 {
+		return RedactedValue
+	}
+	return "..." + key[len(key)-4:]
+}
@@ -0,0 +1,163 @@
+package newsapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go-news-agg/internal/config"
+)
+
+const (
+	testKeyOne = "first-key-0001"
+	testKeyTwo = "second-key-0002"
+)
+
+func newPooledClient(httpClient HTTPClient, keys ...string) *NewsAPIClient {
+	cfg := config.DefaultConfig()
+	cfg.APIKeys = keys
+	return NewNewsAPIClientWithHTTPClient(cfg, httpClient)
+}
+
+func TestNewsAPIClient_RotatesKeyOnRateLimit(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rateLimited","message":"slow down"}`},
+		{status: http.StatusOK, body: mustMarshalResponse(t, createMockNewsAPIResponse())},
+	}}
+	client := newPooledClient(httpClient, testKeyOne, testKeyTwo)
+
+	if _, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest(testKeyOne, "us"), 1); err != nil {
+		t.Fatalf("Expected the second key to succeed, got: %v", err)
+	}
+
+	if len(httpClient.headers) != 2 ||
+		httpClient.headers[0].Get(APIKeyHeader) != testKeyOne ||
+		httpClient.headers[1].Get(APIKeyHeader) != testKeyTwo {
+		t.Fatalf("Expected one request per key in order, got %v", httpClient.headers)
+	}
+
+	usage := client.KeyUsage()
+	if len(usage) != 2 {
+		t.Fatalf("Expected usage for 2 keys, got %d", len(usage))
+	}
+	if usage[0].Requests != 1 || usage[0].RateLimited != 1 || usage[0].ExhaustedUntil.IsZero() {
+		t.Errorf("Expected first key to be rate limited, got %+v", usage[0])
+	}
+	if usage[1].Requests != 1 || usage[1].RateLimited != 0 {
+		t.Errorf("Expected second key to serve one request, got %+v", usage[1])
+	}
+
+	// The exhausted key is skipped for later requests
+	if _, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest(testKeyOne, "us"), 2); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := httpClient.headers[2].Get(APIKeyHeader); got != testKeyTwo {
+		t.Errorf("Expected the active key to stay on the second key, got %s", got)
+	}
+}
+
+func TestNewsAPIClient_RotatesKeyOnExhaustedCode(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusUnauthorized, body: `{"status":"error","code":"apiKeyExhausted","message":"no requests left"}`},
+		{status: http.StatusOK, body: mustMarshalResponse(t, createMockNewsAPIResponse())},
+	}}
+	client := newPooledClient(httpClient, testKeyOne, testKeyTwo)
+
+	if _, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest(testKeyOne, "us"), 1); err != nil {
+		t.Fatalf("Expected the second key to succeed, got: %v", err)
+	}
+
+	if usage := client.KeyUsage(); usage[0].RateLimited != 1 {
+		t.Errorf("Expected first key to be marked exhausted, got %+v", usage[0])
+	}
+}
+
+func TestNewsAPIClient_FailsWhenAllKeysExhausted(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rateLimited","message":"slow down"}`},
+	}}
+	client := newPooledClient(httpClient, testKeyOne, testKeyTwo)
+
+	_, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest(testKeyOne, "us"), 1)
+	apiErr, ok := err.(*NewsAPIError)
+	if !ok || apiErr.Code != CodeAPIKeysExhausted {
+		t.Fatalf("Expected %s error, got %v", CodeAPIKeysExhausted, err)
+	}
+
+	if httpClient.calls() != 2 {
+		t.Errorf("Expected each key to be tried once, got %d calls", httpClient.calls())
+	}
+
+	// Nothing more is sent until a key recovers
+	client.FetchNewsPage(context.Background(), NewDownloadRequest(testKeyOne, "us"), 1)
+	if httpClient.calls() != 2 {
+		t.Errorf("Expected no requests with every key exhausted, got %d calls", httpClient.calls())
+	}
+}
+
+func TestNewsAPIClient_SingleKeyPoolKeepsRateLimitError(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rateLimited","message":"slow down"}`},
+	}}
+	client := newPooledClient(httpClient, testKeyOne)
+
+	_, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest(testKeyOne, "us"), 1)
+	if _, ok := err.(*RateLimitError); !ok {
+		t.Fatalf("Expected RateLimitError so the downloader waits, got %v", err)
+	}
+}
+
+func TestNewsAPIClient_KeyOutsidePoolIsNotRotated(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, createMockNewsAPIResponse())},
+	}}
+	client := newPooledClient(httpClient, testKeyOne, testKeyTwo)
+
+	if _, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest("other-key-0003", "us"), 1); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if got := httpClient.headers[0].Get(APIKeyHeader); got != "other-key-0003" {
+		t.Errorf("Expected the request's own key to be sent, got %s", got)
+	}
+	if usage := client.KeyUsage(); usage[0].Requests != 0 || usage[1].Requests != 0 {
+		t.Errorf("Expected no pool usage, got %+v", usage)
+	}
+}
+
+func TestKeyPool_UsageHidesKeys(t *testing.T) {
+	pool := NewKeyPool(testKeyOne, testKeyTwo, testKeyOne, "", "short")
+	if pool.Len() != 3 {
+		t.Fatalf("Expected blank and duplicate keys to be dropped, got %d keys", pool.Len())
+	}
+
+	usage := pool.Usage()
+	for _, entry := range usage {
+		if strings.Contains(entry.Key, testKeyOne) || strings.Contains(entry.Key, testKeyTwo) || strings.Contains(entry.Key, "short") {
+			t.Errorf("Expected usage label to hide the key, got %s", entry.Key)
+		}
+	}
+
+	if usage[0].Key != "key 1 (...0001)" {
+		t.Errorf("Expected masked label, got %s", usage[0].Key)
+	}
+}
+
+func TestNewsDownloader_ReportsKeyUsage(t *testing.T) {
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusTooManyRequests, body: `{"status":"error","code":"rateLimited","message":"slow down"}`},
+		{status: http.StatusOK, body: mustMarshalResponse(t, createMockNewsAPIResponse())},
+	}}
+	downloader, _ := newTestDownloader(t, httpClient)
+	downloader.provider.(*NewsAPIClient).SetKeyPool(NewKeyPool(testKeyOne, testKeyTwo))
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), NewDownloadRequest(testKeyOne, "us"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(result.KeyUsage) != 2 || result.KeyUsage[0].RateLimited != 1 || result.KeyUsage[1].Requests != 1 {
+		t.Errorf("Unexpected key usage: %+v", result.KeyUsage)
+	}
+}
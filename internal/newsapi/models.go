@@ -1,10 +1,23 @@
 package newsapi
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
+// Endpoint identifies which NewsAPI search endpoint a request targets
+type Endpoint string
+
+const (
+	// EndpointTopHeadlines targets /v2/top-headlines (breaking news by country, category or source)
+	EndpointTopHeadlines Endpoint = "top-headlines"
+	// EndpointEverything targets /v2/everything (full archive search)
+	EndpointEverything Endpoint = "everything"
+)
+
 // NewsAPIResponse represents the top-level structure of the News API response
 type NewsAPIResponse struct {
 	Status       string    `json:"status"`
@@ -32,6 +45,33 @@ type Source struct {
 	Name string `json:"name"`
 }
 
+// SourceInfo describes a publisher indexed by NewsAPI, as returned by /v2/top-headlines/sources
+type SourceInfo struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	Category    string `json:"category"`
+	Language    string `json:"language"`
+	Country     string `json:"country"`
+}
+
+// SourcesResponse represents the top-level structure of the sources endpoint response
+type SourcesResponse struct {
+	Status  string       `json:"status"`
+	Sources []SourceInfo `json:"sources"`
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+}
+
+// SourcesRequest represents the optional filters for listing sources
+type SourcesRequest struct {
+	APIKey   string `json:"api_key"`
+	Category string `json:"category,omitempty"`
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
+}
+
 // NewsAPILimits holds the current rate limit information from NewsAPI response headers
 type NewsAPILimits struct {
 	Limit     int       `json:"limit"`
@@ -39,28 +79,45 @@ type NewsAPILimits struct {
 	Reset     time.Time `json:"reset"`
 }
 
-// DownloadRequest represents a request to download news articles
+// DownloadRequest represents a request to download news articles.
+// Which fields are sent depends on Endpoint: Country and Category only apply to
+// top-headlines, while SearchIn, Domains, ExcludeDomains, From, To, Language and
+// SortBy only apply to everything.
 type DownloadRequest struct {
-	APIKey    string    `json:"api_key"`
-	Query     string    `json:"query"`
-	Country   string    `json:"country"`
-	From      time.Time `json:"from"`
-	To        time.Time `json:"to"`
-	Language  string    `json:"language"`
-	SortBy    string    `json:"sort_by"`
-	PageSize  int       `json:"page_size"`
-	StartPage int       `json:"start_page"`
+	APIKey         string    `json:"api_key"`
+	Endpoint       Endpoint  `json:"endpoint"`
+	Query          string    `json:"query"`
+	SearchIn       []string  `json:"search_in,omitempty"`
+	Country        string    `json:"country"`
+	Category       string    `json:"category,omitempty"`
+	Sources        []string  `json:"sources,omitempty"`
+	Domains        []string  `json:"domains,omitempty"`
+	ExcludeDomains []string  `json:"exclude_domains,omitempty"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	Language       string    `json:"language"`
+	SortBy         string    `json:"sort_by"`
+	PageSize       int       `json:"page_size"`
+	StartPage      int       `json:"start_page"`
 }
 
 // DownloadResult represents the result of a download operation
 type DownloadResult struct {
-	TotalArticles int           `json:"total_articles"`
-	PagesDownloaded int         `json:"pages_downloaded"`
-	FilePaths     []string      `json:"file_paths"`
-	StartTime     time.Time     `json:"start_time"`
-	EndTime       time.Time     `json:"end_time"`
-	Duration      time.Duration `json:"duration"`
-	Errors        []error       `json:"errors,omitempty"`
+	TotalArticles     int           `json:"total_articles"`
+	PagesDownloaded   int           `json:"pages_downloaded"`
+	FilePaths         []string      `json:"file_paths"`
+	StartTime         time.Time     `json:"start_time"`
+	EndTime           time.Time     `json:"end_time"`
+	Duration          time.Duration `json:"duration"`
+	TotalAttempts     int           `json:"total_attempts"`
+	PageAttempts      map[int]int   `json:"page_attempts,omitempty"`
+	ResumedFromPage   int           `json:"resumed_from_page,omitempty"`
+	DuplicatesSkipped int           `json:"duplicates_skipped"`
+	From              time.Time     `json:"from"`
+	MaxPublishedAt    time.Time     `json:"max_published_at"`
+	Truncated         bool          `json:"truncated"`
+	KeyUsage          []KeyUsage    `json:"key_usage,omitempty"`
+	Errors            []error       `json:"errors,omitempty"`
 }
 
 // NewsAPIError represents an error response from the News API
@@ -73,7 +130,7 @@ type NewsAPIError struct {
 
 func (e *NewsAPIError) Error() string {
 	if e.Code != "" && e.Message != "" {
-		return fmt.Sprintf("NewsAPI error %d: %s - %s", e.StatusCode, e.Code, e.Message)
+		return Redact(fmt.Sprintf("NewsAPI error %d: %s - %s", e.StatusCode, e.Code, e.Message))
 	}
 	return fmt.Sprintf("NewsAPI error %d", e.StatusCode)
 }
@@ -88,11 +145,23 @@ type RateLimitError struct {
 
 func (e *RateLimitError) Error() string {
 	if e.Message != "" {
-		return e.Message
+		return Redact(e.Message)
 	}
 	return fmt.Sprintf("rate limit exceeded, retry after %v", e.RetryAfter)
 }
 
+// BudgetExhaustedError reports that the configured daily request budget is
+// spent. Unlike a RateLimitError it is not worth waiting for: the budget only
+// resets at UTC midnight, so callers should give up and report it.
+type BudgetExhaustedError struct {
+	Budget    int       `json:"budget"`
+	ResetTime time.Time `json:"reset_time"`
+}
+
+func (e *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf("daily budget of %d requests used up, resets at %s", e.Budget, e.ResetTime.Format(time.RFC3339))
+}
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field   string `json:"field"`
@@ -103,6 +172,17 @@ func (e *ValidationError) Error() string {
 	return fmt.Sprintf("validation error for field '%s': %s", e.Field, e.Message)
 }
 
+// ResponseTooLargeError is returned when a response body is larger than
+// Config.MaxResponseBytes
+type ResponseTooLargeError struct {
+	Limit int64  `json:"limit"`
+	URL   string `json:"url,omitempty"`
+}
+
+func (e *ResponseTooLargeError) Error() string {
+	return fmt.Sprintf("response from %s exceeds the limit of %d bytes", e.URL, e.Limit)
+}
+
 // FileOperationError represents an error during file operations
 type FileOperationError struct {
 	Operation string `json:"operation"`
@@ -135,14 +215,67 @@ func (e *KafkaError) Unwrap() error {
 	return e.Cause
 }
 
+// maxQueryLength is the longest q parameter NewsAPI accepts
+const maxQueryLength = 500
+
+var (
+	validSortBy = map[string]bool{
+		"relevancy":   true,
+		"popularity":  true,
+		"publishedAt": true,
+	}
+
+	validCategories = map[string]bool{
+		"business":      true,
+		"entertainment": true,
+		"general":       true,
+		"health":        true,
+		"science":       true,
+		"sports":        true,
+		"technology":    true,
+	}
+
+	validSearchIn = map[string]bool{
+		"title":       true,
+		"description": true,
+		"content":     true,
+	}
+
+	validLanguages = map[string]bool{
+		"ar": true, "de": true, "en": true, "es": true, "fr": true, "he": true, "it": true,
+		"nl": true, "no": true, "pt": true, "ru": true, "sv": true, "ud": true, "zh": true,
+	}
+)
+
+// EffectiveEndpoint returns the endpoint the request targets, defaulting to top-headlines
+func (r *DownloadRequest) EffectiveEndpoint() Endpoint {
+	if r.Endpoint == "" {
+		return EndpointTopHeadlines
+	}
+	return r.Endpoint
+}
+
 // Validate validates a DownloadRequest
 func (r *DownloadRequest) Validate() error {
 	if r.APIKey == "" {
 		return &ValidationError{Field: "api_key", Message: "cannot be empty"}
 	}
 
-	if r.Country == "" && r.Query == "" {
-		return &ValidationError{Field: "country/query", Message: "either country or query must be specified"}
+	switch r.EffectiveEndpoint() {
+	case EndpointTopHeadlines:
+		if err := r.validateTopHeadlines(); err != nil {
+			return err
+		}
+	case EndpointEverything:
+		if err := r.validateEverything(); err != nil {
+			return err
+		}
+	default:
+		return &ValidationError{Field: "endpoint", Message: "must be one of: top-headlines, everything"}
+	}
+
+	if len(r.Query) > maxQueryLength {
+		return &ValidationError{Field: "query", Message: fmt.Sprintf("cannot exceed %d characters", maxQueryLength)}
 	}
 
 	if r.PageSize <= 0 || r.PageSize > 100 {
@@ -153,12 +286,6 @@ func (r *DownloadRequest) Validate() error {
 		return &ValidationError{Field: "start_page", Message: "must be >= 1"}
 	}
 
-	validSortBy := map[string]bool{
-		"relevancy":   true,
-		"popularity":  true,
-		"publishedAt": true,
-	}
-
 	if r.SortBy != "" && !validSortBy[r.SortBy] {
 		return &ValidationError{Field: "sort_by", Message: "must be one of: relevancy, popularity, publishedAt"}
 	}
@@ -166,10 +293,104 @@ func (r *DownloadRequest) Validate() error {
 	return nil
 }
 
+// validateTopHeadlines enforces the parameter rules of /v2/top-headlines
+func (r *DownloadRequest) validateTopHeadlines() error {
+	if r.Country == "" && r.Query == "" && r.Category == "" && len(r.Sources) == 0 {
+		return &ValidationError{Field: "country/query", Message: "one of country, category, sources or query must be specified"}
+	}
+
+	if len(r.Sources) > 0 && (r.Country != "" || r.Category != "") {
+		return &ValidationError{Field: "sources", Message: "cannot be mixed with country or category"}
+	}
+
+	if r.Category != "" && !validCategories[r.Category] {
+		return &ValidationError{Field: "category", Message: "must be one of: business, entertainment, general, health, science, sports, technology"}
+	}
+
+	if len(r.SearchIn) > 0 {
+		return &ValidationError{Field: "search_in", Message: "only supported by the everything endpoint"}
+	}
+
+	if len(r.Domains) > 0 || len(r.ExcludeDomains) > 0 {
+		return &ValidationError{Field: "domains", Message: "only supported by the everything endpoint"}
+	}
+
+	if r.Language != "" {
+		return &ValidationError{Field: "language", Message: "only supported by the everything endpoint"}
+	}
+
+	// Headlines always come newest first, which is what NewDownloadRequest's default asks for
+	if r.SortBy != "" && r.SortBy != "publishedAt" {
+		return &ValidationError{Field: "sort_by", Message: "only supported by the everything endpoint"}
+	}
+
+	return nil
+}
+
+// validateEverything enforces the parameter rules of /v2/everything
+func (r *DownloadRequest) validateEverything() error {
+	if r.Query == "" && len(r.Sources) == 0 && len(r.Domains) == 0 {
+		return &ValidationError{Field: "query/sources/domains", Message: "one of query, sources or domains must be specified"}
+	}
+
+	if r.Country != "" {
+		return &ValidationError{Field: "country", Message: "only supported by the top-headlines endpoint"}
+	}
+
+	if r.Category != "" {
+		return &ValidationError{Field: "category", Message: "only supported by the top-headlines endpoint"}
+	}
+
+	for _, field := range r.SearchIn {
+		if !validSearchIn[field] {
+			return &ValidationError{Field: "search_in", Message: "must be a combination of: title, description, content"}
+		}
+	}
+
+	if r.Language != "" && !validLanguages[r.Language] {
+		return &ValidationError{Field: "language", Message: fmt.Sprintf("unsupported language '%s'", r.Language)}
+	}
+
+	if !r.From.IsZero() && !r.To.IsZero() && r.From.After(r.To) {
+		return &ValidationError{Field: "from/to", Message: "from must not be after to"}
+	}
+
+	return nil
+}
+
+// joinList joins a list parameter the way NewsAPI expects (comma-separated, blanks dropped)
+func joinList(values []string) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// Validate validates a SourcesRequest
+func (r *SourcesRequest) Validate() error {
+	if r.APIKey == "" {
+		return &ValidationError{Field: "api_key", Message: "cannot be empty"}
+	}
+
+	if r.Category != "" && !validCategories[r.Category] {
+		return &ValidationError{Field: "category", Message: "must be one of: business, entertainment, general, health, science, sports, technology"}
+	}
+
+	if r.Language != "" && !validLanguages[r.Language] {
+		return &ValidationError{Field: "language", Message: fmt.Sprintf("unsupported language '%s'", r.Language)}
+	}
+
+	return nil
+}
+
 // NewDownloadRequest creates a new DownloadRequest with defaults
 func NewDownloadRequest(apiKey, country string) *DownloadRequest {
 	return &DownloadRequest{
 		APIKey:    apiKey,
+		Endpoint:  EndpointTopHeadlines,
 		Country:   country,
 		PageSize:  20,
 		StartPage: 1,
@@ -177,6 +398,73 @@ func NewDownloadRequest(apiKey, country string) *DownloadRequest {
 	}
 }
 
+// ReportsError reports whether err is, or wraps the cause of, one of r's errors, as
+// the error a download returns does for the page error that stopped it
+func (r *DownloadResult) ReportsError(err error) bool {
+	if r == nil || err == nil {
+		return false
+	}
+
+	for _, recorded := range r.Errors {
+		if recorded == err {
+			return true
+		}
+		if cause := errors.Unwrap(recorded); cause != nil && errors.Is(err, cause) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge adds the counts, files and errors of another download into r
+func (r *DownloadResult) Merge(other *DownloadResult) {
+	if other == nil {
+		return
+	}
+
+	r.TotalArticles += other.TotalArticles
+	r.PagesDownloaded += other.PagesDownloaded
+	r.FilePaths = append(r.FilePaths, other.FilePaths...)
+	r.TotalAttempts += other.TotalAttempts
+	r.DuplicatesSkipped += other.DuplicatesSkipped
+	r.Truncated = r.Truncated || other.Truncated
+	r.Errors = append(r.Errors, other.Errors...)
+
+	if other.MaxPublishedAt.After(r.MaxPublishedAt) {
+		r.MaxPublishedAt = other.MaxPublishedAt
+	}
+	if !other.From.IsZero() && (r.From.IsZero() || other.From.Before(r.From)) {
+		r.From = other.From
+	}
+	// Key usage is cumulative per client, so the latest snapshot wins
+	if len(other.KeyUsage) > 0 {
+		r.KeyUsage = other.KeyUsage
+	}
+}
+
+// MarshalJSON encodes the result with its errors as redacted messages, since
+// error values have no JSON form of their own
+func (r DownloadResult) MarshalJSON() ([]byte, error) {
+	type plain DownloadResult
+	return json.Marshal(struct {
+		plain
+		Errors []string `json:"errors,omitempty"`
+	}{
+		plain:  plain(r),
+		Errors: redactErrors(r.Errors),
+	})
+}
+
+// MarshalJSON encodes the request with its API key redacted
+func (r DownloadRequest) MarshalJSON() ([]byte, error) {
+	type plain DownloadRequest
+	redacted := plain(r)
+	if redacted.APIKey != "" {
+		redacted.APIKey = RedactedValue
+	}
+	return json.Marshal(redacted)
+}
+
 // IsEmpty checks if the NewsAPIResponse contains any articles
 func (r *NewsAPIResponse) IsEmpty() bool {
 	return len(r.Articles) == 0
@@ -193,6 +481,24 @@ func (r *NewsAPIResponse) ToError(statusCode int) *NewsAPIError {
 		return nil
 	}
 
+	return &NewsAPIError{
+		StatusCode: statusCode,
+		Code:       r.Code,
+		Message:    r.Message,
+	}
+}
+
+// IsError checks if the SourcesResponse contains an error
+func (r *SourcesResponse) IsError() bool {
+	return r.Status != "ok" || r.Code != ""
+}
+
+// ToError converts a SourcesResponse to a NewsAPIError if it represents an error
+func (r *SourcesResponse) ToError(statusCode int) *NewsAPIError {
+	if !r.IsError() {
+		return nil
+	}
+
 	return &NewsAPIError{
 		StatusCode: statusCode,
 		Code:       r.Code,
@@ -30,6 +30,7 @@ func TestDownloadRequest_Validate(t *testing.T) {
 			name: "valid request with query",
 			req: &DownloadRequest{
 				APIKey:    "test-api-key",
+				Endpoint:  EndpointEverything,
 				Query:     "technology",
 				PageSize:  50,
 				StartPage: 1,
@@ -45,7 +46,7 @@ func TestDownloadRequest_Validate(t *testing.T) {
 				Query:     "AI",
 				PageSize:  100,
 				StartPage: 1,
-				SortBy:    "popularity",
+				SortBy:    "publishedAt",
 			},
 			wantErr: false,
 		},
@@ -158,6 +159,222 @@ func TestDownloadRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestDownloadRequest_ValidateEndpoints(t *testing.T) {
+	from := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		req     *DownloadRequest
+		wantErr bool
+		errType string
+	}{
+		{
+			name: "top-headlines with sources",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointTopHeadlines,
+				Sources:   []string{"bbc-news"},
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "top-headlines with category only",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Category:  "science",
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "top-headlines sources mixed with country",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointTopHeadlines,
+				Country:   "us",
+				Sources:   []string{"bbc-news"},
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "sources",
+		},
+		{
+			name: "top-headlines invalid category",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Country:   "us",
+				Category:  "weather",
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "category",
+		},
+		{
+			name: "top-headlines with domains",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Country:   "us",
+				Domains:   []string{"bbc.co.uk"},
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "domains",
+		},
+		{
+			name: "top-headlines with language",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Country:   "us",
+				Language:  "en",
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "language",
+		},
+		{
+			name: "top-headlines sorted by relevancy",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Country:   "us",
+				PageSize:  20,
+				StartPage: 1,
+				SortBy:    "relevancy",
+			},
+			wantErr: true,
+			errType: "sort_by",
+		},
+		{
+			name: "everything with domains only",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointEverything,
+				Domains:   []string{"bbc.co.uk"},
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "everything without query, sources or domains",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointEverything,
+				Language:  "en",
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "query/sources/domains",
+		},
+		{
+			name: "everything with country",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointEverything,
+				Query:     "ai",
+				Country:   "us",
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "country",
+		},
+		{
+			name: "everything invalid searchIn",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointEverything,
+				Query:     "ai",
+				SearchIn:  []string{"title", "body"},
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "search_in",
+		},
+		{
+			name: "everything invalid language",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointEverything,
+				Query:     "ai",
+				Language:  "xx",
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "language",
+		},
+		{
+			name: "everything from after to",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  EndpointEverything,
+				Query:     "ai",
+				From:      from,
+				To:        to,
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "from/to",
+		},
+		{
+			name: "unknown endpoint",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Endpoint:  Endpoint("sources"),
+				Query:     "ai",
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "endpoint",
+		},
+		{
+			name: "query too long",
+			req: &DownloadRequest{
+				APIKey:    "test-api-key",
+				Query:     strings.Repeat("a", maxQueryLength+1),
+				PageSize:  20,
+				StartPage: 1,
+			},
+			wantErr: true,
+			errType: "query",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Expected no validation error, got: %v", err)
+				}
+				return
+			}
+
+			validationErr, ok := err.(*ValidationError)
+			if !ok {
+				t.Fatalf("Expected ValidationError, got %T (%v)", err, err)
+			}
+
+			if validationErr.Field != tt.errType {
+				t.Errorf("Expected error field '%s', got '%s'", tt.errType, validationErr.Field)
+			}
+		})
+	}
+}
+
 func TestNewDownloadRequest(t *testing.T) {
 	apiKey := "test-api-key"
 	country := "us"
@@ -183,6 +400,10 @@ func TestNewDownloadRequest(t *testing.T) {
 	if req.SortBy != "publishedAt" {
 		t.Errorf("Expected default SortBy 'publishedAt', got '%s'", req.SortBy)
 	}
+
+	if req.Endpoint != EndpointTopHeadlines {
+		t.Errorf("Expected default Endpoint '%s', got '%s'", EndpointTopHeadlines, req.Endpoint)
+	}
 }
 
 func TestNewsAPIResponse_IsEmpty(t *testing.T) {
@@ -0,0 +1,184 @@
+package newsapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// OutputFormat names a file format pages are saved in
+type OutputFormat string
+
+const (
+	// FormatJSON saves each page as the indented NewsAPI response
+	FormatJSON OutputFormat = "json"
+	// FormatNDJSON saves one Article per line
+	FormatNDJSON OutputFormat = "ndjson"
+	// FormatCSV saves one article per row under a header of CSVColumns
+	FormatCSV OutputFormat = "csv"
+	// FormatParquet saves one article per row with the schema of parquetArticle
+	FormatParquet OutputFormat = "parquet"
+)
+
+// Headers of the Kafka message announcing a saved page, for publishers that
+// support them. The format and compression are also given by the file name
+// extension, and the checksum by the file's sidecar.
+const (
+	// HeaderFormat is the OutputFormat of the file
+	HeaderFormat = "format"
+	// HeaderCompression is the codec the file is compressed with, or none
+	HeaderCompression = "compression"
+	// HeaderSHA256 is the hex-encoded SHA-256 of the file, as in its sidecar
+	HeaderSHA256 = "sha256"
+)
+
+// CSVColumns is the header and column order of CSV output. Columns are only ever
+// appended, so consumers can rely on their positions.
+var CSVColumns = []string{
+	"source_id",
+	"source_name",
+	"author",
+	"title",
+	"description",
+	"url",
+	"url_to_image",
+	"published_at",
+	"content",
+}
+
+// PageWriter writes a page of results in one output format
+type PageWriter interface {
+	// Format returns the format the writer produces
+	Format() OutputFormat
+	// Extension returns the file name extension, without the leading dot
+	Extension() string
+	// WritePage writes the page to w
+	WritePage(w io.Writer, resp *NewsAPIResponse) error
+}
+
+// NewPageWriter returns the writer for format; an empty format means JSON
+func NewPageWriter(format string) (PageWriter, error) {
+	switch OutputFormat(format) {
+	case "", FormatJSON:
+		return jsonPageWriter{}, nil
+	case FormatNDJSON:
+		return ndjsonPageWriter{}, nil
+	case FormatCSV:
+		return csvPageWriter{}, nil
+	case FormatParquet:
+		return parquetPageWriter{}, nil
+	}
+	return nil, fmt.Errorf("unsupported output format '%s'", format)
+}
+
+// jsonPageWriter writes the page as the API returned it
+type jsonPageWriter struct{}
+
+func (jsonPageWriter) Format() OutputFormat { return FormatJSON }
+func (jsonPageWriter) Extension() string    { return "json" }
+
+func (jsonPageWriter) WritePage(w io.Writer, resp *NewsAPIResponse) error {
+	return encodeNewsAPIResponse(w, resp)
+}
+
+// ndjsonPageWriter writes one JSON-encoded Article per line
+type ndjsonPageWriter struct{}
+
+func (ndjsonPageWriter) Format() OutputFormat { return FormatNDJSON }
+func (ndjsonPageWriter) Extension() string    { return "ndjson" }
+
+func (ndjsonPageWriter) WritePage(w io.Writer, resp *NewsAPIResponse) error {
+	enc := json.NewEncoder(w)
+	for i := range resp.Articles {
+		if err := enc.Encode(&resp.Articles[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// csvPageWriter writes a header of CSVColumns, then one row per article
+type csvPageWriter struct{}
+
+func (csvPageWriter) Format() OutputFormat { return FormatCSV }
+func (csvPageWriter) Extension() string    { return "csv" }
+
+func (csvPageWriter) WritePage(w io.Writer, resp *NewsAPIResponse) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(CSVColumns); err != nil {
+		return err
+	}
+
+	for _, article := range resp.Articles {
+		publishedAt := ""
+		if !article.PublishedAt.IsZero() {
+			publishedAt = article.PublishedAt.UTC().Format(time.RFC3339)
+		}
+
+		record := []string{
+			article.Source.ID,
+			article.Source.Name,
+			article.Author,
+			article.Title,
+			article.Description,
+			article.URL,
+			article.URLToImage,
+			publishedAt,
+			article.Content,
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// parquetArticle is the Parquet schema of a saved article. Columns follow the
+// order of CSVColumns; published_at is a UTC timestamp in milliseconds.
+type parquetArticle struct {
+	SourceID    string    `parquet:"source_id"`
+	SourceName  string    `parquet:"source_name"`
+	Author      string    `parquet:"author"`
+	Title       string    `parquet:"title"`
+	Description string    `parquet:"description"`
+	URL         string    `parquet:"url"`
+	URLToImage  string    `parquet:"url_to_image"`
+	PublishedAt time.Time `parquet:"published_at,timestamp(millisecond)"`
+	Content     string    `parquet:"content"`
+}
+
+// parquetPageWriter writes the page as a Parquet file with one row group,
+// Snappy-compressed
+type parquetPageWriter struct{}
+
+func (parquetPageWriter) Format() OutputFormat { return FormatParquet }
+func (parquetPageWriter) Extension() string    { return "parquet" }
+
+func (parquetPageWriter) WritePage(w io.Writer, resp *NewsAPIResponse) error {
+	rows := make([]parquetArticle, len(resp.Articles))
+	for i, article := range resp.Articles {
+		rows[i] = parquetArticle{
+			SourceID:    article.Source.ID,
+			SourceName:  article.Source.Name,
+			Author:      article.Author,
+			Title:       article.Title,
+			Description: article.Description,
+			URL:         article.URL,
+			URLToImage:  article.URLToImage,
+			PublishedAt: article.PublishedAt.UTC(),
+			Content:     article.Content,
+		}
+	}
+
+	writer := parquet.NewGenericWriter[parquetArticle](w, parquet.Compression(&parquet.Snappy))
+	if _, err := writer.Write(rows); err != nil {
+		return err
+	}
+	return writer.Close()
+}
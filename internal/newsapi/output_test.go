@@ -0,0 +1,212 @@
+package newsapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-news-agg/pkg/utils"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// outputTestPage returns a page whose articles exercise quoting and empty fields
+func outputTestPage() *NewsAPIResponse {
+	return &NewsAPIResponse{
+		Status:       "ok",
+		TotalResults: 2,
+		Articles: []Article{
+			{
+				Source:      Source{ID: "bbc-news", Name: "BBC News"},
+				Author:      "Alex Morgan",
+				Title:       `Markets, "rates" and more`,
+				Description: "Line one\nline two",
+				URL:         "https://www.bbc.co.uk/news/1",
+				URLToImage:  "https://www.bbc.co.uk/news/1.jpg",
+				PublishedAt: time.Date(2024, 1, 15, 9, 30, 0, 0, time.UTC),
+				Content:     "Body",
+			},
+			{
+				Source:      Source{Name: "Blog"},
+				Title:       "No author",
+				URL:         "https://blog.example.com/2",
+				PublishedAt: time.Date(2024, 1, 15, 8, 0, 0, 0, time.FixedZone("CET", 3600)),
+			},
+		},
+	}
+}
+
+func TestNewPageWriter(t *testing.T) {
+	for format, extension := range map[string]string{"": "json", "json": "json", "ndjson": "ndjson", "csv": "csv", "parquet": "parquet"} {
+		writer, err := NewPageWriter(format)
+		if err != nil || writer.Extension() != extension {
+			t.Errorf("NewPageWriter(%q): expected extension %s, got %v, %v", format, extension, writer, err)
+		}
+	}
+
+	if _, err := NewPageWriter("xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
+
+func TestNDJSONPageWriter(t *testing.T) {
+	page := outputTestPage()
+	var buf bytes.Buffer
+	if err := (ndjsonPageWriter{}).WritePage(&buf, page); err != nil {
+		t.Fatalf("WritePage() unexpected error: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&buf)
+	lines := 0
+	for scanner.Scan() {
+		var article Article
+		if err := json.Unmarshal(scanner.Bytes(), &article); err != nil {
+			t.Fatalf("Line %d is not an article: %v", lines+1, err)
+		}
+		if article.URL != page.Articles[lines].URL || !article.PublishedAt.Equal(page.Articles[lines].PublishedAt) {
+			t.Errorf("Line %d: unexpected article %+v", lines+1, article)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("Expected 2 lines, got %d", lines)
+	}
+}
+
+func TestCSVPageWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (csvPageWriter{}).WritePage(&buf, outputTestPage()); err != nil {
+		t.Fatalf("WritePage() unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(CSVColumns, ",") {
+		t.Fatalf("Expected a header and 2 rows, got %v", records)
+	}
+
+	expected := []string{"bbc-news", "BBC News", "Alex Morgan", `Markets, "rates" and more`, "Line one\nline two",
+		"https://www.bbc.co.uk/news/1", "https://www.bbc.co.uk/news/1.jpg", "2024-01-15T09:30:00Z", "Body"}
+	if strings.Join(records[1], "|") != strings.Join(expected, "|") {
+		t.Errorf("Unexpected first row: %q", records[1])
+	}
+
+	// Timestamps are normalised to UTC and missing fields are empty
+	if records[2][0] != "" || records[2][2] != "" || records[2][7] != "2024-01-15T07:00:00Z" {
+		t.Errorf("Unexpected second row: %q", records[2])
+	}
+}
+
+func TestParquetPageWriter(t *testing.T) {
+	page := outputTestPage()
+	var buf bytes.Buffer
+	if err := (parquetPageWriter{}).WritePage(&buf, page); err != nil {
+		t.Fatalf("WritePage() unexpected error: %v", err)
+	}
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Output is not a Parquet file: %v", err)
+	}
+	var columns []string
+	for _, field := range file.Schema().Fields() {
+		columns = append(columns, field.Name())
+	}
+	if strings.Join(columns, ",") != strings.Join(CSVColumns, ",") {
+		t.Errorf("Expected columns %v, got %v", CSVColumns, columns)
+	}
+
+	rows, err := parquet.Read[parquetArticle](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read rows: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Title != page.Articles[0].Title || rows[1].SourceName != "Blog" {
+		t.Fatalf("Unexpected rows: %+v", rows)
+	}
+	if !rows[1].PublishedAt.Equal(page.Articles[1].PublishedAt) {
+		t.Errorf("Expected published_at %v, got %v", page.Articles[1].PublishedAt, rows[1].PublishedAt)
+	}
+}
+
+func TestNewsDownloader_SavesConfiguredFormat(t *testing.T) {
+	for _, format := range []string{"ndjson", "csv", "parquet"} {
+		t.Run(format, func(t *testing.T) {
+			provider := &staticProvider{articles: outputTestPage().Articles}
+			downloader, publisher := newTestDownloader(t, nil)
+			downloader.provider = provider
+			downloader.config.OutputFormat = format
+			downloader.pageWriter = pageWriterFor(downloader.config)
+
+			req := &DownloadRequest{PageSize: 1, StartPage: 1, From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+			result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if len(result.FilePaths) != 2 || len(publisher.messages) != 2 {
+				t.Fatalf("Expected 2 files published, got %v", result.FilePaths)
+			}
+			for _, path := range result.FilePaths {
+				if filepath.Ext(path) != "."+format {
+					t.Errorf("Expected a .%s file, got %s", format, path)
+				}
+				if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+					t.Errorf("Expected %s to be written, got %v", path, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewsDownloader_CompressesSavedPages(t *testing.T) {
+	for compression, extension := range map[string]string{"gzip": ".ndjson.gz", "zstd": ".ndjson.zst"} {
+		t.Run(compression, func(t *testing.T) {
+			provider := &staticProvider{articles: outputTestPage().Articles}
+			downloader, publisher := newTestDownloader(t, nil)
+			downloader.provider = provider
+			downloader.config.OutputFormat = "ndjson"
+			downloader.config.OutputCompression = compression
+			downloader.pageWriter = pageWriterFor(downloader.config)
+			downloader.compression = compressionFor(downloader.config)
+
+			req := &DownloadRequest{PageSize: 1, StartPage: 1, From: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}
+			result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(result.FilePaths) != 2 || len(publisher.messages) != 2 {
+				t.Fatalf("Expected 2 files published, got %v", result.FilePaths)
+			}
+
+			for i, path := range result.FilePaths {
+				if !strings.HasSuffix(path, extension) {
+					t.Errorf("Expected a %s file, got %s", extension, path)
+				}
+
+				// The file reads back as the uncompressed page
+				data, err := utils.ReadFile(path)
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", path, err)
+				}
+				var article Article
+				if err := json.Unmarshal(data, &article); err != nil {
+					t.Fatalf("%s does not decompress to an article: %v", path, err)
+				}
+
+				headers := publisher.headers[i]
+				if headers[HeaderFormat] != "ndjson" || headers[HeaderCompression] != compression {
+					t.Errorf("Expected format and compression headers, got %v", headers)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package newsapi
+
+import (
+	"context"
+)
+
+// Provider is a source of news articles that the downloader can page through.
+// Implementations map their native format onto Article, so pages from every
+// provider are saved and published the same way.
+type Provider interface {
+	// Name identifies the provider, e.g. "newsapi"
+	Name() string
+
+	// ValidateRequest checks that req is something this provider can serve
+	ValidateRequest(req *DownloadRequest) error
+
+	// FetchPage fetches one page of results for req. Errors should use the
+	// package's error types so retries and rate limiting behave consistently.
+	// Pages after the first may be fetched concurrently.
+	FetchPage(ctx context.Context, req *DownloadRequest, page int) (*Page, error)
+}
+
+// DownloadFinisher is implemented by providers that keep state for a download in
+// progress. The downloader calls FinishDownload once a download of req ends, however
+// it ends; completed reports whether every page was saved and published, so state
+// describing what was served, such as HTTP cache validators, is only kept then.
+type DownloadFinisher interface {
+	FinishDownload(req *DownloadRequest, completed bool) error
+}
+
+// Page is one page of articles returned by a Provider
+type Page struct {
+	Articles []Article
+	// TotalResults is the number of results across all pages of the query
+	TotalResults int
+	// Limits holds the provider's rate limit status, or nil if it reports none
+	Limits *NewsAPILimits
+}
+
+// response wraps the page in the NewsAPI response format used for saved files
+func (p *Page) response() *NewsAPIResponse {
+	return &NewsAPIResponse{
+		Status:       "ok",
+		TotalResults: p.TotalResults,
+		Articles:     p.Articles,
+	}
+}
+
+// NewsAPIProviderName is the name of the NewsAPI provider
+const NewsAPIProviderName = "newsapi"
+
+// Name implements Provider.
+func (c *NewsAPIClient) Name() string {
+	return NewsAPIProviderName
+}
+
+// ValidateRequest implements Provider.
+func (c *NewsAPIClient) ValidateRequest(req *DownloadRequest) error {
+	return req.Validate()
+}
+
+// FetchPage implements Provider.
+func (c *NewsAPIClient) FetchPage(ctx context.Context, req *DownloadRequest, page int) (*Page, error) {
+	newsResp, limits, err := c.FetchNewsPage(ctx, req, page)
+	if err != nil {
+		return &Page{Limits: limits}, err
+	}
+
+	return &Page{
+		Articles:     newsResp.Articles,
+		TotalResults: newsResp.TotalResults,
+		Limits:       limits,
+	}, nil
+}
@@ -0,0 +1,134 @@
+package newsapi
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/config"
+)
+
+// Compile-time check that the NewsAPI client is a Provider
+var _ Provider = (*NewsAPIClient)(nil)
+
+// staticProvider serves a fixed list of articles in pages of req.PageSize
+type staticProvider struct {
+	mutex    sync.Mutex
+	articles []Article
+	pages    []int
+}
+
+func (p *staticProvider) Name() string {
+	return "static"
+}
+
+func (p *staticProvider) ValidateRequest(req *DownloadRequest) error {
+	if req.PageSize <= 0 {
+		return &ValidationError{Field: "page_size", Message: "must be positive"}
+	}
+	return nil
+}
+
+func (p *staticProvider) FetchPage(ctx context.Context, req *DownloadRequest, page int) (*Page, error) {
+	p.mutex.Lock()
+	p.pages = append(p.pages, page)
+	p.mutex.Unlock()
+
+	start := (page - 1) * req.PageSize
+	end := start + req.PageSize
+	if start > len(p.articles) {
+		start = len(p.articles)
+	}
+	if end > len(p.articles) {
+		end = len(p.articles)
+	}
+
+	return &Page{
+		Articles:     p.articles[start:end],
+		TotalResults: len(p.articles),
+	}, nil
+}
+
+func TestNewsDownloader_UsesProvider(t *testing.T) {
+	base := time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)
+	provider := &staticProvider{}
+	for i := 0; i < 5; i++ {
+		provider.articles = append(provider.articles, Article{
+			Source:      Source{Name: "Static"},
+			Title:       "Article " + string(rune('A'+i)),
+			URL:         "https://static.example.com/" + string(rune('a'+i)),
+			PublishedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	publisher := &recordingPublisher{}
+	downloader := NewNewsDownloader(provider, publisher, cfg)
+
+	// The request has no country or query, which NewsAPI would reject
+	req := &DownloadRequest{PageSize: 2, StartPage: 1, From: base}
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(provider.pages) != 3 {
+		t.Errorf("Expected 3 pages fetched, got %v", provider.pages)
+	}
+	if result.TotalArticles != 5 || len(result.FilePaths) != 3 || len(publisher.messages) != 3 {
+		t.Errorf("Expected 5 articles in 3 published files, got %d in %d files and %d messages",
+			result.TotalArticles, len(result.FilePaths), len(publisher.messages))
+	}
+	if !result.MaxPublishedAt.Equal(base.Add(4 * time.Hour)) {
+		t.Errorf("Expected newest article at %v, got %v", base.Add(4*time.Hour), result.MaxPublishedAt)
+	}
+
+	// Saved files use the same format regardless of provider
+	data, err := ioutil.ReadFile(result.FilePaths[2])
+	if err != nil {
+		t.Fatalf("Failed to read saved page: %v", err)
+	}
+	var saved NewsAPIResponse
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("Saved page is not a NewsAPI response: %v", err)
+	}
+	if saved.Status != "ok" || saved.TotalResults != 5 || len(saved.Articles) != 1 {
+		t.Errorf("Unexpected saved page: %+v", saved)
+	}
+
+	if _, err := downloader.DownloadAllNewsToFile(context.Background(), &DownloadRequest{From: base}); err == nil {
+		t.Error("Expected the provider's validation to reject a zero page size")
+	}
+}
+
+func TestNewsAPIClient_FetchPage(t *testing.T) {
+	cfg := config.DefaultConfig()
+	resp := createMockNewsAPIResponse()
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, resp)},
+		{status: http.StatusUnauthorized, body: `{"status": "error", "code": "apiKeyInvalid", "message": "bad key"}`},
+	}}
+	client := NewNewsAPIClientWithHTTPClient(cfg, httpClient)
+
+	page, err := client.FetchPage(context.Background(), NewDownloadRequest("test-key", "us"), 1)
+	if err != nil {
+		t.Fatalf("FetchPage() unexpected error: %v", err)
+	}
+	if page.TotalResults != resp.TotalResults || len(page.Articles) != len(resp.Articles) {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	if _, err := client.FetchPage(context.Background(), NewDownloadRequest("test-key", "us"), 2); err == nil {
+		t.Error("FetchPage() expected an error for an invalid key")
+	}
+	if client.Name() != NewsAPIProviderName {
+		t.Errorf("Expected name '%s', got '%s'", NewsAPIProviderName, client.Name())
+	}
+}
@@ -0,0 +1,261 @@
+package newsapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimitDir is the subdirectory of OutputDir holding persisted request budgets
+const rateLimitDir = ".ratelimit"
+
+// RateLimitOptions configures the request budget a RateLimiter enforces before
+// the API has to reject anything
+type RateLimitOptions struct {
+	// RequestsPerSecond is the sustained request rate; zero disables pacing
+	RequestsPerSecond float64
+	// RequestsPerDay is the number of requests allowed per UTC day; zero means no daily budget
+	RequestsPerDay int
+	// StatePath is where the day's usage is persisted; empty keeps it in memory only
+	StatePath string
+}
+
+// rateLimitState is the persisted part of a RateLimiter's daily budget
+type rateLimitState struct {
+	Day       string    `json:"day"`
+	Used      int       `json:"used"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RateLimiter manages API rate limiting.
+// It paces requests with a token bucket, enforces a daily budget, and still
+// backs off when the API's rate limit headers report the quota is nearly spent.
+type RateLimiter struct {
+	remaining int
+	resetTime time.Time
+	limit     int
+
+	options    RateLimitOptions
+	tokens     float64
+	lastRefill time.Time
+	day        string
+	dayUsed    int
+
+	mutex sync.RWMutex
+}
+
+// NewRateLimiter creates a new rate limiter without a request budget.
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		remaining: 1000, // Default conservative value
+		resetTime: time.Now().Add(time.Hour),
+		limit:     1000,
+	}
+}
+
+// NewBudgetRateLimiter creates a rate limiter enforcing options, restoring the
+// day's usage from options.StatePath if it was saved earlier today.
+func NewBudgetRateLimiter(options RateLimitOptions) (*RateLimiter, error) {
+	r := NewRateLimiter()
+	r.options = options
+	r.tokens = r.burst()
+	r.lastRefill = time.Now()
+	r.day = utcDay(r.lastRefill)
+
+	if options.StatePath == "" {
+		return r, nil
+	}
+
+	data, err := ioutil.ReadFile(options.StatePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, &FileOperationError{Operation: "read file", FilePath: options.StatePath, Cause: err}
+	}
+
+	var state rateLimitState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, &FileOperationError{Operation: "unmarshal JSON", FilePath: options.StatePath, Cause: err}
+	}
+
+	if state.Day == r.day {
+		r.dayUsed = state.Used
+	}
+
+	return r, nil
+}
+
+// RateLimitStatePath returns where the daily budget of apiKey is persisted.
+// Only a hash of the key appears in the path.
+func RateLimitStatePath(baseOutputDir, apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return filepath.Join(baseOutputDir, rateLimitDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// UpdateFromHeaders updates the rate limiter from HTTP response headers.
+// A daily budget is reconciled with the usage the API reports, which is only
+// known when the response carries both its limit and the remaining count.
+func (r *RateLimiter) UpdateFromHeaders(headers http.Header) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	limit := -1
+	if limitStr := headers.Get("X-RateLimit-Limit"); limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil {
+			r.limit = parsed
+			limit = parsed
+		}
+	}
+
+	if remainingStr := headers.Get("X-RateLimit-Remaining"); remainingStr != "" {
+		if remaining, err := strconv.Atoi(remainingStr); err == nil {
+			r.remaining = remaining
+
+			// The API's count wins when it has seen more requests than we have
+			if used := limit - remaining; r.options.RequestsPerDay > 0 && limit >= 0 && used > r.dayUsed {
+				r.dayUsed = used
+				r.saveState()
+			}
+		}
+	}
+
+	if resetStr := headers.Get("X-RateLimit-Reset"); resetStr != "" {
+		if resetUnix, err := strconv.ParseInt(resetStr, 10, 64); err == nil {
+			r.resetTime = time.Unix(resetUnix, 0)
+		}
+	}
+}
+
+// WaitIfNeeded blocks until a request may be sent. It waits for a token from the
+// bucket, and until reset if the headers say the quota is nearly spent. Once the
+// daily budget is used up it returns a BudgetExhaustedError instead of blocking.
+func (r *RateLimiter) WaitIfNeeded(ctx context.Context) error {
+	r.mutex.RLock()
+	remaining := r.remaining
+	resetTime := r.resetTime
+	r.mutex.RUnlock()
+
+	// If we're low on requests, wait until reset.
+	if remaining <= 5 && time.Now().Before(resetTime) {
+		if err := sleepContext(ctx, time.Until(resetTime)+time.Second); err != nil {
+			return err
+		}
+	}
+
+	for {
+		wait, err := r.take()
+		if err != nil || wait == 0 {
+			return err
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// take spends one request from the budget, or returns how long to wait for the
+// bucket to refill
+func (r *RateLimiter) take() (time.Duration, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	now := time.Now()
+	if day := utcDay(now); day != r.day {
+		r.day = day
+		r.dayUsed = 0
+	}
+
+	if r.options.RequestsPerDay > 0 && r.dayUsed >= r.options.RequestsPerDay {
+		return 0, &BudgetExhaustedError{Budget: r.options.RequestsPerDay, ResetTime: nextUTCDay(now)}
+	}
+
+	if r.options.RequestsPerSecond > 0 {
+		r.tokens = math.Min(r.burst(), r.tokens+now.Sub(r.lastRefill).Seconds()*r.options.RequestsPerSecond)
+		r.lastRefill = now
+		if r.tokens < 1 {
+			return time.Duration((1 - r.tokens) / r.options.RequestsPerSecond * float64(time.Second)), nil
+		}
+		r.tokens--
+	}
+
+	r.dayUsed++
+	r.saveState()
+
+	return 0, nil
+}
+
+// burst is how many requests the bucket allows back to back
+func (r *RateLimiter) burst() float64 {
+	return math.Max(1, math.Floor(r.options.RequestsPerSecond))
+}
+
+// saveState persists the day's usage. Callers hold the mutex. A failure only
+// costs the budget's accuracy across restarts, so it is logged, not returned.
+func (r *RateLimiter) saveState() {
+	if r.options.StatePath == "" {
+		return
+	}
+
+	data, _ := json.Marshal(rateLimitState{Day: r.day, Used: r.dayUsed, UpdatedAt: time.Now()})
+	if err := os.MkdirAll(filepath.Dir(r.options.StatePath), 0755); err != nil {
+		log.Printf("Failed to save rate limit state: %v", &FileOperationError{Operation: "create directory", FilePath: filepath.Dir(r.options.StatePath), Cause: err})
+		return
+	}
+
+	tmpPath := r.options.StatePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		log.Printf("Failed to save rate limit state: %v", &FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err})
+		return
+	}
+	if err := os.Rename(tmpPath, r.options.StatePath); err != nil {
+		log.Printf("Failed to save rate limit state: %v", &FileOperationError{Operation: "rename file", FilePath: r.options.StatePath, Cause: err})
+	}
+}
+
+// GetStatus returns the current rate limit status.
+func (r *RateLimiter) GetStatus() (remaining, limit int, resetTime time.Time) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.remaining, r.limit, r.resetTime
+}
+
+// DailyUsage returns how many requests were sent today and the daily budget,
+// which is zero when there is none.
+func (r *RateLimiter) DailyUsage() (used, budget int) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.dayUsed, r.options.RequestsPerDay
+}
+
+// sleepContext waits for d, returning early if ctx is cancelled or a stop is requested
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-stopChan(ctx):
+		return ErrStopped
+	}
+}
+
+// utcDay names the UTC calendar day t falls on
+func utcDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+// nextUTCDay returns the start of the UTC day after t
+func nextUTCDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+}
@@ -0,0 +1,215 @@
+package newsapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/config"
+)
+
+func TestRateLimiter_PacesRequests(t *testing.T) {
+	limiter, err := NewBudgetRateLimiter(RateLimitOptions{RequestsPerSecond: 20})
+	if err != nil {
+		t.Fatalf("NewBudgetRateLimiter() unexpected error: %v", err)
+	}
+
+	// The first burst goes straight through, the next request waits for a token
+	start := time.Now()
+	for i := 0; i < 21; i++ {
+		if err := limiter.WaitIfNeeded(context.Background()); err != nil {
+			t.Fatalf("WaitIfNeeded() unexpected error: %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Expected the request after the burst to wait ~50ms, took %v", elapsed)
+	}
+}
+
+func TestRateLimiter_WaitCancelled(t *testing.T) {
+	limiter, _ := NewBudgetRateLimiter(RateLimitOptions{RequestsPerSecond: 0.1})
+	limiter.WaitIfNeeded(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := limiter.WaitIfNeeded(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestRateLimiter_DailyBudgetSurvivesRestart(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), rateLimitDir, "key.json")
+	options := RateLimitOptions{RequestsPerDay: 2, StatePath: statePath}
+
+	limiter, err := NewBudgetRateLimiter(options)
+	if err != nil {
+		t.Fatalf("NewBudgetRateLimiter() unexpected error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := limiter.WaitIfNeeded(context.Background()); err != nil {
+			t.Fatalf("WaitIfNeeded() unexpected error: %v", err)
+		}
+	}
+
+	budgetErr, ok := limiter.WaitIfNeeded(context.Background()).(*BudgetExhaustedError)
+	if !ok {
+		t.Fatal("Expected BudgetExhaustedError once the daily budget is spent")
+	}
+	if !budgetErr.ResetTime.Equal(nextUTCDay(time.Now())) {
+		t.Errorf("Expected budget to reset at the next UTC day, got %v", budgetErr.ResetTime)
+	}
+
+	restarted, err := NewBudgetRateLimiter(options)
+	if err != nil {
+		t.Fatalf("NewBudgetRateLimiter() unexpected error: %v", err)
+	}
+	if used, budget := restarted.DailyUsage(); used != 2 || budget != 2 {
+		t.Errorf("Expected 2 of 2 requests used after restart, got %d of %d", used, budget)
+	}
+	if _, ok := restarted.WaitIfNeeded(context.Background()).(*BudgetExhaustedError); !ok {
+		t.Error("Expected the restored budget to still be spent")
+	}
+}
+
+func TestRateLimiter_IgnoresPreviousDaysState(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "key.json")
+	if err := ioutil.WriteFile(statePath, []byte(`{"day":"2000-01-01","used":100}`), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	limiter, err := NewBudgetRateLimiter(RateLimitOptions{RequestsPerDay: 100, StatePath: statePath})
+	if err != nil {
+		t.Fatalf("NewBudgetRateLimiter() unexpected error: %v", err)
+	}
+	if err := limiter.WaitIfNeeded(context.Background()); err != nil {
+		t.Errorf("Expected a fresh budget for a new day, got %v", err)
+	}
+}
+
+func TestRateLimiter_CorruptStateIsAnError(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "key.json")
+	if err := ioutil.WriteFile(statePath, []byte(`{not json`), 0644); err != nil {
+		t.Fatalf("Failed to write state: %v", err)
+	}
+
+	_, err := NewBudgetRateLimiter(RateLimitOptions{RequestsPerDay: 100, StatePath: statePath})
+	if _, ok := err.(*FileOperationError); !ok {
+		t.Errorf("Expected FileOperationError, got %v", err)
+	}
+}
+
+func TestRateLimiter_ReconcilesWithHeaders(t *testing.T) {
+	limiter, _ := NewBudgetRateLimiter(RateLimitOptions{RequestsPerDay: 100})
+	limiter.WaitIfNeeded(context.Background())
+
+	// Without the API's limit its remaining count says nothing about usage
+	headers := make(http.Header)
+	headers.Set("X-RateLimit-Remaining", "40")
+	limiter.UpdateFromHeaders(headers)
+	if used, _ := limiter.DailyUsage(); used != 1 {
+		t.Errorf("Expected only our own request to count, got %d", used)
+	}
+
+	headers.Set("X-RateLimit-Limit", "100")
+	limiter.UpdateFromHeaders(headers)
+	if used, _ := limiter.DailyUsage(); used != 60 {
+		t.Errorf("Expected the API's count of 60 used requests to win, got %d", used)
+	}
+
+	// A higher remaining count does not give back requests already spent
+	headers.Set("X-RateLimit-Remaining", "90")
+	limiter.UpdateFromHeaders(headers)
+	if used, _ := limiter.DailyUsage(); used != 60 {
+		t.Errorf("Expected 60 used requests, got %d", used)
+	}
+}
+
+func TestRateLimiter_ReconcilesWithTheAPIsOwnLimit(t *testing.T) {
+	// Our budget is below the API's limit: 10 of its 1000 requests are used
+	limiter, _ := NewBudgetRateLimiter(RateLimitOptions{RequestsPerDay: 100})
+	headers := make(http.Header)
+	headers.Set("X-RateLimit-Limit", "1000")
+	headers.Set("X-RateLimit-Remaining", "990")
+	limiter.UpdateFromHeaders(headers)
+	if used, _ := limiter.DailyUsage(); used != 10 {
+		t.Errorf("Expected the API's count of 10 used requests, got %d", used)
+	}
+
+	// Our budget is above the API's limit: 5 of its 50 requests are used, which
+	// leaves most of our budget
+	limiter, _ = NewBudgetRateLimiter(RateLimitOptions{RequestsPerDay: 500})
+	headers.Set("X-RateLimit-Limit", "50")
+	headers.Set("X-RateLimit-Remaining", "45")
+	limiter.UpdateFromHeaders(headers)
+	if used, _ := limiter.DailyUsage(); used != 5 {
+		t.Errorf("Expected the API's count of 5 used requests, got %d", used)
+	}
+	if err := limiter.WaitIfNeeded(context.Background()); err != nil {
+		t.Errorf("Expected budget to be left, got %v", err)
+	}
+}
+
+func TestNewsAPIClient_RateLimitStatusBeforeFirstRequest(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.RequestsPerDay = 3
+	client := NewNewsAPIClientWithHTTPClient(cfg, &sequenceHTTPClient{})
+
+	if remaining, limit, reset := client.GetRateLimitStatus(); remaining != 0 || limit != 0 || !reset.IsZero() {
+		t.Errorf("Expected zero values, got %d, %d, %v", remaining, limit, reset)
+	}
+
+	// No limiter, and so no budget file, is made up for the empty key
+	if len(client.limiters) != 0 {
+		t.Errorf("Expected no limiter to be created, got %d", len(client.limiters))
+	}
+	if _, err := ioutil.ReadFile(RateLimitStatePath(cfg.OutputDir, "")); err == nil {
+		t.Error("Expected no budget to be persisted for the empty key")
+	}
+}
+
+func TestNewsAPIClient_SharesLimiterPerKey(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.RequestsPerSecond = 0
+	cfg.RequestsPerDay = 3
+
+	httpClient := &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, createMockNewsAPIResponse())},
+	}}
+	client := NewNewsAPIClientWithHTTPClient(cfg, httpClient)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest("test-key", "us"), 1)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	limited := 0
+	for err := range errs {
+		if _, ok := err.(*BudgetExhaustedError); ok {
+			limited++
+		}
+	}
+
+	if httpClient.calls() != 3 || limited != 2 {
+		t.Errorf("Expected 3 requests within budget and 2 rejected, got %d sent and %d rejected", httpClient.calls(), limited)
+	}
+
+	if _, err := ioutil.ReadFile(RateLimitStatePath(cfg.OutputDir, "test-key")); err != nil {
+		t.Errorf("Expected the daily budget to be persisted: %v", err)
+	}
+}
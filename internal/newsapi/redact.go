@@ -0,0 +1,94 @@
+package newsapi
+
+import (
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces secrets in URLs, errors, logs and serialized results
+const RedactedValue = "***"
+
+// APIKeyHeader is the header NewsAPI reads the API key from
+const APIKeyHeader = "X-Api-Key"
+
+// secretParamPattern matches API keys passed as query parameters or headers,
+// whether or not the key itself was registered
+var secretParamPattern = regexp.MustCompile(`(?i)((?:api_?key|x-api-key)["']?\s*[=:]\s*["']?)[^&\s"',}]+`)
+
+// minSecretLength keeps very short values from being registered, since redacting
+// them would mangle unrelated text
+const minSecretLength = 4
+
+var secrets = struct {
+	mutex  sync.RWMutex
+	values map[string]bool
+}{values: make(map[string]bool)}
+
+// RegisterSecret makes Redact replace every occurrence of secret. The client
+// registers each API key it sends, so errors built from its responses are safe
+// to log; callers can register keys up front as well.
+func RegisterSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secrets.mutex.RLock()
+	known := secrets.values[secret]
+	secrets.mutex.RUnlock()
+	if known {
+		return
+	}
+
+	secrets.mutex.Lock()
+	secrets.values[secret] = true
+	secrets.mutex.Unlock()
+}
+
+// Redact replaces registered secrets and apiKey parameters in s with RedactedValue
+func Redact(s string) string {
+	s = secretParamPattern.ReplaceAllString(s, "${1}"+RedactedValue)
+
+	secrets.mutex.RLock()
+	defer secrets.mutex.RUnlock()
+	for secret := range secrets.values {
+		if strings.Contains(s, secret) {
+			s = strings.Replace(s, secret, RedactedValue, -1)
+		}
+	}
+	return s
+}
+
+// RedactingWriter redacts secrets from everything written through it. Install it
+// with log.SetOutput so that no log line can carry an API key.
+type RedactingWriter struct {
+	w io.Writer
+}
+
+// NewRedactingWriter wraps w in a RedactingWriter
+func NewRedactingWriter(w io.Writer) *RedactingWriter {
+	return &RedactingWriter{w: w}
+}
+
+// Write implements io.Writer. It reports len(p) on success even though the
+// redacted output may differ in length.
+func (r *RedactingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// redactErrors renders errs as redacted strings for serialization
+func redactErrors(errs []error) []string {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = Redact(err.Error())
+	}
+	return messages
+}
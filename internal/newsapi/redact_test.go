@@ -0,0 +1,178 @@
+package newsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/config"
+)
+
+const secretTestKey = "s3cr3t-newsapi-key-0123456789"
+
+func TestRedact(t *testing.T) {
+	RegisterSecret(secretTestKey)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"query parameter", "https://newsapi.org/v2/everything?apiKey=abcdef&q=go", "https://newsapi.org/v2/everything?apiKey=***&q=go"},
+		{"snake case parameter", "api_key=abcdef", "api_key=***"},
+		{"header", "X-Api-Key: abcdef", "X-Api-Key: ***"},
+		{"json field", `{"api_key":"abcdef"}`, `{"api_key":"***"}`},
+		{"registered key", "request with " + secretTestKey + " failed", "request with *** failed"},
+		{"nothing to redact", "page 2: connection reset", "page 2: connection reset"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.input); got != tt.want {
+				t.Errorf("Redact(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactingWriter(t *testing.T) {
+	RegisterSecret(secretTestKey)
+
+	var buf bytes.Buffer
+	logger := log.New(NewRedactingWriter(&buf), "", 0)
+	logger.Printf("Failed to download news: GET https://newsapi.org/v2/top-headlines?apiKey=%s: %s", secretTestKey, secretTestKey)
+
+	if strings.Contains(buf.String(), secretTestKey) {
+		t.Errorf("Log line leaked the API key: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), RedactedValue) {
+		t.Errorf("Expected a redacted marker in: %s", buf.String())
+	}
+}
+
+func TestNewsAPIClient_SendsKeyInHeader(t *testing.T) {
+	mockClient := NewMockHTTPClient()
+	mockClient.SetResponse("*", &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"status": "error", "code": "apiKeyInvalid", "message": "Your API key ` + secretTestKey + ` is invalid."}`)),
+		Header:     make(http.Header),
+	})
+
+	client := NewNewsAPIClientWithHTTPClient(config.DefaultConfig(), mockClient)
+	req := NewDownloadRequest(secretTestKey, "us")
+
+	fullURL, err := client.buildURL(req, 1)
+	if err != nil {
+		t.Fatalf("buildURL() unexpected error: %v", err)
+	}
+	if strings.Contains(fullURL, secretTestKey) || strings.Contains(fullURL, "apiKey") {
+		t.Errorf("Expected no API key in URL, got %s", fullURL)
+	}
+
+	_, _, err = client.FetchNewsPage(context.Background(), req, 1)
+	if err == nil {
+		t.Fatal("Expected an error, got nil")
+	}
+
+	if got := mockClient.LastHeader().Get(APIKeyHeader); got != secretTestKey {
+		t.Errorf("Expected the key in the %s header, got '%s'", APIKeyHeader, got)
+	}
+
+	var apiErr *NewsAPIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected NewsAPIError, got %T", err)
+	}
+	if strings.Contains(apiErr.URL, secretTestKey) || strings.Contains(err.Error(), secretTestKey) {
+		t.Errorf("Error leaked the API key: %v (URL %s)", err, apiErr.URL)
+	}
+}
+
+// getOnlyHTTPClient implements HTTPClient without Do, like clients written
+// before the key moved into a header
+type getOnlyHTTPClient struct {
+	url string
+}
+
+func (g *getOnlyHTTPClient) Get(url string) (*http.Response, error) {
+	return g.GetWithContext(context.Background(), url)
+}
+
+func (g *getOnlyHTTPClient) GetWithContext(ctx context.Context, url string) (*http.Response, error) {
+	g.url = url
+	return &http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Body:       ioutil.NopCloser(strings.NewReader(`{"status": "error", "code": "apiKeyInvalid", "message": "Your API key is invalid."}`)),
+		Header:     make(http.Header),
+	}, nil
+}
+
+func TestNewsAPIClient_SendsKeyThroughGetOnlyClients(t *testing.T) {
+	httpClient := &getOnlyHTTPClient{}
+	client := NewNewsAPIClientWithHTTPClient(config.DefaultConfig(), httpClient)
+
+	_, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest(secretTestKey, "us"), 1)
+	var apiErr *NewsAPIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected NewsAPIError, got %v", err)
+	}
+
+	// Without headers the key travels in the query string, but still never surfaces
+	if !strings.Contains(httpClient.url, "apiKey="+secretTestKey) {
+		t.Errorf("Expected the key in the query string, got %s", httpClient.url)
+	}
+	if strings.Contains(apiErr.URL, secretTestKey) || strings.Contains(err.Error(), secretTestKey) {
+		t.Errorf("Error leaked the API key: %v (URL %s)", err, apiErr.URL)
+	}
+}
+
+func TestErrorsAndResultsAreRedacted(t *testing.T) {
+	RegisterSecret(secretTestKey)
+
+	rateErr := &RateLimitError{RetryAfter: time.Second, Message: "rate limited for key " + secretTestKey}
+	if strings.Contains(rateErr.Error(), secretTestKey) {
+		t.Errorf("RateLimitError leaked the API key: %v", rateErr)
+	}
+
+	result := &DownloadResult{
+		TotalArticles: 3,
+		Errors: []error{
+			fmt.Errorf("page 2: %w", &NewsAPIError{StatusCode: 500, Code: "unexpectedError", Message: "bad key " + secretTestKey}),
+			fmt.Errorf("page 3: GET https://newsapi.org/v2/everything?apiKey=%s: timeout", secretTestKey),
+		},
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+	if strings.Contains(string(data), secretTestKey) {
+		t.Errorf("Serialized result leaked the API key: %s", data)
+	}
+
+	var decoded struct {
+		TotalArticles int      `json:"total_articles"`
+		Errors        []string `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if decoded.TotalArticles != 3 || len(decoded.Errors) != 2 || !strings.Contains(decoded.Errors[0], "page 2") {
+		t.Errorf("Unexpected serialized result: %s", data)
+	}
+
+	data, err = json.Marshal(NewDownloadRequest(secretTestKey, "us"))
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+	if strings.Contains(string(data), secretTestKey) || !strings.Contains(string(data), `"api_key":"***"`) {
+		t.Errorf("Serialized request did not redact the API key: %s", data)
+	}
+}
@@ -0,0 +1,135 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultRetryBaseDelay is the backoff before the first retry
+	defaultRetryBaseDelay = 1 * time.Second
+	// defaultRetryMaxDelay caps the backoff between two attempts
+	defaultRetryMaxDelay = 30 * time.Second
+)
+
+// RetryPolicy retries transient request failures with exponential backoff and jitter.
+// Only network timeouts, dropped connections and 5xx responses are retried; client
+// errors such as apiKeyInvalid and rate limiting are returned immediately.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+
+	// randInt63n is the jitter source, replaceable in tests
+	randInt63n func(n int64) int64
+}
+
+// NewRetryPolicy creates a retry policy allowing up to maxRetries retries after the first attempt
+func NewRetryPolicy(maxRetries int) *RetryPolicy {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	return &RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  defaultRetryBaseDelay,
+		MaxDelay:   defaultRetryMaxDelay,
+		randInt63n: rand.Int63n,
+	}
+}
+
+// Backoff returns the delay before the given retry (1 for the first retry).
+// The delay doubles with every retry up to MaxDelay, and the upper half of it is
+// randomised so that concurrent clients do not retry in lockstep.
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	if retry < 1 {
+		retry = 1
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+
+	randInt63n := p.randInt63n
+	if randInt63n == nil {
+		randInt63n = rand.Int63n
+	}
+	return half + time.Duration(randInt63n(int64(half)+1))
+}
+
+// Do calls fn until it succeeds, fails with a non-retryable error, or the retry
+// budget is used up. It returns the number of attempts made and the last error.
+func (p *RetryPolicy) Do(ctx context.Context, fn func() error) (int, error) {
+	attempts := 0
+	for {
+		attempts++
+		err := fn()
+		if err == nil {
+			return attempts, nil
+		}
+
+		// Never retry once the caller has given up
+		if ctx.Err() != nil {
+			return attempts, err
+		}
+
+		if attempts > p.MaxRetries || !IsRetryable(err) {
+			return attempts, err
+		}
+
+		select {
+		case <-time.After(p.Backoff(attempts)):
+		case <-ctx.Done():
+			return attempts, err
+		}
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		// Rate limiting has its own wait-until-reset handling
+		return false
+	}
+
+	var apiErr *NewsAPIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
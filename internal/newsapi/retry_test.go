@@ -0,0 +1,132 @@
+package newsapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// timeoutError implements net.Error with Timeout() == true
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"nil error", nil, false},
+		{"server error", &NewsAPIError{StatusCode: 500, Code: "unexpectedError"}, true},
+		{"bad gateway", &NewsAPIError{StatusCode: 502}, true},
+		{"invalid api key", &NewsAPIError{StatusCode: 401, Code: "apiKeyInvalid"}, false},
+		{"bad request", &NewsAPIError{StatusCode: 400, Code: "parametersMissing"}, false},
+		{"rate limited", &RateLimitError{RetryAfter: time.Second}, false},
+		{"budget exhausted", &BudgetExhaustedError{Budget: 100, ResetTime: time.Now().Add(time.Hour)}, false},
+		{"network timeout", fmt.Errorf("failed to make HTTP request: %w", timeoutError{}), true},
+		{"connection reset", fmt.Errorf("failed to make HTTP request: %w", &net.OpError{Op: "read", Err: syscall.ECONNRESET}), true},
+		{"truncated body", fmt.Errorf("failed to read response body: %w", io.ErrUnexpectedEOF), true},
+		{"cancelled", fmt.Errorf("failed to make HTTP request: %w", context.Canceled), false},
+		{"plain error", fmt.Errorf("connection refused"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.expected {
+				t.Errorf("IsRetryable(%v) = %v, expected %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := NewRetryPolicy(5)
+	policy.BaseDelay = 100 * time.Millisecond
+	policy.MaxDelay = 1 * time.Second
+
+	// Without jitter the delay is exactly half of the exponential step
+	policy.randInt63n = func(n int64) int64 { return 0 }
+	expected := []time.Duration{50, 100, 200, 400, 500, 500}
+	for i, want := range expected {
+		if got := policy.Backoff(i + 1); got != want*time.Millisecond {
+			t.Errorf("Backoff(%d) = %v, expected %v", i+1, got, want*time.Millisecond)
+		}
+	}
+
+	// With maximum jitter the delay reaches the full step
+	policy.randInt63n = func(n int64) int64 { return n - 1 }
+	if got := policy.Backoff(2); got != 200*time.Millisecond {
+		t.Errorf("Backoff(2) with full jitter = %v, expected %v", got, 200*time.Millisecond)
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	policy := NewRetryPolicy(3)
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = time.Millisecond
+
+	t.Run("succeeds after transient failures", func(t *testing.T) {
+		calls := 0
+		attempts, err := policy.Do(context.Background(), func() error {
+			calls++
+			if calls < 3 {
+				return &NewsAPIError{StatusCode: 503}
+			}
+			return nil
+		})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if attempts != 3 {
+			t.Errorf("Expected 3 attempts, got %d", attempts)
+		}
+	})
+
+	t.Run("gives up after max retries", func(t *testing.T) {
+		attempts, err := policy.Do(context.Background(), func() error {
+			return &NewsAPIError{StatusCode: 500}
+		})
+
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+		if attempts != 4 {
+			t.Errorf("Expected 4 attempts (1 + 3 retries), got %d", attempts)
+		}
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		attempts, err := policy.Do(context.Background(), func() error {
+			return &NewsAPIError{StatusCode: 401, Code: "apiKeyInvalid"}
+		})
+
+		if err == nil {
+			t.Fatal("Expected an error, got nil")
+		}
+		if attempts != 1 {
+			t.Errorf("Expected 1 attempt, got %d", attempts)
+		}
+	})
+
+	t.Run("stops when context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts, _ := policy.Do(ctx, func() error {
+			cancel()
+			return &NewsAPIError{StatusCode: 500}
+		})
+
+		if attempts != 1 {
+			t.Errorf("Expected 1 attempt after cancellation, got %d", attempts)
+		}
+	})
+}
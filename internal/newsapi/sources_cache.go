@@ -0,0 +1,82 @@
+package newsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// sourcesCacheDir is the subdirectory of OutputDir holding cached source listings
+const sourcesCacheDir = "sources"
+
+// SourcesCacheEntry is the on-disk representation of a cached sources listing
+type SourcesCacheEntry struct {
+	FetchedAt time.Time    `json:"fetched_at"`
+	Category  string       `json:"category,omitempty"`
+	Language  string       `json:"language,omitempty"`
+	Country   string       `json:"country,omitempty"`
+	Sources   []SourceInfo `json:"sources"`
+}
+
+// SourcesCachePath returns the cache file used for the given filters under baseOutputDir.
+// Each combination of filters is cached separately; unset filters are recorded as "all".
+func SourcesCachePath(baseOutputDir string, req *SourcesRequest) string {
+	parts := []string{"sources"}
+	for _, filter := range []string{req.Category, req.Language, req.Country} {
+		if filter == "" {
+			filter = "all"
+		}
+		parts = append(parts, strings.ToLower(filter))
+	}
+	return filepath.Join(baseOutputDir, sourcesCacheDir, strings.Join(parts, "_")+".json")
+}
+
+// LoadSourcesCache reads a cached sources listing.
+// It returns nil without an error when the cache is missing or older than maxAge.
+func LoadSourcesCache(filePath string, maxAge time.Duration) (*SourcesCacheEntry, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, &FileOperationError{Operation: "read file", FilePath: filePath, Cause: err}
+	}
+
+	var entry SourcesCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, &FileOperationError{Operation: "unmarshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	if maxAge > 0 && time.Since(entry.FetchedAt) > maxAge {
+		return nil, nil
+	}
+
+	return &entry, nil
+}
+
+// SaveSourcesCache writes a sources listing to the cache file, creating parent directories as needed
+func SaveSourcesCache(filePath string, entry *SourcesCacheEntry) error {
+	if entry == nil {
+		return fmt.Errorf("sources cache entry cannot be nil")
+	}
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &FileOperationError{Operation: "create directory", FilePath: dir, Cause: err}
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return &FileOperationError{Operation: "marshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
+		return &FileOperationError{Operation: "write file", FilePath: filePath, Cause: err}
+	}
+
+	return nil
+}
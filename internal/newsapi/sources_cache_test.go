@@ -0,0 +1,59 @@
+package newsapi
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSourcesCachePath(t *testing.T) {
+	path := SourcesCachePath("/tmp/news", &SourcesRequest{Category: "technology", Country: "US"})
+	expected := filepath.Join("/tmp/news", "sources", "sources_technology_all_us.json")
+
+	if path != expected {
+		t.Errorf("Expected cache path '%s', got '%s'", expected, path)
+	}
+}
+
+func TestSourcesCacheRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sources", "sources_all_all_all.json")
+
+	entry := &SourcesCacheEntry{
+		FetchedAt: time.Now(),
+		Sources: []SourceInfo{
+			{ID: "bbc-news", Name: "BBC News", Category: "general", Language: "en", Country: "gb"},
+		},
+	}
+
+	if err := SaveSourcesCache(path, entry); err != nil {
+		t.Fatalf("Failed to save sources cache: %v", err)
+	}
+
+	loaded, err := LoadSourcesCache(path, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to load sources cache: %v", err)
+	}
+
+	if loaded == nil || len(loaded.Sources) != 1 || loaded.Sources[0].ID != "bbc-news" {
+		t.Fatalf("Unexpected cache entry: %+v", loaded)
+	}
+}
+
+func TestLoadSourcesCache_MissingOrExpired(t *testing.T) {
+	dir := t.TempDir()
+
+	loaded, err := LoadSourcesCache(filepath.Join(dir, "missing.json"), time.Hour)
+	if err != nil || loaded != nil {
+		t.Errorf("Expected nil entry and no error for missing cache, got %+v, %v", loaded, err)
+	}
+
+	path := filepath.Join(dir, "old.json")
+	if err := SaveSourcesCache(path, &SourcesCacheEntry{FetchedAt: time.Now().Add(-2 * time.Hour)}); err != nil {
+		t.Fatalf("Failed to save sources cache: %v", err)
+	}
+
+	loaded, err = LoadSourcesCache(path, time.Hour)
+	if err != nil || loaded != nil {
+		t.Errorf("Expected nil entry and no error for expired cache, got %+v, %v", loaded, err)
+	}
+}
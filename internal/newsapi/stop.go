@@ -0,0 +1,35 @@
+package newsapi
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStopped is returned by a download that was asked to stop between pages
+var ErrStopped = errors.New("download stopped")
+
+type stopKey struct{}
+
+// WithStop returns a context that asks downloads to stop once stop is closed. Unlike
+// cancelling the context, this lets the page in flight finish saving and publishing,
+// and the download returns ErrStopped at the next page boundary with its checkpoint
+// in place so a later run can resume.
+func WithStop(ctx context.Context, stop <-chan struct{}) context.Context {
+	return context.WithValue(ctx, stopKey{}, stop)
+}
+
+// StopRequested reports whether the stop channel attached by WithStop has been closed
+func StopRequested(ctx context.Context) bool {
+	select {
+	case <-stopChan(ctx):
+		return true
+	default:
+		return false
+	}
+}
+
+// stopChan returns the stop channel attached to ctx, or nil, which never fires
+func stopChan(ctx context.Context) <-chan struct{} {
+	stop, _ := ctx.Value(stopKey{}).(<-chan struct{})
+	return stop
+}
@@ -0,0 +1,201 @@
+package newsapi
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// responseBody is a response body that fails with a ResponseTooLargeError once
+// more than limit bytes have been read. It remembers the first read error, so a
+// decoding failure caused by the transport can be told apart from a malformed body.
+type responseBody struct {
+	body  io.ReadCloser
+	url   string
+	limit int64
+	read  int64
+	err   error
+}
+
+// newResponseBody caps body at limit bytes; a limit of zero or less disables the cap
+func newResponseBody(body io.ReadCloser, url string, limit int64) *responseBody {
+	return &responseBody{body: body, url: url, limit: limit}
+}
+
+// LimitResponseBody caps body at limit bytes the way the client caps API
+// responses, for other providers: reading past the limit fails with a
+// ResponseTooLargeError. A limit of zero or less disables the cap.
+func LimitResponseBody(body io.ReadCloser, url string, limit int64) io.ReadCloser {
+	return newResponseBody(body, url, limit)
+}
+
+func (b *responseBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	// Read at most one byte past the limit, enough to tell the body is too large
+	if b.limit > 0 && int64(len(p)) > b.limit-b.read+1 {
+		p = p[:b.limit-b.read+1]
+	}
+
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+	if b.limit > 0 && b.read > b.limit {
+		b.err = &ResponseTooLargeError{Limit: b.limit, URL: b.url}
+		return 0, b.err
+	}
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+func (b *responseBody) Close() error {
+	return b.body.Close()
+}
+
+// decodeError explains why decoding the body failed. Read errors, such as a
+// dropped connection or an oversized body, are wrapped so that callers can
+// recognise and retry them. Anything else means the body is not valid JSON; that
+// error is not wrapped, because a body that ends early decodes to
+// io.ErrUnexpectedEOF, which would otherwise look like a retryable network error.
+func (b *responseBody) decodeError(err error) error {
+	if b.err != nil {
+		return fmt.Errorf("failed to read response body: %w", b.err)
+	}
+	return fmt.Errorf("failed to unmarshal JSON response: %v", err)
+}
+
+// decodeNewsAPIResponse reads a NewsAPI response one article at a time, so the
+// decoder buffers a single article rather than the whole body
+func decodeNewsAPIResponse(r io.Reader) (*NewsAPIResponse, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
+	}
+
+	resp := &NewsAPIResponse{}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var field interface{}
+		switch token {
+		case "status":
+			field = &resp.Status
+		case "totalResults":
+			field = &resp.TotalResults
+		case "code":
+			field = &resp.Code
+		case "message":
+			field = &resp.Message
+		case "articles":
+			if resp.Articles, err = decodeArticles(dec); err != nil {
+				return nil, err
+			}
+			continue
+		default:
+			field = &json.RawMessage{}
+		}
+
+		if err := dec.Decode(field); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// decodeArticles reads the articles array, which may be null
+func decodeArticles(dec *json.Decoder) ([]Article, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected articles to be an array, got %v", token)
+	}
+
+	articles := make([]Article, 0)
+	for dec.More() {
+		var article Article
+		if err := dec.Decode(&article); err != nil {
+			return nil, err
+		}
+		articles = append(articles, article)
+	}
+
+	if err := expectDelim(dec, ']'); err != nil {
+		return nil, err
+	}
+	return articles, nil
+}
+
+// expectDelim reads the next token and checks that it is delim
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if got, ok := token.(json.Delim); !ok || got != delim {
+		return fmt.Errorf("expected '%v', got %v", delim, token)
+	}
+	return nil
+}
+
+// encodeNewsAPIResponse writes resp as indented JSON one article at a time. The
+// output is identical to json.MarshalIndent(resp, "", "  ") without holding the
+// whole document in memory.
+func encodeNewsAPIResponse(w io.Writer, resp *NewsAPIResponse) error {
+	bw := bufio.NewWriter(w)
+
+	status, err := json.Marshal(resp.Status)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(bw, "{\n  \"status\": %s,\n  \"totalResults\": %d,\n  \"articles\": ", status, resp.TotalResults)
+
+	switch {
+	case resp.Articles == nil:
+		bw.WriteString("null")
+	case len(resp.Articles) == 0:
+		bw.WriteString("[]")
+	default:
+		bw.WriteString("[\n")
+		for i := range resp.Articles {
+			data, err := json.MarshalIndent(&resp.Articles[i], "    ", "  ")
+			if err != nil {
+				return err
+			}
+			bw.WriteString("    ")
+			bw.Write(data)
+			if i < len(resp.Articles)-1 {
+				bw.WriteByte(',')
+			}
+			bw.WriteByte('\n')
+		}
+		bw.WriteString("  ]")
+	}
+
+	code, err := json.Marshal(resp.Code)
+	if err != nil {
+		return err
+	}
+	message, err := json.Marshal(resp.Message)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(bw, ",\n  \"code\": %s,\n  \"message\": %s\n}", code, message)
+
+	return bw.Flush()
+}
@@ -0,0 +1,124 @@
+package newsapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-news-agg/internal/config"
+)
+
+func TestEncodeNewsAPIResponse_MatchesMarshalIndent(t *testing.T) {
+	withArticles := createMockNewsAPIResponse()
+	withArticles.Articles[0].Title = `Quotes "and" <tags> & unicode – ok`
+
+	for _, resp := range []*NewsAPIResponse{
+		withArticles,
+		{Status: "ok", Articles: []Article{}},
+		{Status: "error", Code: "apiKeyInvalid", Message: "bad key"},
+	} {
+		var streamed bytes.Buffer
+		if err := encodeNewsAPIResponse(&streamed, resp); err != nil {
+			t.Fatalf("encodeNewsAPIResponse() unexpected error: %v", err)
+		}
+
+		expected, _ := json.MarshalIndent(resp, "", "  ")
+		if streamed.String() != string(expected) {
+			t.Errorf("Streamed output differs from MarshalIndent:\n%s\nwant:\n%s", streamed.String(), expected)
+		}
+	}
+}
+
+func TestDecodeNewsAPIResponse(t *testing.T) {
+	resp := createMockNewsAPIResponse()
+	data := mustMarshalResponse(t, resp)
+
+	decoded, err := decodeNewsAPIResponse(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("decodeNewsAPIResponse() unexpected error: %v", err)
+	}
+	if decoded.Status != resp.Status || decoded.TotalResults != resp.TotalResults || len(decoded.Articles) != len(resp.Articles) {
+		t.Fatalf("Unexpected decoded response: %+v", decoded)
+	}
+	if decoded.Articles[0] != resp.Articles[0] {
+		t.Errorf("Expected article %+v, got %+v", resp.Articles[0], decoded.Articles[0])
+	}
+
+	// Unknown fields are skipped and a null article list is allowed
+	decoded, err = decodeNewsAPIResponse(strings.NewReader(`{"extra": {"nested": [1, 2]}, "status": "ok", "articles": null, "totalResults": 0}`))
+	if err != nil || decoded.Status != "ok" || decoded.Articles != nil {
+		t.Errorf("Unexpected result for a null article list: %+v, %v", decoded, err)
+	}
+
+	for _, body := range []string{``, `[]`, `{"articles": {}}`, `{"status": "ok", "articles": [{"title": `} {
+		if _, err := decodeNewsAPIResponse(strings.NewReader(body)); err == nil {
+			t.Errorf("Expected an error decoding %q", body)
+		}
+	}
+}
+
+func TestNewsAPIClient_RejectsOversizedResponses(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RequestsPerSecond = 0
+	cfg.MaxResponseBytes = 512
+
+	// A body that is streamed without a declared length is cut off at the limit
+	large := createMockNewsAPIResponse()
+	large.Articles[0].Content = strings.Repeat("x", 1024)
+	client := NewNewsAPIClientWithHTTPClient(cfg, &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, large)},
+	}})
+
+	_, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest("test-key", "us"), 1)
+	var tooLarge *ResponseTooLargeError
+	if !errors.As(err, &tooLarge) || tooLarge.Limit != 512 {
+		t.Fatalf("Expected a ResponseTooLargeError, got %v", err)
+	}
+	if IsRetryable(err) {
+		t.Error("Expected an oversized response not to be retried")
+	}
+
+	// A body that declares its length is refused before it is read
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", fmt.Sprint(4096))
+		w.Write(bytes.Repeat([]byte(" "), 4096))
+	}))
+	defer server.Close()
+
+	client = NewNewsAPIClientWithHTTPClient(cfg, &defaultHTTPClient{client: server.Client()})
+	client.baseURL = server.URL + "/v2"
+	if _, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest("test-key", "us"), 1); !errors.As(err, &tooLarge) {
+		t.Errorf("Expected a ResponseTooLargeError for a declared length, got %v", err)
+	}
+
+	// Responses within the limit are unaffected
+	cfg.MaxResponseBytes = 0
+	client = NewNewsAPIClientWithHTTPClient(cfg, &sequenceHTTPClient{responses: []stubResponse{
+		{status: http.StatusOK, body: mustMarshalResponse(t, large)},
+	}})
+	if _, _, err := client.FetchNewsPage(context.Background(), NewDownloadRequest("test-key", "us"), 1); err != nil {
+		t.Errorf("Expected no limit when MaxResponseBytes is 0, got %v", err)
+	}
+}
+
+func TestNewsDownloader_SkipsOversizedPages(t *testing.T) {
+	downloader, _, req := newFaultyDownloader(t)
+	downloader.config.MaxResponseBytes = 1
+	downloader.config.Concurrency = 1
+
+	result, err := downloader.DownloadAllNewsToFile(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Expected the oversized page to be skipped, got: %v", err)
+	}
+
+	var tooLarge *ResponseTooLargeError
+	if len(result.Errors) != 1 || !errors.As(result.Errors[0], &tooLarge) || result.PageAttempts[1] != 1 {
+		t.Errorf("Expected page 1 to fail once with a ResponseTooLargeError, got %v and %v", result.Errors, result.PageAttempts)
+	}
+}
@@ -0,0 +1,107 @@
+package newsapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// watermarkDir is the subdirectory of OutputDir holding per-query watermarks
+const watermarkDir = ".watermarks"
+
+// Watermark records the newest article publication time seen for a query, so the
+// next scheduled run only asks for articles published since then
+type Watermark struct {
+	QueryKey    string    `json:"query_key"`
+	PublishedAt time.Time `json:"published_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WatermarkKey identifies the query a request describes, ignoring the time window
+// and paging so that consecutive runs of the same query share a watermark
+func (r *DownloadRequest) WatermarkKey() string {
+	identity := struct {
+		Endpoint       Endpoint `json:"endpoint"`
+		Query          string   `json:"query"`
+		SearchIn       []string `json:"search_in"`
+		Country        string   `json:"country"`
+		Category       string   `json:"category"`
+		Sources        []string `json:"sources"`
+		Domains        []string `json:"domains"`
+		ExcludeDomains []string `json:"exclude_domains"`
+		Language       string   `json:"language"`
+	}{
+		Endpoint:       r.EffectiveEndpoint(),
+		Query:          r.Query,
+		SearchIn:       r.SearchIn,
+		Country:        r.Country,
+		Category:       r.Category,
+		Sources:        r.Sources,
+		Domains:        r.Domains,
+		ExcludeDomains: r.ExcludeDomains,
+		Language:       r.Language,
+	}
+
+	return hashIdentity(identity)
+}
+
+// WatermarkPath returns where the watermark for a query key is stored
+func WatermarkPath(baseOutputDir, queryKey string) string {
+	return filepath.Join(baseOutputDir, watermarkDir, queryKey+".json")
+}
+
+// LoadWatermark reads a watermark file. It returns nil without an error when none exists.
+func LoadWatermark(filePath string) (*Watermark, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, &FileOperationError{Operation: "read file", FilePath: filePath, Cause: err}
+	}
+
+	var watermark Watermark
+	if err := json.Unmarshal(data, &watermark); err != nil {
+		return nil, &FileOperationError{Operation: "unmarshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	return &watermark, nil
+}
+
+// SaveWatermark writes a watermark file atomically
+func SaveWatermark(filePath string, watermark *Watermark) error {
+	if watermark == nil {
+		return fmt.Errorf("watermark cannot be nil")
+	}
+
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return &FileOperationError{Operation: "create directory", FilePath: dir, Cause: err}
+	}
+
+	data, err := json.MarshalIndent(watermark, "", "  ")
+	if err != nil {
+		return &FileOperationError{Operation: "marshal JSON", FilePath: filePath, Cause: err}
+	}
+
+	tmpPath := filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, data, 0644); err != nil {
+		return &FileOperationError{Operation: "write file", FilePath: tmpPath, Cause: err}
+	}
+
+	if err := os.Rename(tmpPath, filePath); err != nil {
+		return &FileOperationError{Operation: "rename file", FilePath: filePath, Cause: err}
+	}
+
+	return nil
+}
+
+// defaultFrom is the window start used for a query that has no watermark yet:
+// the start of yesterday, in local time
+func defaultFrom(now time.Time) time.Time {
+	yesterday := now.AddDate(0, 0, -1)
+	return time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 0, 0, 0, 0, yesterday.Location())
+}
@@ -0,0 +1,59 @@
+package newsapi
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDownloadRequest_WatermarkKey(t *testing.T) {
+	req := NewDownloadRequest("test-key", "us")
+	req.Query = "ai"
+
+	otherWindow := *req
+	otherWindow.From = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	otherWindow.PageSize = 100
+	otherWindow.StartPage = 3
+
+	otherQuery := *req
+	otherQuery.Query = "climate"
+
+	if req.WatermarkKey() != otherWindow.WatermarkKey() {
+		t.Error("Expected watermark key to ignore the time window and paging")
+	}
+
+	if req.WatermarkKey() == otherQuery.WatermarkKey() {
+		t.Error("Expected different queries to have different watermark keys")
+	}
+}
+
+func TestWatermarkRoundTrip(t *testing.T) {
+	path := WatermarkPath(t.TempDir(), "abc123")
+
+	loaded, err := LoadWatermark(path)
+	if err != nil || loaded != nil {
+		t.Fatalf("Expected no watermark before saving, got %+v, %v", loaded, err)
+	}
+
+	publishedAt := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	if err := SaveWatermark(path, &Watermark{QueryKey: "abc123", PublishedAt: publishedAt}); err != nil {
+		t.Fatalf("Failed to save watermark: %v", err)
+	}
+
+	loaded, err = LoadWatermark(path)
+	if err != nil {
+		t.Fatalf("Failed to load watermark: %v", err)
+	}
+
+	if !loaded.PublishedAt.Equal(publishedAt) {
+		t.Errorf("Expected watermark %v, got %v", publishedAt, loaded.PublishedAt)
+	}
+}
+
+func TestDefaultFrom(t *testing.T) {
+	now := time.Date(2024, 3, 1, 15, 4, 5, 0, time.UTC)
+	expected := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+
+	if got := defaultFrom(now); !got.Equal(expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+}
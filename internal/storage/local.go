@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go-news-agg/pkg/utils"
+)
+
+// tmpSuffix marks files that are still being written
+const tmpSuffix = ".tmp"
+
+// Local stores objects as files under a root directory
+type Local struct {
+	root string
+}
+
+// NewLocal returns a storage keeping objects under root. URIs are plain file
+// paths, as published before storage backends existed.
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+// path returns the file holding key
+func (l *Local) path(key string) string {
+	return filepath.Join(l.root, filepath.FromSlash(key))
+}
+
+// Put implements Storage.Put. The object is written under a unique temporary
+// name in the same directory, flushed to disk and renamed into place, so a crash or a
+// full disk never leaves a truncated file under key.
+func (l *Local) Put(ctx context.Context, key string, body io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	path := l.path(key)
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	// Concurrent puts of the same key each get their own temporary file
+	file, err := ioutil.TempFile(dir, filepath.Base(path)+".*"+tmpSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for '%s': %w", path, err)
+	}
+	tmpPath := file.Name()
+
+	_, err = io.Copy(file, body)
+	if err == nil {
+		// TempFile creates the file readable by its owner only
+		err = file.Chmod(0644)
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write '%s': %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename '%s': %w", tmpPath, err)
+	}
+
+	// Make the rename durable before the object is reported as stored
+	if err := utils.SyncDir(dir); err != nil {
+		return fmt.Errorf("failed to sync directory '%s': %w", dir, err)
+	}
+	return nil
+}
+
+// Get implements Storage.Get
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(l.path(key))
+	if os.IsNotExist(err) {
+		return nil, notFound(key)
+	}
+	return file, err
+}
+
+// List implements Storage.List. Files still being written are left out.
+func (l *Local) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	// Only walk the deepest directory the prefix names
+	start := l.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		start = l.path(prefix[:i])
+	}
+
+	objects := make([]ObjectInfo, 0)
+	err := filepath.Walk(start, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(path, tmpSuffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.root, path)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list '%s': %w", start, err)
+	}
+
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// Delete implements Storage.Delete
+func (l *Local) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	if err := os.Remove(l.path(key)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete '%s': %w", key, err)
+	}
+	return nil
+}
+
+// Stat implements Storage.Stat
+func (l *Local) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(l.path(key))
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return nil, notFound(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat '%s': %w", key, err)
+	}
+	return &ObjectInfo{Key: key, Size: info.Size(), ModTime: info.ModTime()}, nil
+}
+
+// URI implements Storage.URI
+func (l *Local) URI(key string) string {
+	return l.path(key)
+}
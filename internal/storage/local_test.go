@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// failingReader returns part of a body, then fails as a full disk or dropped stream would
+type failingReader struct {
+	data string
+	read bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, errors.New("no space left on device")
+	}
+	r.read = true
+	return copy(p, r.data), nil
+}
+
+// exerciseStorage runs the operations every Storage supports
+func exerciseStorage(t *testing.T, s Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	keys := []string{"2024/01/page1.json", "2024/01/page2.json", "2024/02/page1.json", "other.txt"}
+	for _, key := range keys {
+		if err := s.Put(ctx, key, strings.NewReader("content of "+key)); err != nil {
+			t.Fatalf("Put(%s) unexpected error: %v", key, err)
+		}
+	}
+
+	reader, err := s.Get(ctx, "2024/01/page2.json")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	data, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	if string(data) != "content of 2024/01/page2.json" {
+		t.Errorf("Unexpected content %q", data)
+	}
+
+	info, err := s.Stat(ctx, "other.txt")
+	if err != nil || info.Size != int64(len("content of other.txt")) || info.ModTime.IsZero() {
+		t.Errorf("Unexpected Stat() result %+v, %v", info, err)
+	}
+
+	objects, err := s.List(ctx, "2024/01/")
+	if err != nil || len(objects) != 2 || objects[0].Key != "2024/01/page1.json" || objects[1].Key != "2024/01/page2.json" {
+		t.Errorf("Unexpected List() result %+v, %v", objects, err)
+	}
+	if objects, err := s.List(ctx, ""); err != nil || len(objects) != len(keys) {
+		t.Errorf("Expected every object listed, got %+v, %v", objects, err)
+	}
+
+	// Overwriting replaces the content
+	if err := s.Put(ctx, "other.txt", strings.NewReader("new")); err != nil {
+		t.Fatalf("Put() unexpected error overwriting: %v", err)
+	}
+	if info, err := s.Stat(ctx, "other.txt"); err != nil || info.Size != 3 {
+		t.Errorf("Expected the overwritten size, got %+v, %v", info, err)
+	}
+
+	// A body that fails part way stores nothing
+	if err := s.Put(ctx, "2024/03/partial.json", &failingReader{data: `{"status": "ok"`}); err == nil {
+		t.Error("Expected Put() to fail with the body")
+	}
+	if _, err := s.Stat(ctx, "2024/03/partial.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected no partial object, got %v", err)
+	}
+
+	if err := s.Delete(ctx, "2024/01/page1.json"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+	if _, err := s.Get(ctx, "2024/01/page1.json"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete(), got %v", err)
+	}
+	if err := s.Delete(ctx, "2024/01/page1.json"); err != nil {
+		t.Errorf("Expected deleting a missing object to succeed, got %v", err)
+	}
+
+	for _, key := range []string{"", "/etc/passwd", "../outside.json", "2024//page1.json"} {
+		if err := s.Put(ctx, key, strings.NewReader("x")); err == nil {
+			t.Errorf("Expected key %q to be rejected", key)
+		}
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	root := t.TempDir()
+	s := NewLocal(root)
+	exerciseStorage(t, s)
+
+	// Objects are plain files and their URIs are their paths
+	path := s.URI("2024/02/page1.json")
+	if path != filepath.Join(root, "2024", "02", "page1.json") {
+		t.Errorf("Unexpected URI %s", path)
+	}
+	if data, err := ioutil.ReadFile(path); err != nil || string(data) != "content of 2024/02/page1.json" {
+		t.Errorf("Expected the object at %s, got %q, %v", path, data, err)
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(root, "*", "*", "*"+tmpSuffix)); len(matches) != 0 {
+		t.Errorf("Expected no temporary files left behind, got %v", matches)
+	}
+}
+
+func TestLocalStorageConcurrentPutsOfOneKey(t *testing.T) {
+	root := t.TempDir()
+	s := NewLocal(root)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs <- s.Put(context.Background(), "2024/page1.json", strings.NewReader(strings.Repeat(strconv.Itoa(i), 4096)))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Put() unexpected error: %v", err)
+		}
+	}
+
+	// The object is one writer's content in full, and readable by consumers
+	path := filepath.Join(root, "2024", "page1.json")
+	data, err := ioutil.ReadFile(path)
+	if err != nil || len(data) != 4096 || strings.Count(string(data), string(data[:1])) != 4096 {
+		t.Errorf("Expected one complete object, got %d bytes, %v", len(data), err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm()&0044 != 0044 {
+		t.Errorf("Expected the object to be readable by others, got %v, %v", info.Mode(), err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(root, "2024", "*"+tmpSuffix)); len(matches) != 0 {
+		t.Errorf("Expected no temporary files left behind, got %v", matches)
+	}
+}
+
+func TestLocalStorageListSkipsFilesBeingWritten(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "2024"), 0755)
+	ioutil.WriteFile(filepath.Join(root, "2024", "page1.json"), []byte("{}"), 0644)
+	ioutil.WriteFile(filepath.Join(root, "2024", "page2.json"+tmpSuffix), []byte("{"), 0644)
+
+	objects, err := NewLocal(root).List(context.Background(), "2024/")
+	if err != nil || len(objects) != 1 || objects[0].Key != "2024/page1.json" {
+		t.Errorf("Expected only the finished file, got %+v, %v", objects, err)
+	}
+
+	// Listing a prefix with nothing under it is empty rather than an error
+	if objects, err := NewLocal(root).List(context.Background(), "2030/"); err != nil || len(objects) != 0 {
+		t.Errorf("Expected an empty listing, got %+v, %v", objects, err)
+	}
+}
+
+func TestLocalStorageRespectsCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := NewLocal(t.TempDir()).Put(ctx, "page1.json", io.LimitReader(strings.NewReader("{}"), 2))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled Put(), got %v", err)
+	}
+}
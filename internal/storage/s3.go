@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultS3Region is used when no region is configured
+const DefaultS3Region = "us-east-1"
+
+// S3Options configures an S3-compatible storage
+type S3Options struct {
+	// Endpoint is the service URL, such as http://localhost:9000 for MinIO. It
+	// defaults to the AWS endpoint of Region.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// Prefix is prepended to every key, separated by a slash
+	Prefix string
+	// PathStyle addresses the bucket in the path (endpoint/bucket/key) rather
+	// than the host name (bucket.endpoint/key). Most self-hosted services need it.
+	PathStyle bool
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// HTTPClient sends the requests; it defaults to a client with a 60 second timeout
+	HTTPClient *http.Client
+}
+
+// S3Error is an error response from an S3-compatible service
+type S3Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Key        string
+}
+
+func (e *S3Error) Error() string {
+	return fmt.Sprintf("S3 error %d (%s) for '%s': %s", e.StatusCode, e.Code, e.Key, e.Message)
+}
+
+// S3 stores objects in a bucket of an S3-compatible service. Requests are signed
+// with AWS Signature Version 4.
+type S3 struct {
+	endpoint  *url.URL
+	bucket    string
+	prefix    string
+	pathStyle bool
+	signer    *signer
+	client    *http.Client
+}
+
+// NewS3 returns a storage for the bucket described by options
+func NewS3(options S3Options) (*S3, error) {
+	if options.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket cannot be empty")
+	}
+	if options.AccessKeyID == "" || options.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 access key ID and secret access key are required")
+	}
+
+	if options.Region == "" {
+		options.Region = DefaultS3Region
+	}
+	if options.Endpoint == "" {
+		options.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", options.Region)
+	}
+	endpoint, err := url.Parse(options.Endpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s'", options.Endpoint)
+	}
+
+	client := options.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 60 * time.Second}
+	}
+
+	return &S3{
+		endpoint:  endpoint,
+		bucket:    options.Bucket,
+		prefix:    strings.Trim(options.Prefix, "/"),
+		pathStyle: options.PathStyle,
+		signer: &signer{
+			accessKeyID:     options.AccessKeyID,
+			secretAccessKey: options.SecretAccessKey,
+			sessionToken:    options.SessionToken,
+			region:          options.Region,
+			service:         "s3",
+		},
+		client: client,
+	}, nil
+}
+
+// objectKey returns the key of the object in the bucket
+func (s *S3) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+// objectURL returns the URL of an object, or of the bucket for an empty key
+func (s *S3) objectURL(objectKey string, query url.Values) *url.URL {
+	u := *s.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.pathStyle {
+		u.Path = base + "/" + s.bucket
+		if objectKey != "" {
+			u.Path += "/" + objectKey
+		}
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = base + "/" + objectKey
+	}
+	u.RawPath = escapePath(u.Path)
+	u.RawQuery = query.Encode()
+	return &u
+}
+
+// do sends a signed request. Error responses are returned as an *S3Error, with
+// ErrNotFound for a missing object.
+func (s *S3) do(ctx context.Context, method, key string, query url.Values, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	objectKey := ""
+	if key != "" {
+		objectKey = s.objectKey(key)
+	}
+
+	// An empty body must be nil, or it would be sent chunked with no length
+	if size == 0 {
+		body = nil
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(objectKey, query).String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.ContentLength = size
+	s.signer.sign(req, payloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("S3 %s request failed: %w", method, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound && key != "" {
+		return nil, notFound(key)
+	}
+
+	s3Err := &S3Error{StatusCode: resp.StatusCode, Code: http.StatusText(resp.StatusCode), Key: key}
+	var errorBody struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if data, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64<<10)); err == nil && xml.Unmarshal(data, &errorBody) == nil {
+		if errorBody.Code != "" {
+			s3Err.Code = errorBody.Code
+		}
+		s3Err.Message = errorBody.Message
+	}
+	return nil, s3Err
+}
+
+// Put implements Storage.Put. S3 needs the length and hash of the body before
+// the upload starts, so the body is first spooled to a temporary file.
+func (s *S3) Put(ctx context.Context, key string, body io.Reader) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	spool, err := ioutil.TempFile("", "s3-put-")
+	if err != nil {
+		return fmt.Errorf("failed to create spool file: %w", err)
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, hash), body)
+	if err != nil {
+		return fmt.Errorf("failed to read body of '%s': %w", key, err)
+	}
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind spool file: %w", err)
+	}
+
+	resp, err := s.do(ctx, http.MethodPut, key, nil, spool, size, hex.EncodeToString(hash.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get implements Storage.Get
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, key, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// listBucketResult is the response of ListObjectsV2
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List implements Storage.List, following continuation tokens until every
+// matching object has been listed
+func (s *S3) List(ctx context.Context, prefix string) ([]ObjectInfo, error) {
+	objectPrefix := prefix
+	if s.prefix != "" {
+		objectPrefix = s.prefix + "/" + prefix
+	}
+
+	objects := make([]ObjectInfo, 0)
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {objectPrefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil, 0, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var result listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode object listing: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			key := object.Key
+			if s.prefix != "" {
+				key = strings.TrimPrefix(key, s.prefix+"/")
+			}
+			objects = append(objects, ObjectInfo{Key: key, Size: object.Size, ModTime: object.LastModified})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+// Delete implements Storage.Delete
+func (s *S3) Delete(ctx context.Context, key string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Stat implements Storage.Stat
+func (s *S3) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(ctx, http.MethodHead, key, nil, nil, 0, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	info := &ObjectInfo{Key: key, Size: resp.ContentLength}
+	if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.ModTime = modTime
+	}
+	return info, nil
+}
+
+// URI implements Storage.URI
+func (s *S3) URI(key string) string {
+	return "s3://" + s.bucket + "/" + s.objectKey(key)
+}
@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"go-news-agg/internal/config"
+	"go-news-agg/internal/fakes3"
+)
+
+// newFakeS3 starts an S3 stand-in and returns storage for a bucket on it
+func newFakeS3(t *testing.T, prefix string) (*S3, *fakes3.TestServer) {
+	t.Helper()
+
+	server := fakes3.NewTestServer(fakes3.Options{AccessKeyID: "minio", MaxKeys: 2})
+	t.Cleanup(server.Close)
+
+	s, err := NewS3(S3Options{
+		Endpoint:        server.URL,
+		Bucket:          "news-pages",
+		Prefix:          prefix,
+		PathStyle:       true,
+		AccessKeyID:     "minio",
+		SecretAccessKey: "minio-secret",
+	})
+	if err != nil {
+		t.Fatalf("NewS3() unexpected error: %v", err)
+	}
+	return s, server
+}
+
+func TestS3Storage(t *testing.T) {
+	// Listings span several pages of two keys each
+	s, server := newFakeS3(t, "")
+	exerciseStorage(t, s)
+
+	if _, ok := server.API.Object("news-pages", "2024/02/page1.json"); !ok {
+		t.Errorf("Expected the object in the bucket, got keys %v", server.API.Keys("news-pages"))
+	}
+	if uri := s.URI("2024/02/page1.json"); uri != "s3://news-pages/2024/02/page1.json" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+}
+
+func TestS3StorageWithPrefix(t *testing.T) {
+	s, server := newFakeS3(t, "/raw/pages/")
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "2024/01/page 1.json", strings.NewReader("{}")); err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if keys := server.API.Keys("news-pages"); len(keys) != 1 || keys[0] != "raw/pages/2024/01/page 1.json" {
+		t.Errorf("Expected the key under the prefix, got %v", keys)
+	}
+
+	objects, err := s.List(ctx, "2024/")
+	if err != nil || len(objects) != 1 || objects[0].Key != "2024/01/page 1.json" {
+		t.Errorf("Expected keys relative to the prefix, got %+v, %v", objects, err)
+	}
+	if uri := s.URI("2024/01/page 1.json"); uri != "s3://news-pages/raw/pages/2024/01/page 1.json" {
+		t.Errorf("Unexpected URI %s", uri)
+	}
+}
+
+func TestS3StorageErrors(t *testing.T) {
+	s, _ := newFakeS3(t, "")
+	s.signer.accessKeyID = "someone-else"
+
+	err := s.Put(context.Background(), "page1.json", strings.NewReader("{}"))
+	var s3Err *S3Error
+	if !errors.As(err, &s3Err) || s3Err.StatusCode != 403 || s3Err.Code != "InvalidAccessKeyId" {
+		t.Errorf("Expected an InvalidAccessKeyId error, got %v", err)
+	}
+
+	if _, err := NewS3(S3Options{Bucket: "news-pages"}); err == nil {
+		t.Error("Expected an error without credentials")
+	}
+	if _, err := NewS3(S3Options{AccessKeyID: "a", SecretAccessKey: "b"}); err == nil {
+		t.Error("Expected an error without a bucket")
+	}
+}
+
+func TestS3StorageVirtualHostedURLs(t *testing.T) {
+	s, err := NewS3(S3Options{Region: "eu-west-1", Bucket: "news-pages", AccessKeyID: "a", SecretAccessKey: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u := s.objectURL("2024/01/page1.json", nil); u.String() != "https://news-pages.s3.eu-west-1.amazonaws.com/2024/01/page1.json" {
+		t.Errorf("Unexpected object URL %s", u)
+	}
+}
+
+func TestNew(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+
+	if s, err := New(cfg); err != nil || s.URI("a.json") != cfg.OutputDir+"/a.json" {
+		t.Errorf("Expected local storage under OutputDir, got %v, %v", s, err)
+	}
+
+	cfg.StorageURL = "file:///srv/news"
+	if s, err := New(cfg); err != nil || s.URI("a.json") != "/srv/news/a.json" {
+		t.Errorf("Expected local storage under /srv/news, got %v, %v", s, err)
+	}
+
+	cfg.StorageURL = "s3://news-pages/raw"
+	cfg.S3AccessKeyID = "minio"
+	cfg.S3SecretAccessKey = "minio-secret"
+	if s, err := New(cfg); err != nil || s.URI("a.json") != "s3://news-pages/raw/a.json" {
+		t.Errorf("Expected S3 storage, got %v, %v", s, err)
+	}
+
+	cfg.StorageURL = "gs://news-pages"
+	if _, err := New(cfg); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+// TestS3StorageAgainstService runs the storage operations against a real
+// S3-compatible service, such as a local MinIO
+func TestS3StorageAgainstService(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("Skipping S3 integration test: S3_TEST_ENDPOINT not set")
+	}
+
+	s, err := NewS3(S3Options{
+		Endpoint:        endpoint,
+		Region:          os.Getenv("S3_TEST_REGION"),
+		Bucket:          os.Getenv("S3_TEST_BUCKET"),
+		Prefix:          fmt.Sprintf("go-news-agg-test/%d", time.Now().UnixNano()),
+		PathStyle:       true,
+		AccessKeyID:     os.Getenv("S3_TEST_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("S3_TEST_SECRET_ACCESS_KEY"),
+	})
+	if err != nil {
+		t.Fatalf("NewS3() unexpected error: %v", err)
+	}
+
+	exerciseStorage(t, s)
+
+	// Leave the bucket as it was
+	objects, _ := s.List(context.Background(), "")
+	for _, object := range objects {
+		s.Delete(context.Background(), object.Key)
+	}
+}
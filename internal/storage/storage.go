@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-news-agg/internal/config"
+)
+
+// ErrNotFound is returned, possibly wrapped, when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key     string
+	Size    int64
+	ModTime time.Time
+}
+
+// Storage stores saved pages under slash-separated keys such as
+// "2024/01/20240115-093000-9f86d081_us_page1.json"
+type Storage interface {
+	// Put stores everything read from body under key, replacing any existing
+	// object. The object only becomes visible once body has been read to the end;
+	// if reading body fails, nothing is stored.
+	Put(ctx context.Context, key string, body io.Reader) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the objects whose keys start with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]ObjectInfo, error)
+	// Delete removes the object stored under key; a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// Stat describes the object stored under key
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	// URI returns the location of key that is handed to consumers, such as
+	// s3://bucket/key or a local file path
+	URI(key string) string
+}
+
+// New returns the storage configured by cfg.StorageURL: the local OutputDir when
+// it is empty, a directory for a file:// URL, or a bucket for an s3:// URL
+func New(cfg *config.Config) (Storage, error) {
+	if cfg.StorageURL == "" {
+		return NewLocal(cfg.OutputDir), nil
+	}
+
+	location, err := url.Parse(cfg.StorageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid storage URL '%s': %w", cfg.StorageURL, err)
+	}
+
+	switch location.Scheme {
+	case "file":
+		return NewLocal(location.Path), nil
+	case "s3":
+		if location.Host == "" {
+			return nil, fmt.Errorf("storage URL '%s' has no bucket", cfg.StorageURL)
+		}
+		return NewS3(S3Options{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          location.Host,
+			Prefix:          strings.Trim(location.Path, "/"),
+			PathStyle:       cfg.S3PathStyle,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			SessionToken:    cfg.S3SessionToken,
+		})
+	}
+	return nil, fmt.Errorf("unsupported storage URL scheme '%s'", location.Scheme)
+}
+
+// validateKey rejects keys that are empty or could escape the storage root
+func validateKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+	if strings.HasPrefix(key, "/") {
+		return fmt.Errorf("key '%s' must be relative", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("key '%s' contains an invalid path segment", key)
+		}
+	}
+	return nil
+}
+
+// notFound returns ErrNotFound for key
+func notFound(key string) error {
+	return fmt.Errorf("%w: %s", ErrNotFound, key)
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumExtension is appended to a file's path to name its SHA-256 sidecar
+const ChecksumExtension = "sha256"
+
+// ErrChecksumMismatch is returned by VerifyChecksum when a file does not match its sidecar
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// ChecksumPath returns the path of the SHA-256 sidecar of a file
+func ChecksumPath(filePath string) string {
+	return filePath + "." + ChecksumExtension
+}
+
+// FormatChecksum returns the sidecar line for a file in the format of sha256sum,
+// so the sidecar can also be checked with `sha256sum -c`
+func FormatChecksum(checksum, filePath string) string {
+	return fmt.Sprintf("%s  %s\n", checksum, filepath.Base(filePath))
+}
+
+// FileChecksum returns the hex-encoded SHA-256 of a file's bytes as stored on disk
+func FileChecksum(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read '%s': %w", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// VerifyChecksum checks a file against its sidecar. Consumers should call it
+// before processing a file; an error wrapping ErrChecksumMismatch means the file
+// is not the one that was written.
+func VerifyChecksum(filePath string) error {
+	if err := ValidateFilePath(filePath); err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(ChecksumPath(filePath))
+	if err != nil {
+		return fmt.Errorf("failed to read checksum of '%s': %w", filePath, err)
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum file of '%s' is empty", filePath)
+	}
+
+	actual, err := FileChecksum(filePath)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(fields[0], actual) {
+		return fmt.Errorf("%w for '%s': expected %s, got %s", ErrChecksumMismatch, filePath, fields[0], actual)
+	}
+	return nil
+}
+
+// SyncDir flushes a directory's entries to disk, so that a file renamed into
+// it survives a crash
+func SyncDir(dirPath string) error {
+	dir, err := os.Open(dirPath)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page1.json")
+	if err := ioutil.WriteFile(path, []byte(`{"status": "ok"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyChecksum(path); err == nil {
+		t.Error("Expected an error without a sidecar")
+	}
+
+	checksum, err := FileChecksum(path)
+	if err != nil {
+		t.Fatalf("FileChecksum() unexpected error: %v", err)
+	}
+
+	// The sidecar is in sha256sum format
+	sidecar := FormatChecksum(checksum, path)
+	if sidecar != checksum+"  page1.json\n" {
+		t.Errorf("Unexpected sidecar %q", sidecar)
+	}
+	if err := ioutil.WriteFile(ChecksumPath(path), []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := VerifyChecksum(path); err != nil {
+		t.Errorf("VerifyChecksum() unexpected error: %v", err)
+	}
+
+	// A file changed after the sidecar was written no longer verifies
+	if err := ioutil.WriteFile(path, []byte(`{"status": "o`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyChecksum(path); !errors.Is(err, ErrChecksumMismatch) || !strings.Contains(err.Error(), checksum) {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression names a codec files can be compressed with
+type Compression string
+
+const (
+	// CompressionNone leaves files as they are
+	CompressionNone Compression = "none"
+	// CompressionGzip compresses files with gzip and appends .gz to their names
+	CompressionGzip Compression = "gzip"
+	// CompressionZstd compresses files with Zstandard and appends .zst to their names
+	CompressionZstd Compression = "zstd"
+)
+
+// ParseCompression returns the codec with the given name; an empty name means none
+func ParseCompression(name string) (Compression, error) {
+	switch c := Compression(strings.ToLower(name)); c {
+	case "", CompressionNone:
+		return CompressionNone, nil
+	case CompressionGzip, CompressionZstd:
+		return c, nil
+	}
+	return CompressionNone, fmt.Errorf("unsupported compression '%s'", name)
+}
+
+// Extension returns the file name extension of the codec, without the leading
+// dot, or an empty string when files are not compressed
+func (c Compression) Extension() string {
+	switch c {
+	case CompressionGzip:
+		return "gz"
+	case CompressionZstd:
+		return "zst"
+	}
+	return ""
+}
+
+// CompressionFromPath detects the codec a file was written with from its extension
+func CompressionFromPath(filePath string) Compression {
+	switch {
+	case strings.HasSuffix(filePath, ".gz"):
+		return CompressionGzip
+	case strings.HasSuffix(filePath, ".zst"):
+		return CompressionZstd
+	}
+	return CompressionNone
+}
+
+// nopWriteCloser passes writes through and does nothing on Close
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// NewCompressingWriter returns a writer that compresses everything written to it
+// into w. Close flushes the compressed stream but does not close w.
+func NewCompressingWriter(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case "", CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	}
+	return nil, fmt.Errorf("unsupported compression '%s'", c)
+}
+
+// decompressingReader closes both the codec and the file underneath it
+type decompressingReader struct {
+	io.Reader
+	closeCodec func() error
+	file       *os.File
+}
+
+func (r *decompressingReader) Close() error {
+	err := r.closeCodec()
+	if closeErr := r.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// OpenFile opens a file for reading. Files whose extension names a codec are
+// decompressed as they are read, so callers see the original content.
+func OpenFile(filePath string) (io.ReadCloser, error) {
+	if err := ValidateFilePath(filePath); err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch CompressionFromPath(filePath) {
+	case CompressionGzip:
+		reader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to read gzip header of '%s': %w", filePath, err)
+		}
+		return &decompressingReader{Reader: reader, closeCodec: reader.Close, file: file}, nil
+	case CompressionZstd:
+		decoder, err := zstd.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to open zstd stream of '%s': %w", filePath, err)
+		}
+		closeCodec := func() error {
+			decoder.Close()
+			return nil
+		}
+		return &decompressingReader{Reader: decoder, closeCodec: closeCodec, file: file}, nil
+	}
+	return file, nil
+}
+
+// ReadFile reads the whole content of a file, decompressing it like OpenFile
+func ReadFile(filePath string) ([]byte, error) {
+	reader, err := OpenFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCompression(t *testing.T) {
+	for name, expected := range map[string]Compression{"": CompressionNone, "none": CompressionNone, "GZIP": CompressionGzip, "zstd": CompressionZstd} {
+		if c, err := ParseCompression(name); err != nil || c != expected {
+			t.Errorf("ParseCompression(%q): expected %s, got %s, %v", name, expected, c, err)
+		}
+	}
+
+	if _, err := ParseCompression("bzip2"); err == nil {
+		t.Error("Expected an error for an unknown codec")
+	}
+}
+
+func TestCompressionFromPath(t *testing.T) {
+	tests := map[string]Compression{
+		"/data/2024/01/page1.json":    CompressionNone,
+		"/data/2024/01/page1.json.gz": CompressionGzip,
+		"/data/2024/01/page1.csv.zst": CompressionZstd,
+	}
+	for path, expected := range tests {
+		if c := CompressionFromPath(path); c != expected {
+			t.Errorf("CompressionFromPath(%q): expected %s, got %s", path, expected, c)
+		}
+	}
+}
+
+func TestCompressedFilesRoundTrip(t *testing.T) {
+	content := []byte(strings.Repeat(`{"title": "Markets rally", "source": "bbc-news"}`+"\n", 200))
+	dir := t.TempDir()
+
+	for _, c := range []Compression{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run(string(c), func(t *testing.T) {
+			path := filepath.Join(dir, "page1.json")
+			if ext := c.Extension(); ext != "" {
+				path += "." + ext
+			}
+
+			var buf bytes.Buffer
+			writer, err := NewCompressingWriter(&buf, c)
+			if err != nil {
+				t.Fatalf("NewCompressingWriter() unexpected error: %v", err)
+			}
+			writer.Write(content)
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close() unexpected error: %v", err)
+			}
+			if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if c != CompressionNone && buf.Len() >= len(content) {
+				t.Errorf("Expected %s to shrink %d bytes, got %d", c, len(content), buf.Len())
+			}
+
+			data, err := ReadFile(path)
+			if err != nil || !bytes.Equal(data, content) {
+				t.Fatalf("ReadFile() did not return the original content: %v", err)
+			}
+
+			size, err := GetFileSize(path)
+			if err != nil || size != int64(len(content)) {
+				t.Errorf("Expected GetFileSize to report %d bytes, got %d, %v", len(content), size, err)
+			}
+
+			stored, err := GetStoredFileSize(path)
+			if err != nil || stored != int64(buf.Len()) {
+				t.Errorf("Expected GetStoredFileSize to report %d bytes, got %d, %v", buf.Len(), stored, err)
+			}
+		})
+	}
+}
+
+func TestOpenFileRejectsCorruptArchives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "page1.json.gz")
+	if err := ioutil.WriteFile(path, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ReadFile(path); err == nil {
+		t.Error("Expected an error reading a corrupt gzip file")
+	}
+	if _, err := GetFileSize(path); err == nil {
+		t.Error("Expected an error sizing a corrupt gzip file")
+	}
+}
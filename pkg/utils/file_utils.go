@@ -2,6 +2,8 @@ package utils
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"time"
@@ -44,16 +46,35 @@ func (m *MockTimeProvider) SetTime(t time.Time) {
 // FilePathGenerator handles generation of file paths for news data
 type FilePathGenerator struct {
 	timeProvider TimeProvider
+	template     *PathTemplate
 }
 
+// defaultPathTemplate and legacyPathTemplate are DefaultPathTemplate and
+// LegacyPathTemplate, parsed
+var (
+	defaultPathTemplate, _ = ParsePathTemplate(DefaultPathTemplate, false)
+	legacyPathTemplate, _  = ParsePathTemplate(LegacyPathTemplate, false)
+)
+
 // NewFilePathGenerator creates a new file path generator with the given time provider
+// that lays files out with LegacyPathTemplate
 func NewFilePathGenerator(timeProvider TimeProvider) *FilePathGenerator {
+	return NewTemplateFilePathGenerator(timeProvider, legacyPathTemplate)
+}
+
+// NewTemplateFilePathGenerator creates a file path generator that lays files out
+// with template; a nil template means DefaultPathTemplate
+func NewTemplateFilePathGenerator(timeProvider TimeProvider, template *PathTemplate) *FilePathGenerator {
 	if timeProvider == nil {
 		timeProvider = &RealTimeProvider{}
 	}
-	
+	if template == nil {
+		template = defaultPathTemplate
+	}
+
 	return &FilePathGenerator{
 		timeProvider: timeProvider,
+		template:     template,
 	}
 }
 
@@ -65,34 +86,39 @@ func NewDefaultFilePathGenerator() *FilePathGenerator {
 // GenerateJSONFilePath creates the full path for the JSON file based on the current time,
 // the provided base output directory, country, and page number
 func (g *FilePathGenerator) GenerateJSONFilePath(baseOutputDir, country string, page int) (string, string) {
-	now := g.timeProvider.Now()
-	
-	yearDir := now.Format("2006")
-	monthDir := now.Format("01")
-	filename := fmt.Sprintf("%s_%s_page%d.json", 
-		now.Format("2006-01-02_15-04-05"), 
-		country, 
-		page)
-	
-	fullOutputDir := filepath.Join(baseOutputDir, yearDir, monthDir)
-	fullJSONPath := filepath.Join(fullOutputDir, filename)
+	return g.GenerateFilePath(baseOutputDir, country, page, "json")
+}
 
-	return fullOutputDir, fullJSONPath
+// GenerateFilePath creates the full path for a file with the given extension (without
+// the leading dot) based on the current time, base output directory, country, and page number
+func (g *FilePathGenerator) GenerateFilePath(baseOutputDir, country string, page int, extension string) (string, string) {
+	return g.GenerateFilePathWithTime(baseOutputDir, country, page, extension, g.timeProvider.Now())
 }
 
 // GenerateJSONFilePathWithTime creates a file path with a specific time (useful for batch processing)
 func (g *FilePathGenerator) GenerateJSONFilePathWithTime(baseOutputDir, country string, page int, timestamp time.Time) (string, string) {
-	yearDir := timestamp.Format("2006")
-	monthDir := timestamp.Format("01")
-	filename := fmt.Sprintf("%s_%s_page%d.json", 
-		timestamp.Format("2006-01-02_15-04-05"), 
-		country, 
-		page)
-	
-	fullOutputDir := filepath.Join(baseOutputDir, yearDir, monthDir)
-	fullJSONPath := filepath.Join(fullOutputDir, filename)
+	return g.GenerateFilePathWithTime(baseOutputDir, country, page, "json", timestamp)
+}
 
-	return fullOutputDir, fullJSONPath
+// GenerateFilePathWithTime creates a file path with the given extension for a specific time
+func (g *FilePathGenerator) GenerateFilePathWithTime(baseOutputDir, country string, page int, extension string, timestamp time.Time) (string, string) {
+	return g.GeneratePath(baseOutputDir, PathParams{
+		Country:   country,
+		Page:      page,
+		Extension: extension,
+		Time:      timestamp,
+	})
+}
+
+// GeneratePath renders the generator's template for params under baseOutputDir
+// and returns the directory and the full path. A zero params.Time means now.
+func (g *FilePathGenerator) GeneratePath(baseOutputDir string, params PathParams) (string, string) {
+	if params.Time.IsZero() {
+		params.Time = g.timeProvider.Now()
+	}
+
+	fullPath := filepath.Join(baseOutputDir, filepath.FromSlash(g.template.Render(params)))
+	return filepath.Dir(fullPath), fullPath
 }
 
 // ValidateFilePath checks if a file path is valid and safe
@@ -140,8 +166,34 @@ func EnsureDirectoryExists(dirPath string) error {
 	return nil
 }
 
-// GetFileSize returns the size of a file in bytes
+// GetFileSize returns the size of a file in bytes. For a compressed file this is
+// the size of its decompressed content, which means reading and decompressing
+// the whole file; use GetStoredFileSize when the size on disk is enough.
 func GetFileSize(filePath string) (int64, error) {
+	size, err := GetStoredFileSize(filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	if CompressionFromPath(filePath) == CompressionNone {
+		return size, nil
+	}
+
+	reader, err := OpenFile(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open '%s': %w", filePath, err)
+	}
+	defer reader.Close()
+
+	size, err = io.Copy(ioutil.Discard, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress '%s': %w", filePath, err)
+	}
+	return size, nil
+}
+
+// GetStoredFileSize returns the size of a file on disk in bytes, compressed or not
+func GetStoredFileSize(filePath string) (int64, error) {
 	if err := ValidateFilePath(filePath); err != nil {
 		return 0, err
 	}
@@ -150,7 +202,6 @@ func GetFileSize(filePath string) (int64, error) {
 	if err != nil {
 		return 0, fmt.Errorf("failed to get file info for '%s': %w", filePath, err)
 	}
-
 	return info.Size(), nil
 }
 
@@ -175,6 +226,11 @@ func GenerateJSONFilePath(baseOutputDir, country string, page int) (string, stri
 	return defaultGenerator.GenerateJSONFilePath(baseOutputDir, country, page)
 }
 
+// GenerateFilePath creates a file path with the given extension using the default generator
+func GenerateFilePath(baseOutputDir, country string, page int, extension string) (string, string) {
+	return defaultGenerator.GenerateFilePath(baseOutputDir, country, page, extension)
+}
+
 // SetTimeProvider allows changing the time provider for the default generator (useful for testing)
 func SetTimeProvider(provider TimeProvider) {
 	defaultGenerator = NewFilePathGenerator(provider)
@@ -29,6 +29,20 @@ func TestGenerateJSONFilePath(t *testing.T) {
 	}
 }
 
+func TestGenerateFilePath(t *testing.T) {
+	fixedTime := time.Date(2025, time.August, 15, 12, 0, 0, 0, time.UTC)
+	generator := NewFilePathGenerator(NewMockTimeProvider(fixedTime))
+
+	for _, extension := range []string{"json", "ndjson", "csv", "parquet"} {
+		expectedPath := filepath.Join("/tmp/test_news", "2025", "08", "2025-08-15_12-00-00_us_page3."+extension)
+
+		_, fullPath := generator.GenerateFilePath("/tmp/test_news", "us", 3, extension)
+		if fullPath != expectedPath {
+			t.Errorf("Expected full path '%s', but got '%s'", expectedPath, fullPath)
+		}
+	}
+}
+
 func TestValidateFilePath(t *testing.T) {
 	tests := []struct {
 		name     string
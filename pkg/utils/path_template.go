@@ -0,0 +1,306 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPathTemplate lays files out as YYYY/MM/<run ID>_<country>_page<N>.<ext>.
+// Runs without a country are named after their query instead. The run ID starts
+// with the UTC start time, so files still sort by time, and two runs for the
+// same country that start in the same second no longer overwrite each other.
+const DefaultPathTemplate = "{yyyy}/{mm}/{run_id}_{country|query-slug}_page{page}.{ext}"
+
+// LegacyPathTemplate is the layout used before run IDs, with the download time
+// to the second in place of the run ID. Consumers that parse that file name can
+// keep it by setting output_path_template to this template. FilePathGenerators
+// made by NewFilePathGenerator also keep it, as their callers have no run ID.
+const LegacyPathTemplate = "{yyyy}/{mm}/{timestamp}_{country|query-slug}_page{page}.{ext}"
+
+// emptyValue stands in for a placeholder whose value is empty, such as the
+// country of a query-only run
+const emptyValue = "all"
+
+// maxSlugLength caps the length of a slugged query
+const maxSlugLength = 64
+
+// PathParams are the values a PathTemplate is rendered with
+type PathParams struct {
+	Provider  string
+	Endpoint  string
+	Country   string
+	Query     string
+	RunID     string
+	Page      int
+	Extension string
+	Time      time.Time
+}
+
+// placeholder is one {a|b} field of a template: the first alternative with a
+// non-empty value is used
+type placeholder struct {
+	alternatives []string
+}
+
+// templatePart is either literal text or a placeholder
+type templatePart struct {
+	literal     string
+	placeholder *placeholder
+}
+
+// PathTemplate turns PathParams into a relative, slash-separated file path.
+// Placeholders are written in braces:
+//
+//	{provider}    the provider name, e.g. newsapi
+//	{endpoint}    the NewsAPI endpoint, e.g. top-headlines
+//	{country}     the country code
+//	{query-slug}  the query, lower-cased with runs of other characters turned into dashes
+//	{yyyy} {mm} {dd} {hh}  parts of the download time
+//	{date}        the download date as YYYY-MM-DD
+//	{timestamp}   the download time as YYYY-MM-DD_hh-mm-ss
+//	{run_id}      an ID unique to the run, see NewRunID
+//	{page}        the page number
+//	{ext}         the file extension, without the leading dot
+//
+// Alternatives are separated by '|': {country|query-slug} is the country, or the
+// query when there is no country. A placeholder left empty renders as "all".
+//
+// With Hive-style partitioning, a path segment made of a single placeholder
+// renders as name=value, e.g. {yyyy}/{country} becomes year=2024/country=us.
+// The name is that of the first alternative: {country|query-slug} is always
+// country=, holding the query when there is no country.
+type PathTemplate struct {
+	raw      string
+	segments [][]templatePart
+	hive     bool
+}
+
+// partitionNames are the Hive partition names of placeholders that differ from
+// their own name
+var partitionNames = map[string]string{
+	"yyyy":       "year",
+	"mm":         "month",
+	"dd":         "day",
+	"hh":         "hour",
+	"query-slug": "query",
+	"run_id":     "run",
+}
+
+// placeholderNames lists every placeholder a template may use
+var placeholderNames = map[string]bool{
+	"provider": true, "endpoint": true, "country": true, "query-slug": true,
+	"yyyy": true, "mm": true, "dd": true, "hh": true, "date": true, "timestamp": true,
+	"run_id": true, "page": true, "ext": true,
+}
+
+// ParsePathTemplate parses a template. It must be relative and use {page} and
+// {ext} in its last segment, so pages of a run never overwrite each other and
+// readers can tell the format of a file. An empty template is DefaultPathTemplate.
+func ParsePathTemplate(template string, hive bool) (*PathTemplate, error) {
+	if template == "" {
+		template = DefaultPathTemplate
+	}
+	if strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("path template '%s' must be relative", template)
+	}
+
+	t := &PathTemplate{raw: template, hive: hive}
+	segments := strings.Split(template, "/")
+	for i, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("path template '%s' contains an invalid path segment", template)
+		}
+
+		parts, err := parseSegment(segment)
+		if err != nil {
+			return nil, fmt.Errorf("path template '%s': %w", template, err)
+		}
+		if i == len(segments)-1 && (!usesPlaceholder(parts, "page") || !usesPlaceholder(parts, "ext")) {
+			return nil, fmt.Errorf("path template '%s' must use {page} and {ext} in the file name", template)
+		}
+		t.segments = append(t.segments, parts)
+	}
+	return t, nil
+}
+
+// parseSegment splits one path segment into literals and placeholders
+func parseSegment(segment string) ([]templatePart, error) {
+	var parts []templatePart
+	for segment != "" {
+		open := strings.IndexByte(segment, '{')
+		if closing := strings.IndexByte(segment, '}'); closing >= 0 && (open < 0 || closing < open) {
+			return nil, fmt.Errorf("unmatched '}'")
+		}
+		if open < 0 {
+			parts = append(parts, templatePart{literal: segment})
+			break
+		}
+		if open > 0 {
+			parts = append(parts, templatePart{literal: segment[:open]})
+		}
+
+		length := strings.IndexByte(segment[open:], '}')
+		if length < 0 {
+			return nil, fmt.Errorf("unclosed '{'")
+		}
+		alternatives := strings.Split(segment[open+1:open+length], "|")
+		for _, name := range alternatives {
+			if !placeholderNames[name] {
+				return nil, fmt.Errorf("unknown placeholder '{%s}'", name)
+			}
+		}
+		parts = append(parts, templatePart{placeholder: &placeholder{alternatives: alternatives}})
+		segment = segment[open+length+1:]
+	}
+	return parts, nil
+}
+
+// usesPlaceholder reports whether parts contain a placeholder with the given name
+func usesPlaceholder(parts []templatePart, name string) bool {
+	for _, part := range parts {
+		if part.placeholder == nil {
+			continue
+		}
+		for _, alternative := range part.placeholder.alternatives {
+			if alternative == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns the template as written
+func (t *PathTemplate) String() string {
+	return t.raw
+}
+
+// Render returns the relative, slash-separated path for params. A missing run
+// ID is generated from params.Time.
+func (t *PathTemplate) Render(params PathParams) string {
+	if params.RunID == "" {
+		params.RunID = NewRunID(params.Time)
+	}
+
+	segments := make([]string, len(t.segments))
+	for i, parts := range t.segments {
+		var segment strings.Builder
+		for _, part := range parts {
+			if part.placeholder == nil {
+				segment.WriteString(part.literal)
+				continue
+			}
+
+			// A partition is named after the first alternative, whichever one
+			// has the value, so every path of a template shares its partitions
+			if t.hive && len(parts) == 1 {
+				segment.WriteString(partitionName(part.placeholder.alternatives[0]) + "=")
+			}
+			segment.WriteString(part.placeholder.render(params))
+		}
+		segments[i] = segment.String()
+	}
+	return path.Join(segments...)
+}
+
+// render returns the value of the first alternative that has one
+func (p *placeholder) render(params PathParams) string {
+	for _, name := range p.alternatives {
+		if value := placeholderValue(name, params); value != "" {
+			return value
+		}
+	}
+	return emptyValue
+}
+
+// placeholderValue returns the value of one placeholder, safe to use in a path
+func placeholderValue(name string, params PathParams) string {
+	switch name {
+	case "provider":
+		return Slugify(params.Provider)
+	case "endpoint":
+		return Slugify(params.Endpoint)
+	case "country":
+		return Slugify(params.Country)
+	case "query-slug":
+		return Slugify(params.Query)
+	case "yyyy":
+		return params.Time.Format("2006")
+	case "mm":
+		return params.Time.Format("01")
+	case "dd":
+		return params.Time.Format("02")
+	case "hh":
+		return params.Time.Format("15")
+	case "date":
+		return params.Time.Format("2006-01-02")
+	case "timestamp":
+		return params.Time.Format("2006-01-02_15-04-05")
+	case "run_id":
+		return Slugify(params.RunID)
+	case "page":
+		return strconv.Itoa(params.Page)
+	case "ext":
+		return params.Extension
+	}
+	return ""
+}
+
+// partitionName returns the Hive partition name of a placeholder
+func partitionName(name string) string {
+	if partition, ok := partitionNames[name]; ok {
+		return partition
+	}
+	return name
+}
+
+// Slugify lower-cases s and turns every run of characters other than ASCII
+// letters and digits into a single dash, so it can be used in a path. Long
+// results are cut at a dash; text with nothing usable, such as a query in
+// another script, becomes a short hash of itself so it still tells runs apart.
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+
+	slug := strings.TrimSuffix(b.String(), "-")
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+	if slug == "" && strings.TrimSpace(s) != "" {
+		sum := sha256.Sum256([]byte(s))
+		slug = "h" + hex.EncodeToString(sum[:4])
+	}
+	return slug
+}
+
+// NewRunID returns an ID for a run starting at t: the UTC time to the second
+// followed by random hex, e.g. 20240115-093000-9f86d081, so runs that start in
+// the same second still get different IDs and IDs sort by start time
+func NewRunID(t time.Time) string {
+	prefix := t.UTC().Format("20060102-150405")
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		// Fall back to the sub-second part of the time
+		return fmt.Sprintf("%s-%08x", prefix, uint32(t.UnixNano()))
+	}
+	return prefix + "-" + hex.EncodeToString(suffix)
+}
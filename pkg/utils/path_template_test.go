@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPathTemplateRender(t *testing.T) {
+	fixedTime := time.Date(2025, time.August, 15, 12, 0, 0, 0, time.UTC)
+	params := PathParams{
+		Provider:  "newsapi",
+		Endpoint:  "everything",
+		Query:     "Climate Change & COP30!",
+		RunID:     "20250815-120000-9f86d081",
+		Page:      3,
+		Extension: "json.gz",
+		Time:      fixedTime,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		hive     bool
+		params   PathParams
+		expected string
+	}{
+		{
+			name:     "default layout with a country",
+			params:   PathParams{Country: "us", RunID: params.RunID, Page: 2, Extension: "json", Time: fixedTime},
+			expected: "2025/08/20250815-120000-9f86d081_us_page2.json",
+		},
+		{
+			name:     "default layout for a query-only run",
+			params:   params,
+			expected: "2025/08/20250815-120000-9f86d081_climate-change-cop30_page3.json.gz",
+		},
+		{
+			name:     "legacy layout",
+			template: LegacyPathTemplate,
+			params:   PathParams{Country: "us", RunID: params.RunID, Page: 2, Extension: "json", Time: fixedTime},
+			expected: "2025/08/2025-08-15_12-00-00_us_page2.json",
+		},
+		{
+			name:     "full template",
+			template: "{provider}/{endpoint}/{country|query-slug}/{yyyy}/{mm}/{dd}/{run_id}_p{page}.{ext}",
+			params:   params,
+			expected: "newsapi/everything/climate-change-cop30/2025/08/15/20250815-120000-9f86d081_p3.json.gz",
+		},
+		{
+			name:     "hive partitions",
+			template: "{provider}/{country|query-slug}/{yyyy}/{mm}/{dd}/part-{run_id}-{page}.{ext}",
+			hive:     true,
+			params:   params,
+			expected: "provider=newsapi/country=climate-change-cop30/year=2025/month=08/day=15/part-20250815-120000-9f86d081-3.json.gz",
+		},
+		{
+			name:     "hive partitions with a country",
+			template: "{provider}/{country|query-slug}/{yyyy}/{mm}/{dd}/part-{run_id}-{page}.{ext}",
+			hive:     true,
+			params:   PathParams{Provider: "newsapi", Country: "us", RunID: params.RunID, Page: 1, Extension: "json", Time: fixedTime},
+			expected: "provider=newsapi/country=us/year=2025/month=08/day=15/part-20250815-120000-9f86d081-1.json",
+		},
+		{
+			name:     "empty values",
+			template: "{country}/{date}/{page}.{ext}",
+			params:   PathParams{Page: 1, Extension: "csv", Time: fixedTime},
+			expected: "all/2025-08-15/1.csv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := ParsePathTemplate(tt.template, tt.hive)
+			if err != nil {
+				t.Fatalf("ParsePathTemplate() unexpected error: %v", err)
+			}
+			if path := template.Render(tt.params); path != tt.expected {
+				t.Errorf("Expected '%s', got '%s'", tt.expected, path)
+			}
+		})
+	}
+}
+
+func TestParsePathTemplateErrors(t *testing.T) {
+	for template, errMsg := range map[string]string{
+		"/abs/{page}.{ext}":          "must be relative",
+		"{yyyy}/../{page}.{ext}":     "invalid path segment",
+		"{yyyy}//{page}.{ext}":       "invalid path segment",
+		"{year}/{page}.{ext}":        "unknown placeholder '{year}'",
+		"{yyyy/{page}.{ext}":         "unclosed '{'",
+		"yyyy}/{page}.{ext}":         "unmatched '}'",
+		"{yyyy}/{country}.{ext}":     "must use {page} and {ext}",
+		"{page}/{country}_data.json": "must use {page} and {ext}",
+	} {
+		if _, err := ParsePathTemplate(template, false); err == nil || !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("ParsePathTemplate(%q): expected an error containing %q, got %v", template, errMsg, err)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"us":                        "us",
+		"  Bitcoin OR Ethereum  ":   "bitcoin-or-ethereum",
+		"\"exact phrase\" -exclude": "exact-phrase-exclude",
+		"":                          "",
+	}
+	for input, expected := range tests {
+		if slug := Slugify(input); slug != expected {
+			t.Errorf("Slugify(%q): expected %q, got %q", input, expected, slug)
+		}
+	}
+
+	// Long queries are cut at a word boundary
+	long := Slugify(strings.Repeat("election results ", 10))
+	if len(long) > maxSlugLength || strings.HasSuffix(long, "-") || !strings.HasPrefix(long, "election-results-") {
+		t.Errorf("Unexpected slug for a long query: %q", long)
+	}
+
+	// Queries with nothing usable still get distinct, path-safe names
+	first, second := Slugify("東京"), Slugify("大阪")
+	if first == "" || first == second || !regexp.MustCompile(`^h[0-9a-f]{8}$`).MatchString(first) {
+		t.Errorf("Expected distinct hashes, got %q and %q", first, second)
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	start := time.Date(2025, time.August, 15, 12, 0, 0, 0, time.UTC)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := NewRunID(start)
+		if !strings.HasPrefix(id, "20250815-120000-") || len(id) != len("20250815-120000-")+8 {
+			t.Fatalf("Unexpected run ID %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("Run ID %q generated twice for the same second", id)
+		}
+		seen[id] = true
+	}
+}